@@ -53,13 +53,15 @@ const project = new typescript.TypeScriptProject({
     'json2jsii',
     'colors',
     'ajv',
+    'glob@^7',
+    'minimatch@^3',
   ],
   devDeps: [
     '@cdk8s/projen-common',
     '@types/fs-extra@^8',
     '@types/json-schema',
-    'glob',
     '@types/glob',
+    '@types/minimatch@^3',
     'typescript-json-schema',
   ],
 
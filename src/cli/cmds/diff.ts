@@ -0,0 +1,102 @@
+import { green, red, yellow } from 'colors';
+import * as yargs from 'yargs';
+import { readConfigSync } from '../../config';
+import { getContextNamespace, getLiveObject, listLiveObjects } from '../../kubectl';
+import { loadSynthPlugins } from '../../plugins/transform';
+import { KUBE_CONTEXT_ENV, resolveContext } from '../../synth/contexts';
+import { diffResource, findDeletions, resourceKey } from '../../synth/diff';
+import { readManifests } from '../../synth/manifests';
+import { synthManifests } from '../../synth/pipeline';
+import { mkdtemp } from '../../util';
+
+const config = readConfigSync();
+
+class Command implements yargs.CommandModule {
+  public readonly command = 'diff';
+  public readonly describe = 'Diffs the synthesized manifests of your app against the resources in a Kubernetes cluster.';
+
+  public readonly builder = (args: yargs.Argv) => args
+    .option('app', { default: config.app, required: true, desc: 'Command to use in order to execute cdk8s app', alias: 'a' })
+    .option('context', { type: 'string', required: false, desc: 'Synthesize the app for a context defined in the "contexts" section of cdk8s.yaml and diff against its cluster' })
+    .option('kube-context', { type: 'string', required: false, desc: 'The kubeconfig context of the cluster. By default, the kubeconfig context of --context or the current context is used' })
+    .option('prune', { type: 'boolean', default: false, required: false, desc: 'Also show resources that exist in the cluster but not in the app as deletions' })
+    .option('manifests', { type: 'string', required: false, desc: 'Diff the manifests previously synthesized into this directory (compressed or not) instead of synthesizing the app. Patches and plugins are not applied again' })
+    .option('plugin-dir', { type: 'string', default: config.pluginDir, required: false, desc: 'Transform the synthesized resources by the synth plugins in this directory, like "cdk8s synth"' })
+    .option('selector', { type: 'string', required: false, desc: 'Label selector used to find resources to prune (e.g. "app=my-app"). Required by --prune unless --all is set', alias: 'l' })
+    .option('all', { type: 'boolean', default: false, required: false, desc: 'Prune all resources of the types defined by the app, in all namespaces (instead of the ones matching --selector)' })
+    .example('cdk8s diff', 'Diffs the app against the cluster of the current kubeconfig context')
+    .example('cdk8s diff --context staging', 'Diffs the app synthesized for the "staging" context of cdk8s.yaml against its cluster')
+    .example('cdk8s diff --manifests dist', 'Diffs the manifests in "dist" (e.g. written by "cdk8s synth --compress") against the cluster')
+    .example('cdk8s diff --prune -l app=my-app', 'Also shows resources labeled with "app=my-app" that are no longer defined by the app')
+    .example('cdk8s diff --prune --all', 'Also shows all resources of the types defined by the app that are not defined by the app');
+
+  public async handler(argv: any) {
+    const command = argv.app;
+    const env = argv.context ? await resolveContext(argv.context, config.contexts ?? { }) : { };
+    const options = { context: argv.kubeContext ?? env[KUBE_CONTEXT_ENV] };
+
+    if (argv.prune && !argv.selector && !argv.all) {
+      throw new Error('--prune requires a label selector (-l) to find the resources of the app, or --all to consider all resources of the types defined by the app');
+    }
+
+    const diff = async (desired: any[]) => {
+      for (const resource of desired) {
+        const key = resourceKey(resource);
+        const live = await getLiveObject(resource, options);
+
+        if (!live) {
+          console.log(green(`+ ${key}`));
+          continue;
+        }
+
+        const differences = diffResource(resource, live);
+        if (differences.length === 0) {
+          continue;
+        }
+
+        console.log(yellow(`~ ${key}`));
+        for (const difference of differences) {
+          console.log(`    ${difference.path}: ${red(format(difference.live))} => ${green(format(difference.desired))}`);
+        }
+      }
+
+      if (!argv.prune) {
+        return;
+      }
+
+      // only look for deletions of types that are defined by the app
+      const types = new Map<string, { apiVersion: string; kind: string }>();
+      for (const resource of desired) {
+        types.set(`${resource.apiVersion}/${resource.kind}`, { apiVersion: resource.apiVersion, kind: resource.kind });
+      }
+
+      // resources defined without a namespace are created in the namespace of the context
+      const namespace = await getContextNamespace(options);
+
+      for (const { apiVersion, kind } of types.values()) {
+        const live = (await listLiveObjects(apiVersion, kind, argv.selector, options)).map(r => ({ ...r, apiVersion, kind }));
+        for (const resource of findDeletions(desired, live, namespace)) {
+          console.log(red(`- ${resourceKey(resource)}`));
+        }
+      }
+    };
+
+    if (argv.manifests) {
+      await diff((await readManifests(argv.manifests)).flatMap(m => m.resources));
+      return;
+    }
+
+    // the resources are synthesized like "cdk8s synth" does
+    const plugins = argv.pluginDir ? await loadSynthPlugins(argv.pluginDir) : [];
+    await mkdtemp(async tempDir => {
+      const manifests = await synthManifests(command, tempDir, { env, patches: config.patches, plugins });
+      await diff(manifests.flatMap(m => m.resources));
+    });
+  }
+}
+
+function format(value: any) {
+  return value === undefined ? '<none>' : JSON.stringify(value);
+}
+
+module.exports = new Command();
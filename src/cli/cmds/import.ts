@@ -1,7 +1,14 @@
 import * as yargs from 'yargs';
 import { readConfigSync, ImportSpec } from '../../config';
-import { importDispatch } from '../../import/dispatch';
+import { DEFAULT_IMPORT_CONCURRENCY, importBatch, printImportBatchSummary, readImportManifest } from '../../import/batch';
+import { DEFAULT_IMPORT_CACHE_DIR, ImportCache } from '../../import/cache';
+import { importDispatch, ImportDispatchOptions } from '../../import/dispatch';
+import { GoOptionsStyle } from '../../import/go-options';
 import { DEFAULT_API_VERSION } from '../../import/k8s';
+import { DEFAULT_REMOTE_REF_TIMEOUT } from '../../import/refs';
+import { loadRenames, parseRenames } from '../../import/rename';
+import { loadCodegenHooks } from '../../plugins/codegen';
+import { DEFAULT_DOWNLOAD_RETRIES, DEFAULT_DOWNLOAD_RETRY_MAX_DELAY } from '../../util';
 
 const config = readConfigSync();
 
@@ -14,17 +21,56 @@ class Command implements yargs.CommandModule {
   public readonly aliases = ['gen', 'import', 'generate'];
 
   public readonly builder = (args: yargs.Argv) => args
-    .positional('SPEC', { default: config.imports, desc: 'import spec with the syntax [NAME:=]SPEC where NAME is an optional module name and supported SPEC are: k8s, crd.yaml, https://domain/crd.yaml, github:account/repo[@VERSION]).', array: true })
+    .positional('SPEC', { default: config.imports, desc: 'import spec with the syntax [NAME:=]SPEC where NAME is an optional module name and supported SPEC are: k8s, aggregated:GROUP/VERSION (with --from-cluster), crd.yaml, ./crds/, './crds/**/*.yaml', https://domain/crd.yaml, github:account/repo[@VERSION], helm:https://domain/CHART[@VERSION], oci://registry/REPOSITORY[:TAG], proto:FILE.proto, git+https://domain/REPOSITORY.git[//SUBDIR][?ref=REF]).', array: true })
     .example('cdk8s import k8s', `Imports Kubernetes API objects to imports/k8s.ts. Defaults to ${DEFAULT_API_VERSION}`)
     .example('cdk8s import k8s --no-class-prefix', 'Imports Kubernetes API objects without the "Kube" prefix')
     .example('cdk8s import k8s@1.13.0', 'Imports a specific version of the Kubernetes API')
+    .example('cdk8s import k8s --from-cluster', 'Imports exactly the Kubernetes API objects (including alpha/beta APIs) served by the current cluster')
+    .example('cdk8s import aggregated:metrics.k8s.io/v1beta1 --from-cluster', 'Imports the API objects of an aggregated API (e.g. served by metrics-server) from the current cluster')
     .example('cdk8s import jenkins.io_jenkins_crd.yaml', 'Imports constructs for the Jenkins custom resource definition from a file')
+    .example('cdk8s import github:aws-controllers-k8s/s3-controller@0.1 --include-crd \'s3.services.k8s.aws/*\'', 'Imports only the custom resource definitions of the "s3.services.k8s.aws" group')
+    .example('cdk8s import ./crds/', 'Imports constructs for all custom resource definitions in a directory, resolving $refs between its files')
+    .example('cdk8s import \'./crds/**/*.crd.yaml\'', 'Imports constructs for all custom resource definitions in the files that match a glob pattern, resolving $refs between them')
     .example('cdk8s import mattermost:=mattermost_crd.yaml', 'Imports constructs for the mattermost cluster custom resource definition using a custom module name')
     .example('cdk8s import github:crossplane/crossplane@0.14.0', 'Imports constructs for a GitHub repo using doc.crds.dev')
+    .example('cdk8s import cert-manager.yaml --single-file cert-manager', 'Imports constructs for all API groups into a single cert-manager.ts file')
+    .example('cdk8s import k8s -l go --go-module-name example.com/app/imports', 'Imports Kubernetes API objects for Go using an explicit module path')
+    .example('cdk8s import crd.yaml -l go --go-options-style functional', 'Also generates constructors that take functional options, e.g. NewWidgetWithOptions(scope, id, WidgetWithReplicas(3))')
+    .example('cdk8s import helm:https://charts.jetstack.io/cert-manager@1.9.1', 'Imports constructs for the CRDs in a Helm chart (requires "helm")')
+    .example('cdk8s import crd.yaml --rename \'IssuerSpecAcme#private_key=privateKey\'', 'Generates a "privateKey" member for the "private_key" field of "IssuerSpecAcme"')
+    .example('cdk8s import crd.yaml --dry-run', 'Prints the files and constructs that would be generated without writing them')
+    .example('cdk8s import crd.yaml --cache-dir .cdk8s-cache', 'Caches the generated code in ".cdk8s-cache" (e.g. to persist it between CI runs)')
+    .example('cdk8s import git+https://github.com/org/repo.git//config/crd?ref=v1.2.0', 'Imports constructs for all CRDs in a directory of a Git repository (requires "git")')
+    .example('cdk8s import proto:./api/widget.proto', 'Imports the top-level messages with a "spec" field as custom resources (kind = message name). The package determines the group and version (e.g. "widgets.example.com.v1")')
+    .example('cdk8s import --from-file imports.yaml --concurrency 8', 'Imports all sources listed in "imports.yaml", eight at a time')
+    .example('cdk8s import oci://registry.example.com/crds/myapp:v1', 'Imports constructs for the CRDs in an OCI artifact (requires "oras")')
 
     .option('output', { default: DEFAULT_OUTDIR, type: 'string', desc: 'Output directory', alias: 'o' })
     .option('exclude', { type: 'array', desc: 'Do not import types that match these regular expressions. They will be represented as the "any" type (only for "k8s")' })
+    .option('include-crd', { type: 'array', desc: 'Only import custom resource definitions whose "group/kind" matches these glob patterns (only for CRDs)' })
+    .option('exclude-crd', { type: 'array', desc: 'Do not import custom resource definitions whose "group/kind" matches these glob patterns (only for CRDs)' })
+    .option('resolve-remote-refs', { type: 'boolean', default: false, desc: 'Fetch $refs to https URLs and resolve them into the imported schemas (only for CRDs)' })
+    .option('remote-ref-timeout', { type: 'number', default: DEFAULT_REMOTE_REF_TIMEOUT, desc: 'Timeout in milliseconds for fetching a single remote $ref' })
+    .option('retries', { type: 'number', default: DEFAULT_DOWNLOAD_RETRIES, desc: 'Retry downloads from URLs this many times on connection errors and 5xx responses, with exponential backoff' })
+    .option('retry-max-delay', { type: 'number', default: DEFAULT_DOWNLOAD_RETRY_MAX_DELAY, desc: 'The maximum delay in milliseconds between retries of a download' })
+    .option('from-cluster', { type: 'boolean', default: false, desc: 'Generate "k8s" (or "aggregated:") types from the OpenAPI spec served by the cluster of the current kubeconfig context (requires "kubectl")' })
+    .option('kube-context', { type: 'string', desc: 'The kubeconfig context of the cluster used by --from-cluster' })
+    .option('from-file', { type: 'string', desc: 'Import the sources listed under "imports" in this YAML or JSON file instead of SPEC. Each entry is a spec or an object with "source" and optionally "name", "language", "output" (relative to --output), "includeCrd" and "excludeCrd"' })
+    .option('concurrency', { type: 'number', default: DEFAULT_IMPORT_CONCURRENCY, desc: 'The maximum number of sources of --from-file that are imported at the same time' })
+    .option('cache', { type: 'boolean', default: true, desc: 'Reuse the generated code of a previous import if the content of the source did not change. Use --no-cache to always generate the code' })
+    .option('cache-dir', { type: 'string', default: DEFAULT_IMPORT_CACHE_DIR, desc: 'The directory of the import cache' })
+    .option('rename', { type: 'array', desc: 'Override the name of a generated member with the syntax [TYPE#]PROPERTY=NAME, where TYPE is the generated type (all types by default) and PROPERTY the name of the field in the schema (only for CRDs)' })
+    .option('rename-file', { type: 'string', desc: 'A YAML or JSON file that maps [TYPE#]PROPERTY to the name of the generated member (only for CRDs)' })
+    .option('enums-as-unions', { type: 'boolean', default: false, desc: 'Generate string literal union types instead of enums (only for "typescript")' })
+    .option('emit-validations', { type: 'boolean', default: false, desc: 'Check the schema constraints of custom resources (e.g. "minimum" or "pattern") when constructs are created and throw an error if they are violated (only for CRDs)' })
+    .option('apply-defaults', { type: 'boolean', default: false, desc: 'Set the schema defaults of the properties of custom resources that are left undefined, so that the synthesized manifests match what the API server stores (only for CRDs)' })
+    .option('unify-versions', { type: 'boolean', default: false, desc: 'Generate a single construct for all versions of a custom resource, with a "version" prop that selects the apiVersion and the spec of that version. Versions with different top-level fields are generated separately (only for CRDs and "typescript")' })
+    .option('dry-run', { type: 'boolean', default: false, desc: 'Generate the code without writing any files and print which files and constructs would be generated' })
+    .option('single-file', { type: 'string', desc: 'Emit all imported constructs into a single file (module) with this name instead of one per API group' })
     .option('class-prefix', { type: 'string', desc: 'A prefix to add to all generated class names. By default, this is "Kube" for "k8s" imports and disabled for CRD imports. Use --no-class-prefix to disable. Must be PascalCase' })
+    .option('go-module-name', { type: 'string', desc: 'The Go module path of the generated packages (only for "go"). By default, this is derived from the go.mod file of your project' })
+    .option('go-package-name', { type: 'string', desc: 'The Go package name of the generated code (only for "go"). By default, this is derived from the imported module name' })
+    .option('go-options-style', { type: 'string', default: GoOptionsStyle.STRUCT, choices: Object.values(GoOptionsStyle), desc: 'Use "functional" to also generate a NewXWithOptions constructor and an XWithField option for each field of the props and their spec of every construct (only for "go")' })
     .option('language', { default: config.language, demand: true, type: 'string', desc: 'Output programming language', alias: 'l', choices: LANGUAGES });
 
   public async handler(argv: any) {
@@ -32,11 +78,41 @@ class Command implements yargs.CommandModule {
     const imports: string[] = Array.isArray(argv.spec) ? argv.spec : [argv.spec];
     const specs: ImportSpec[] = imports.filter(spec => spec != null).map(parseImports);
 
-    await importDispatch(specs, argv, {
+    const options: ImportDispatchOptions = {
       outdir: argv.output,
       targetLanguage: argv.language,
       classNamePrefix,
-    });
+      singleFile: argv.singleFile,
+      goModuleName: argv.goModuleName,
+      goPackageName: argv.goPackageName,
+      goOptionsStyle: argv.goOptionsStyle,
+      codegenHooks: loadCodegenHooks(config.codegenHooks ?? []),
+      renames: [
+        ...parseRenames((argv.rename ?? []).map(String)),
+        ...argv.renameFile ? loadRenames(argv.renameFile) : [],
+      ],
+      enumsAsUnions: argv.enumsAsUnions,
+      emitValidations: argv.emitValidations,
+      applyDefaults: argv.applyDefaults,
+      unifyVersions: argv.unifyVersions,
+      dryRun: argv.dryRun,
+      cache: argv.cache ? new ImportCache(argv.cacheDir) : undefined,
+    };
+
+    if (argv.fromFile) {
+      // validate all entries before importing any of them
+      const manifest = await readImportManifest(argv.fromFile);
+      const results = await importBatch(manifest, argv, options, argv.concurrency);
+      printImportBatchSummary(results);
+
+      const failed = results.filter(r => r.error).length;
+      if (failed > 0) {
+        throw new Error(`${failed} of ${results.length} imports of ${argv.fromFile} failed`);
+      }
+      return;
+    }
+
+    await importDispatch(specs, argv, options);
   }
 }
 
@@ -4,6 +4,12 @@ import * as pacmak from 'jsii-pacmak';
 import * as pacmakv from 'jsii-pacmak/lib/targets/version-utils';
 import { sscaff } from 'sscaff';
 import * as yargs from 'yargs';
+import { Language } from '../../import/base';
+import { DEFAULT_API_VERSION, ImportKubernetesApi } from '../../import/k8s';
+import { ChartCode, emptyChartCode, FROM_EXISTING_LANGUAGES, generateChartCode, readExistingManifests } from '../../init/from-existing';
+import { fetchTemplate } from '../../init/template';
+import { getLogLevel, logger } from '../../logger';
+import { mkdtemp } from '../../util';
 
 const pkgroot = path.join(__dirname, '..', '..', '..');
 
@@ -14,32 +20,95 @@ const templatesDir = path.join(pkgroot, 'templates');
 const availableTemplates = fs.readdirSync(templatesDir).filter(x => !x.startsWith('.'));
 
 class Command implements yargs.CommandModule {
-  public readonly command = 'init TYPE';
+  public readonly command = 'init [TYPE]';
   public readonly describe = 'Create a new cdk8s project from a template.';
   public readonly builder = (args: yargs.Argv) => args
-    .positional('TYPE', { demandOption: true, desc: 'Project type' })
+    .positional('TYPE', { desc: 'Project type (required unless --template-url is used)' })
+    .option('from-existing', { type: 'string', required: false, desc: 'Generate chart code that recreates the resources of an existing manifest file or directory of manifests' })
+    .option('template-url', { type: 'string', required: false, desc: 'Create the project from a template archive (.tgz, .tar.gz or .tar) or Git repository (git+https://domain/REPOSITORY.git[//SUBDIR][?ref=REF]) instead of a built-in template. The template must contain a cdk8s.yaml with its "language" and uses the same variables as the built-in templates' })
     .showHelpOnFail(false)
-    .choices('TYPE', availableTemplates);
+    .choices('TYPE', availableTemplates)
+    .example('cdk8s init typescript-app --from-existing ../manifests', 'Creates a project with a chart that defines the resources of the manifests in "../manifests"')
+    .example('cdk8s init --template-url https://example.com/templates/app.tgz', 'Creates a project from a template archive')
+    .example('cdk8s init --template-url git+https://github.com/org/templates.git//typescript?ref=v1', 'Creates a project from a directory of a Git repository (requires "git")');
 
   public async handler(argv: any) {
+    if (!argv.type && !argv.templateUrl) {
+      throw new Error('Specify a project type or --template-url');
+    }
+
+    if (argv.type && argv.templateUrl) {
+      throw new Error('A project type and \'--template-url\' are mutually exclusive. Please only use one.');
+    }
+
     if (fs.readdirSync('.').filter(f => !f.startsWith('.')).length > 0) {
-      console.error('Cannot initialize a project in a non-empty directory');
+      logger.error('Cannot initialize a project in a non-empty directory');
       process.exit(1);
     }
 
-    console.error(`Initializing a project from the ${argv.type} template`);
-    const templatePath = path.join(templatesDir, argv.type);
-    const deps: any = await determineDeps();
+    if (argv.templateUrl) {
+      await mkdtemp(async workdir => {
+        logger.info(`Fetching the template ${argv.templateUrl}`);
+        const template = await fetchTemplate(argv.templateUrl, workdir);
+
+        logger.info(`Initializing a ${template.language} project from ${argv.templateUrl}`);
+        await scaffold(template.dir, argv.fromExisting ? await generateChart(template.language, argv.fromExisting) : emptyChartCode(template.language));
+      });
+      return;
+    }
 
-    try {
-      await sscaff(templatePath, '.', deps);
-    } catch (er) {
-      const e = er as any;
-      throw new Error(`error during project initialization: ${e.stack}\nSTDOUT:\n${e.stdout?.toString()}\nSTDERR:\n${e.stderr?.toString()}`);
+    const language = argv.type.split('-')[0] as Language;
+    if (argv.fromExisting && !argv.type.endsWith('-app')) {
+      throw new Error(`--from-existing is only supported for the ${FROM_EXISTING_LANGUAGES.map(l => `${l}-app`).join(' and ')} templates`);
     }
+    const chart = argv.fromExisting ? await generateChart(language, argv.fromExisting) : emptyChartCode(language);
+
+    logger.info(`Initializing a project from the ${argv.type} template`);
+    await scaffold(path.join(templatesDir, argv.type), chart);
   }
 }
 
+/**
+ * Scaffolds a template into the working directory, substituting the
+ * dependency versions and chart code.
+ */
+async function scaffold(templatePath: string, chart: ChartCode) {
+  const deps: any = {
+    ...await determineDeps(),
+    chart_imports: chart.imports,
+    chart_resources: chart.resources,
+
+    // the hooks of the templates log their progress at this level
+    log_level: getLogLevel(),
+  };
+
+  try {
+    await sscaff(templatePath, '.', deps);
+  } catch (er) {
+    const e = er as any;
+    throw new Error(`error during project initialization: ${e.stack}\nSTDOUT:\n${e.stdout?.toString()}\nSTDERR:\n${e.stderr?.toString()}`);
+  }
+}
+
+async function generateChart(language: Language, source: string): Promise<ChartCode> {
+  if (!FROM_EXISTING_LANGUAGES.includes(language)) {
+    throw new Error(`--from-existing is only supported for the ${FROM_EXISTING_LANGUAGES.map(l => `${l}-app`).join(' and ')} templates`);
+  }
+
+  const resources = await readExistingManifests(source);
+  logger.info(`Generating chart code for ${resources.length} resource(s) from ${source}`);
+
+  // without the schema all resources are defined as an ApiObject
+  let schema;
+  try {
+    schema = await new ImportKubernetesApi({ apiVersion: DEFAULT_API_VERSION }).loadSchema();
+  } catch (e) {
+    logger.warn(`Unable to load the k8s schema, all resources are defined as ApiObject: ${e}`);
+  }
+
+  return generateChartCode(language, resources, schema);
+}
+
 async function determineDeps(): Promise<Deps> {
   const cdk8s = new ModuleVersion('cdk8s', { jsii: true });
   const cdk8sPlus = new ModuleVersion('cdk8s-plus-22', { jsii: true });
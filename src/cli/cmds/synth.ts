@@ -2,10 +2,27 @@ import * as path from 'path';
 import * as fs from 'fs-extra';
 import * as yargs from 'yargs';
 import { readConfigSync } from '../../config';
-import { synthApp, mkdtemp } from '../../util';
+import { logger } from '../../logger';
+import { loadSynthPlugins } from '../../plugins/transform';
+import { findViolations, printValidationReports, validateManifests, ValidationSeverity } from '../../plugins/validation';
+import { ApplyMode, DEFAULT_FIELD_MANAGER, writeApplyScript } from '../../synth/apply';
+import { conformManifests, DEFAULT_SCHEMA_CACHE_DIR, importedCrds, kubernetesVersion, printConformReport } from '../../synth/conform';
+import { resolveContext } from '../../synth/contexts';
+import { writeKustomization } from '../../synth/kustomize';
+import { checkOutputSize, checkResourceLimits, DEFAULT_CHART_RESOURCES_WARNING } from '../../synth/limits';
+import { addContentHash, CONTENT_HASH_ANNOTATION, Manifest, OutputFormat, readManifests, serializeResources, sortKeys, writeManifests } from '../../synth/manifests';
+import { DEFAULT_NAMESPACE, writeByNamespace } from '../../synth/namespaces';
+import { validateOutputPath, writeOutputPath } from '../../synth/output-path';
+import { synthManifests } from '../../synth/pipeline';
+import { resourceCharts, summarizeManifests, writeSummary } from '../../synth/summary';
+import { compareSnapshots, DEFAULT_WATCH_EXCLUDE, formatChanges, snapshotManifests, watchSources } from '../../synth/watch';
+import { mkdtemp } from '../../util';
 
 const config = readConfigSync();
 
+// exit code used when validation violations are found
+const VALIDATION_FAILED_EXIT_CODE = 2;
+
 class Command implements yargs.CommandModule {
   public readonly command = 'synth';
   public readonly describe = 'Synthesizes Kubernetes manifests for all charts in your app.';
@@ -14,30 +31,257 @@ class Command implements yargs.CommandModule {
   public readonly builder = (args: yargs.Argv) => args
     .option('app', { default: config.app, required: true, desc: 'Command to use in order to execute cdk8s app', alias: 'a' })
     .option('output', { default: config.output, required: false, desc: 'Output directory', alias: 'o' })
-    .option('stdout', { type: 'boolean', required: false, desc: 'Write synthesized manifests to STDOUT instead of the output directory', alias: 'p' });
+    .option('stdout', { type: 'boolean', required: false, desc: 'Write synthesized manifests to STDOUT instead of the output directory', alias: 'p' })
+    .option('context', { type: 'string', required: false, desc: 'Synthesize for a context defined in the "contexts" section of cdk8s.yaml. The app can read it from the CDK8S_CONTEXT (name) and CDK8S_KUBE_CONTEXT (kubeconfig context) environment variables' })
+    .option('chart', { type: 'string', required: false, desc: 'Only write the manifests of the chart with this construct id' })
+    .option('validate', { type: 'boolean', default: false, required: false, desc: `Run the validation plugins configured in cdk8s.yaml and exit with code ${VALIDATION_FAILED_EXIT_CODE} on violations` })
+    .option('validate-severity', { type: 'string', default: ValidationSeverity.LOW, required: false, desc: 'Minimum severity of violations that fail validation', choices: Object.values(ValidationSeverity) })
+    .option('validation-report-output-file', { type: 'string', required: false, desc: 'Write the validation reports as JSON to this file' })
+    .option('conform', { type: 'boolean', default: false, required: false, desc: `Validate the synthesized resources against the JSON schemas of the Kubernetes version of the "k8s" import in cdk8s.yaml (like kubeconform) and exit with code ${VALIDATION_FAILED_EXIT_CODE} if any is invalid. Custom resources are validated if their CRD is synthesized or imported from a local file` })
+    .option('conform-cache-dir', { type: 'string', default: DEFAULT_SCHEMA_CACHE_DIR, required: false, desc: 'The directory the downloaded schemas of --conform are cached in' })
+    .option('apply-mode', { type: 'string', default: ApplyMode.CLIENT_SIDE, required: false, desc: `Prepare the manifests for this apply mode. "${ApplyMode.SERVER_SIDE}" also emits an apply script`, choices: Object.values(ApplyMode) })
+    .option('field-manager', { type: 'string', default: DEFAULT_FIELD_MANAGER, required: false, desc: 'Field manager used by the server-side apply script' })
+    .option('format', { type: 'string', default: OutputFormat.YAML, required: false, desc: 'Format of the synthesized manifests. "json" writes a JSON array and "json-stream" newline-delimited JSON per chart', choices: Object.values(OutputFormat), alias: 'output-format' })
+    .option('compress', { type: 'boolean', default: false, required: false, desc: 'Write each manifest as a gzip-compressed file (e.g. "<chart>.k8s.yaml.gz")' })
+    .option('deterministic', { type: 'boolean', default: false, required: false, desc: `Annotate each resource with a hash of its content ("${CONTENT_HASH_ANNOTATION}") that does not depend on the order of its keys. Enables --sort-keys unless it is disabled explicitly` })
+    .option('sort-keys', { type: 'boolean', required: false, desc: 'Sort the keys of all objects within each resource ("apiVersion", "kind" and "metadata" stay first). The order of resources and array items is preserved' })
+    .option('split-by-namespace', { type: 'boolean', default: false, required: false, desc: 'Write the resources of all charts to a file per namespace ("namespace-<namespace>.k8s.yaml") and the cluster-scoped resources to "cluster.k8s.yaml"' })
+    .option('default-namespace', { type: 'string', default: DEFAULT_NAMESPACE, required: false, desc: 'The namespace of namespaced resources without "metadata.namespace" when using --split-by-namespace' })
+    .option('kustomize', { type: 'boolean', default: false, required: false, desc: 'Also write a "kustomization.yaml" that references all synthesized manifests' })
+    .option('summary', { type: 'string', required: false, desc: 'Write a JSON summary of the synthesized resources (per chart, with the file of each resource), counts and warnings to this file' })
+    .option('max-resources', { type: 'number', required: false, desc: 'Fail if the app synthesizes more resources than this (e.g. because of a runaway loop). The output directory is removed' })
+    .option('max-output-bytes', { type: 'number', required: false, desc: 'Fail if the synthesized manifests are larger than this in total. The output directory is removed' })
+    .option('chart-resources-warning', { type: 'number', default: DEFAULT_CHART_RESOURCES_WARNING, required: false, desc: 'Warn about charts that synthesize more resources than this. Use 0 to disable the warning' })
+    .option('plugin-dir', { type: 'string', default: config.pluginDir, required: false, desc: 'Load the synth plugins in this directory. Each module exports a "transform(resources)" function that can add, modify or remove the synthesized resources. Plugins run in the order of their file names' })
+    .option('watch', { type: 'boolean', default: false, required: false, desc: 'Watch the source files of the app and synthesize again when they change', alias: 'w' })
+    .example('cdk8s synth --context staging', 'Synthesizes the app for the "staging" context of cdk8s.yaml')
+    .example('cdk8s synth --chart my-chart', 'Only writes the manifests of the "my-chart" chart')
+    .example('cdk8s synth --format json', 'Writes the resources of each chart to a "<chart>.k8s.json" file')
+    .example('cdk8s synth --compress', 'Writes the resources of each chart to a gzip-compressed "<chart>.k8s.yaml.gz" file')
+    .example('cdk8s synth --deterministic', 'Writes manifests with sorted keys and content hashes (e.g. for GitOps drift detection)')
+    .example('cdk8s synth --conform', 'Fails if a synthesized resource does not match the schema of its kind')
+    .example('cdk8s synth --split-by-namespace', 'Writes a file per namespace that can be applied independently')
+    .example('cdk8s synth --kustomize', 'Also writes a "kustomization.yaml" that can be used as a base of Kustomize overlays')
+    .example('cdk8s synth --summary dist/summary.json', 'Also writes a summary of the synthesized resources for downstream CI steps')
+    .example('cdk8s synth --max-resources 5000', 'Fails instead of writing the manifests if the app synthesizes more than 5000 resources')
+    .example('cdk8s synth --plugin-dir ./cdk8s-plugins', 'Transforms the synthesized resources by the plugins in "./cdk8s-plugins" (e.g. to inject labels) before writing them')
+    .example('cdk8s synth --watch', 'Synthesizes the app whenever a source file changes (configure which files using "watch" in cdk8s.yaml)');
 
   public async handler(argv: any) {
     const command = argv.app;
     const outdir = argv.output;
     const stdout = argv.stdout;
+    const validations = config.validations ?? [];
+    const patches = config.patches ?? [];
 
     if (outdir !== config.output && stdout) {
       throw new Error('\'--output\' and \'--stdout\' are mutually exclusive. Please only use one.');
     }
 
-    await fs.remove(outdir);
+    if (argv.watch && stdout) {
+      throw new Error('\'--watch\' and \'--stdout\' are mutually exclusive. Please only use one.');
+    }
+
+    if (argv.compress && stdout) {
+      throw new Error('\'--compress\' and \'--stdout\' are mutually exclusive. Pipe the output to "gzip" instead.');
+    }
+
+    if (argv.kustomize && (argv.compress || (argv.format ?? OutputFormat.YAML) !== OutputFormat.YAML)) {
+      throw new Error('\'--kustomize\' can only be used with uncompressed YAML manifests.');
+    }
+
+    if (argv.validate && validations.length === 0) {
+      throw new Error('\'--validate\' requires at least one plugin in the "validations" section of cdk8s.yaml.');
+    }
+
+    if (config.outputPath) {
+      validateOutputPath(config.outputPath);
+    }
+
+    if (argv.splitByNamespace && config.outputPath) {
+      throw new Error('\'--split-by-namespace\' cannot be used with "outputPath" in cdk8s.yaml. Use "{namespace}" in "outputPath" instead.');
+    }
+
+    const plugins = argv.pluginDir ? await loadSynthPlugins(argv.pluginDir) : [];
+
+    // resolve the context before removing the previous output
+    const env = argv.context ? await resolveContext(argv.context, config.contexts ?? { }) : { };
+
+    // nothing is written to the output directory when writing to STDOUT
+    if (!stdout) {
+      await fs.remove(outdir);
+    }
+
+    let violations = 0;
+    const validate = async (manifests: string[]) => {
+      if (validations.length === 0) {
+        return;
+      }
+
+      const reports = await validateManifests(validations, manifests);
+      printValidationReports(reports);
+
+      if (argv.validationReportOutputFile) {
+        await fs.writeJson(argv.validationReportOutputFile, reports, { spaces: 2 });
+      }
+
+      violations = findViolations(reports, argv.validateSeverity).length;
+    };
+
+    let invalid = 0;
+    const conform = async (manifests: Manifest[]) => {
+      const report = await conformManifests(manifests, {
+        kubernetesVersion: kubernetesVersion(config.imports),
+        cacheDir: argv.conformCacheDir ?? DEFAULT_SCHEMA_CACHE_DIR,
+        crds: await importedCrds(config.imports),
+      });
+      printConformReport(report);
+      invalid = report.violations.length;
+    };
+
+    const format: OutputFormat = argv.format ?? OutputFormat.YAML;
+    const sortResourceKeys: boolean = argv.sortKeys ?? argv.deterministic ?? false;
+
+    const synth = async (dir: string) => {
+      // nothing is left to be applied if a limit is exceeded
+      const withinLimits = async <T>(check: () => T | Promise<T>): Promise<T> => {
+        try {
+          return await check();
+        } catch (e) {
+          await fs.remove(dir);
+          throw e;
+        }
+      };
+
+      let manifests = await synthManifests(command, dir, {
+        env,
+        chart: argv.chart,
+        patches,
+        plugins,
+        applyMode: argv.applyMode,
+      });
+      let files = manifests.map(m => path.join(dir, m.file));
+
+      const warnings = await withinLimits(() => checkResourceLimits(manifests, {
+        maxResources: argv.maxResources,
+        chartResourcesWarning: argv.chartResourcesWarning ?? DEFAULT_CHART_RESOURCES_WARNING,
+      }));
+      warnings.forEach(warning => logger.warn(warning));
+
+      // the files are named after the charts until they are moved
+      const charts = resourceCharts(manifests);
+
+      if (config.outputPath) {
+        manifests = await writeOutputPath(dir, manifests, config.outputPath);
+        files = manifests.map(m => path.join(dir, m.file));
+      }
+
+      if (argv.splitByNamespace) {
+        manifests = await writeByNamespace(dir, manifests, argv.defaultNamespace ?? DEFAULT_NAMESPACE);
+        files = manifests.map(m => path.join(dir, m.file));
+      }
+
+      // the hash does not depend on the order of the keys
+      if (argv.deterministic) {
+        manifests = manifests.map(m => ({ ...m, resources: m.resources.map(addContentHash) }));
+      }
+
+      if (sortResourceKeys) {
+        manifests = manifests.map(m => ({ ...m, resources: m.resources.map(sortKeys) }));
+      }
+
+      if (argv.deterministic || sortResourceKeys) {
+        await writeManifests(dir, manifests);
+      }
+
+      // validation plugins always receive the YAML manifests
+      await validate(files);
+
+      if (format !== OutputFormat.YAML || argv.compress) {
+        manifests = await writeManifests(dir, manifests, format, argv.compress);
+      }
+
+      if (argv.maxOutputBytes !== undefined) {
+        await withinLimits(() => checkOutputSize(dir, manifests, argv.maxOutputBytes));
+      }
+
+      if (argv.conform) {
+        await conform(manifests);
+      }
+
+      // there is no directory to apply when writing to STDOUT
+      if (argv.applyMode === ApplyMode.SERVER_SIDE && !stdout) {
+        await writeApplyScript(dir, manifests, argv.fieldManager ?? DEFAULT_FIELD_MANAGER);
+      }
+
+      if (argv.kustomize && !stdout) {
+        await writeKustomization(dir, manifests);
+      }
+
+      // files are not known when writing to STDOUT
+      if (argv.summary) {
+        const summary = summarizeManifests(manifests, { files: !stdout, charts, warnings });
+        await writeSummary(argv.summary, summary);
+      }
+    };
+
+    if (argv.watch) {
+      const resynth = async () => {
+        const before = await snapshotManifests(outdir);
+        try {
+          await fs.remove(outdir);
+          await synth(outdir);
+          logger.info(`Synthesized ${outdir}: ${formatChanges(compareSnapshots(before, await snapshotManifests(outdir)))}`);
+        } catch (e) {
+          // keep watching, the next change might fix it
+          logger.error(`Synthesis failed: ${e}`);
+        }
+      };
+
+      await resynth();
+
+      const watchOptions = {
+        dir: process.cwd(),
+        include: config.watch?.include ?? ['**/*'],
+        exclude: [
+          ...DEFAULT_WATCH_EXCLUDE,
+          `${path.relative(process.cwd(), path.resolve(outdir))}/**`,
+          ...config.watch?.exclude ?? [],
+        ],
+      };
+
+      logger.info('Watching for changes...');
+      watchSources(watchOptions, async changes => {
+        logger.info(`Detected changes: ${formatChanges(changes)}`);
+        await resynth();
+      });
+
+      // watch until interrupted
+      await new Promise<void>(() => undefined);
+      return;
+    }
 
     if (stdout) {
       await mkdtemp(async tempDir => {
-        await synthApp(command, tempDir);
+        await synth(tempDir);
 
-        const manifests = (await fs.readdir(tempDir)).filter(f => path.extname(f) === '.yaml');
-        for (const f of manifests) {
-          fs.createReadStream(path.join(tempDir, f)).pipe(process.stdout);
-        }
+        // all charts are written as a single stream (multi-document yaml, a
+        // json array or newline-delimited json) in the order of synthesis
+        const resources = (await readManifests(tempDir, format)).flatMap(m => m.resources);
+        process.stdout.write(serializeResources(resources, format));
       });
     } else {
-      await synthApp(command, outdir);
+      await synth(outdir);
+    }
+
+    if (argv.validate && violations > 0) {
+      logger.error(`Validation failed: found ${violations} violation(s) with severity "${argv.validateSeverity}" or higher`);
+      process.exit(VALIDATION_FAILED_EXIT_CODE);
+    }
+
+    if (argv.conform && invalid > 0) {
+      logger.error(`Conform failed: ${invalid} resource(s) do not match their schema`);
+      process.exit(VALIDATION_FAILED_EXIT_CODE);
     }
   }
 }
@@ -1,19 +1,23 @@
 import { yellow } from 'colors';
 import * as yargs from 'yargs';
+import { logger, LogLevel, setLogLevel } from '../logger';
 import { upgradeAvailable } from '../upgrades';
 
 async function main() {
   const ya = yargs
     .option('check-upgrade', { type: 'boolean', desc: 'Check for cdk8s-cli upgrade', default: true })
+    .option('log-level', { type: 'string', desc: 'The most verbose level of messages written to STDERR. STDOUT is reserved for the results of commands', default: LogLevel.INFO, choices: Object.values(LogLevel) })
+    .option('quiet', { type: 'boolean', desc: 'Only log errors (same as --log-level error)', default: false, alias: 'q' })
+    .middleware(argv => setLogLevel(argv.quiet ? LogLevel.ERROR : argv.logLevel as LogLevel), true)
     .check(argv => {
       if (argv.checkUpgrade) {
         const versions = upgradeAvailable();
         if (versions) {
-          console.error('------------------------------------------------------------------------------------------------');
-          console.error(yellow(`A new version ${versions.latest} of cdk8s-cli is available (current ${versions.current}).`));
-          console.error(yellow('Run "npm install -g cdk8s-cli" to install the latest version on your system.'));
-          console.error(yellow('For additional installation methods, see https://cdk8s.io/docs/latest/getting-started'));
-          console.error('------------------------------------------------------------------------------------------------');
+          logger.info('------------------------------------------------------------------------------------------------');
+          logger.info(yellow(`A new version ${versions.latest} of cdk8s-cli is available (current ${versions.current}).`));
+          logger.info(yellow('Run "npm install -g cdk8s-cli" to install the latest version on your system.'));
+          logger.info(yellow('For additional installation methods, see https://cdk8s.io/docs/latest/getting-started'));
+          logger.info('------------------------------------------------------------------------------------------------');
         }
       }
 
@@ -34,6 +38,6 @@ async function main() {
 }
 
 main().catch(e => {
-  console.error(e.stack);
+  logger.error(e.stack);
   process.exit(1);
 });
@@ -1,6 +1,7 @@
 import * as fs from 'fs-extra';
 import * as yaml from 'yaml';
 import { Language } from './import/base';
+import { PatchConfig } from './synth/patches';
 
 const CONFIG_FILE = 'cdk8s.yaml';
 
@@ -9,11 +10,86 @@ export interface ImportSpec {
   readonly source: string;
 }
 
+export interface ValidationConfig {
+  /**
+   * The module that exports the plugin. Relative paths are resolved from the
+   * project directory.
+   */
+  readonly package: string;
+
+  /**
+   * The name of the exported plugin class.
+   */
+  readonly class: string;
+
+  /**
+   * Properties passed to the plugin constructor.
+   */
+  readonly properties?: Record<string, any>;
+}
+
+export interface WatchConfig {
+  /**
+   * Glob patterns of the source files that trigger synthesis in watch mode.
+   *
+   * @default ["**\/*"]
+   */
+  readonly include?: string[];
+
+  /**
+   * Glob patterns of files to ignore in watch mode. The output directory,
+   * `node_modules` and `.git` are always ignored.
+   *
+   * @default []
+   */
+  readonly exclude?: string[];
+}
+
 export interface Config {
   readonly app?: string;
   readonly language?: Language;
   readonly output?: string;
+
+  /**
+   * Template of the path of each synthesized resource, relative to the output
+   * directory (e.g. "{chartId}/{kind}-{name}.yaml"). Available variables are
+   * `{chartId}`, `{kind}`, `{name}` and `{namespace}`. Without `{name}`,
+   * resources of a chart that render to the same path are written to the same
+   * file (e.g. "{chartId}/{namespace}.yaml"). With `{name}`, resources that
+   * render to the same path are an error.
+   *
+   * @default - one file per chart
+   */
+  readonly outputPath?: string;
   readonly imports?: string[];
+  readonly validations?: ValidationConfig[];
+  readonly watch?: WatchConfig;
+
+  /**
+   * Modules that post-process the files generated by `cdk8s import`. Each
+   * module exports a `transform(file, contents)` function that returns the
+   * new contents. Hooks run in order.
+   */
+  readonly codegenHooks?: string[];
+
+  /**
+   * Maps logical names of target clusters to kubeconfig contexts. The context
+   * selected with `cdk8s synth --context NAME` is exposed to the app through
+   * the `CDK8S_CONTEXT` and `CDK8S_KUBE_CONTEXT` environment variables.
+   */
+  readonly contexts?: Record<string, string>;
+
+  /**
+   * JSON Patch or strategic merge patches applied, in order, to the
+   * synthesized resources that match their target.
+   */
+  readonly patches?: PatchConfig[];
+
+  /**
+   * A directory of synth plugins (see `cdk8s synth --plugin-dir`). Relative
+   * to the project directory.
+   */
+  readonly pluginDir?: string;
 }
 
 const DEFAULTS: Config = {
@@ -2,7 +2,13 @@ import * as path from 'path';
 import { CodeMaker } from 'codemaker';
 import * as fs from 'fs-extra';
 import * as srcmak from 'jsii-srcmak';
+import { logger } from '../logger';
+import { CodegenHook, runCodegenHooks } from '../plugins/codegen';
 import { mkdtemp } from '../util';
+import { ModuleSummary, printDryRunSummary, summarizeModule } from './dry-run';
+import { enumsToUnions } from './enums';
+import { emitGoOptions, GoOptionsStyle } from './go-options';
+import { PropertyRename } from './rename';
 
 export enum Language {
   TYPESCRIPT = 'typescript',
@@ -30,27 +36,169 @@ export interface ImportOptions {
    * k8s imports will add a "Kube" prefix by default.
    */
   readonly classNamePrefix?: string;
+
+  /**
+   * Emit all modules into a single file (module) with this name.
+   *
+   * @default - a file is emitted for each module (e.g. API group)
+   */
+  readonly singleFile?: string;
+
+  /**
+   * The Go module path of the generated packages (only for Go).
+   *
+   * @default - derived from the `go.mod` file of the project and the output
+   * directory.
+   */
+  readonly goModuleName?: string;
+
+  /**
+   * The Go package name of the generated code (only for Go). Can only be used
+   * if a single module is imported.
+   *
+   * @default - derived from the module name
+   */
+  readonly goPackageName?: string;
+
+  /**
+   * How the constructors of the generated Go constructs receive their props
+   * (only for Go).
+   *
+   * @default GoOptionsStyle.STRUCT
+   */
+  readonly goOptionsStyle?: GoOptionsStyle;
+
+  /**
+   * Hooks that post-process every generated file, in order.
+   *
+   * @default - generated files are not modified
+   */
+  readonly codegenHooks?: CodegenHook[];
+
+  /**
+   * Overrides the names of generated members.
+   *
+   * @default - member names are derived from the property names
+   */
+  readonly renames?: PropertyRename[];
+
+  /**
+   * Emit string literal union types instead of enums (only for TypeScript).
+   *
+   * @default false
+   */
+  readonly enumsAsUnions?: boolean;
+
+  /**
+   * Check the schema constraints of custom resources (e.g. `minimum` or
+   * `pattern`) when constructs are created (only for CRDs).
+   *
+   * @default false
+   */
+  readonly emitValidations?: boolean;
+
+  /**
+   * Set the schema defaults (`default`) of the properties of custom resources
+   * that are left undefined when constructs are rendered, like the API server
+   * would (only for CRDs).
+   *
+   * @default false
+   */
+  readonly applyDefaults?: boolean;
+
+  /**
+   * Generate a single construct for all versions of a custom resource, with a
+   * `version` prop that selects the apiVersion (only for CRDs and TypeScript).
+   * Versions with different top-level fields are still generated separately.
+   *
+   * @default false
+   */
+  readonly unifyVersions?: boolean;
+
+  /**
+   * Generate the code without writing it, and print what would be written.
+   *
+   * @default false
+   */
+  readonly dryRun?: boolean;
 }
 
 export interface GenerateOptions {
   readonly classNamePrefix?: string;
+  readonly emitValidations?: boolean;
+  readonly applyDefaults?: boolean;
+  readonly unifyVersions?: boolean;
+  readonly renames?: PropertyRename[];
 }
 
 export abstract class ImportBase {
   public abstract get moduleNames(): string[];
 
+  /**
+   * Whether the importer applies `renames`.
+   */
+  protected get supportsRenames(): boolean {
+    return false;
+  }
+
+  /**
+   * Post-processes the generated TypeScript code of a file. It is not applied
+   * to the code compiled by jsii for the other languages.
+   */
+  protected transformTypeScript(code: string): string {
+    return code;
+  }
+
   protected abstract generateTypeScript(code: CodeMaker, moduleName: string, options: GenerateOptions): Promise<void>;
 
-  public async import(options: ImportOptions) {
+  /**
+   * Generates the code for multiple modules into a single file. Importers that
+   * emit more than one module should override this in order to avoid duplicate
+   * declarations.
+   */
+  protected async generateMergedTypeScript(code: CodeMaker, moduleNames: string[], options: GenerateOptions): Promise<void> {
+    for (const moduleName of moduleNames) {
+      await this.generateTypeScript(code, moduleName, options);
+    }
+  }
+
+  /**
+   * Generates the code of all modules.
+   *
+   * @returns the files and directories that were emitted (or would be emitted
+   * in a dry run)
+   */
+  public async import(options: ImportOptions): Promise<string[]> {
     const code = new CodeMaker();
+    const allEmitted = new Array<string>();
+    const dryRun = options.dryRun ?? false;
+    const summaries = new Array<ModuleSummary>();
 
     const outdir = path.resolve(options.outdir);
-    await fs.mkdirp(outdir);
+    if (!dryRun) {
+      await fs.mkdirp(outdir);
+    }
     const isTypescript = options.targetLanguage === Language.TYPESCRIPT;
     const { moduleNamePrefix } = options;
 
     if (this.moduleNames.length === 0) {
-      console.error('warning: no definitions to import');
+      logger.warn('no definitions to import');
+    }
+
+    if (options.goPackageName && this.moduleNames.length > 1 && !options.singleFile) {
+      throw new Error(`A Go package name can only be specified when importing a single module, but found ${this.moduleNames.length} (${this.moduleNames.join(', ')}). Use a single file to merge them.`);
+    }
+
+    if (options.enumsAsUnions && !isTypescript) {
+      logger.warn(`union types are only supported for TypeScript, emitting enums for ${options.targetLanguage}`);
+    }
+
+    if ((options.renames ?? []).length > 0 && !this.supportsRenames) {
+      logger.warn('renames are only supported for custom resource definitions, ignoring them');
+    }
+
+    if (options.unifyVersions && !isTypescript) {
+      logger.warn(`unified versions are only supported for TypeScript, emitting a construct per version for ${options.targetLanguage}`);
     }
 
     const mapFunc = ( origName: string ) => {
@@ -68,32 +216,51 @@ export abstract class ImportBase {
     };
 
     // sort to ensure python writes parent packages first, so children are not deleted
-    const modules = this.moduleNames.map(mapFunc).sort((a: any, b: any) => a.name.localeCompare(b.name));
+    const modules = options.singleFile
+      ? [{ origName: options.singleFile, name: options.singleFile }]
+      : this.moduleNames.map(mapFunc).sort((a: any, b: any) => a.name.localeCompare(b.name));
+
+    // the typescript files are saved once all modules are generated
+    const typescriptFiles = new Array<string>();
 
     for (const module of modules) {
       // output the name of the imported resource
-      console.log(module.origName);
+      logger.info(module.origName);
 
       const fileName = moduleNamePrefix ? `${moduleNamePrefix}-${module.name}.ts` : `${module.name}.ts`;
       code.openFile(fileName);
       code.indentation = 2;
-      await this.generateTypeScript(code, module.origName, {
+      const generateOptions: GenerateOptions = {
         classNamePrefix: options.classNamePrefix,
-      });
+        emitValidations: options.emitValidations,
+        applyDefaults: options.applyDefaults,
+        unifyVersions: options.unifyVersions && isTypescript,
+        renames: options.renames,
+      };
+
+      if (options.singleFile) {
+        await this.generateMergedTypeScript(code, this.moduleNames, generateOptions);
+      } else {
+        await this.generateTypeScript(code, module.origName, generateOptions);
+      }
 
       code.closeFile(fileName);
 
+      // the files and directories emitted for this module
+      const emitted = new Array<string>();
+
       if (isTypescript) {
-        await code.save(outdir);
+        typescriptFiles.push(fileName);
+        emitted.push(path.join(outdir, fileName));
       }
 
-      if (!isTypescript || options.outputJsii) {
+      if (!isTypescript || options.outputJsii || dryRun) {
         await mkdtemp(async staging => {
 
           // this is not typescript, so we generate in a staging directory and
           // use jsii-srcmak to compile and extract the language-specific source
           // into our project.
-          await code.save(staging);
+          await this.save(code, staging, [], false);
 
           // these are the module dependencies we compile against
           const deps = ['@types/node', 'constructs', 'cdk8s'];
@@ -109,6 +276,12 @@ export abstract class ImportBase {
             opts.jsii = { path: options.outputJsii };
           }
 
+          // the functional options of go are generated from the jsii assembly
+          const goOptions = options.targetLanguage === Language.GO && options.goOptionsStyle === GoOptionsStyle.FUNCTIONAL;
+          if (goOptions && !opts.jsii) {
+            opts.jsii = { path: path.join(staging, '.jsii') };
+          }
+
           // python!
           if (options.targetLanguage === Language.PYTHON) {
             const moduleName = `${moduleNamePrefix ? `${moduleNamePrefix}.${module.name}` : module.name}`.replace(/-/g, '_');
@@ -116,6 +289,7 @@ export abstract class ImportBase {
               outdir: outdir,
               moduleName,
             };
+            emitted.push(path.join(outdir, ...moduleName.split('.')));
           }
 
           // java!
@@ -125,13 +299,11 @@ export abstract class ImportBase {
               outdir: '.',
               package: `imports.${moduleNamePrefix ? moduleNamePrefix + '.' + javaName : javaName}`,
             };
+            emitted.push(path.join(opts.java.outdir, 'src', 'main', 'java', ...opts.java.package.split('.')));
           }
 
           // go!
           if (options.targetLanguage === Language.GO) {
-            const { userModuleName, userModulePath } = this.getGoModuleName(outdir);
-            const relativeDir = path.relative(userModulePath, outdir);
-
             // go package names may only consist of letters or digits.
             // underscores are allowed too, but they are less idiomatic
             // this converts e.g. "cert-manager.path.to.url" to "certmanagerpathtourl"
@@ -139,15 +311,75 @@ export abstract class ImportBase {
 
             opts.golang = {
               outdir: outdir,
-              moduleName: `${userModuleName}/${relativeDir}`,
-              packageName: moduleNamePrefix ? moduleNamePrefix + '_' + importModuleName : importModuleName,
+              moduleName: options.goModuleName ?? this.inferGoModuleName(outdir),
+              packageName: options.goPackageName ?? (moduleNamePrefix ? moduleNamePrefix + '_' + importModuleName : importModuleName),
             };
+            emitted.push(path.join(outdir, opts.golang.packageName));
+          }
+
+          if (dryRun) {
+            summaries.push(summarizeModule(module.origName, emitted, await fs.readFile(path.join(staging, fileName), 'utf-8')));
+            return;
           }
 
           await srcmak.srcmak(staging, opts);
+
+          if (goOptions && opts.jsii && opts.golang) {
+            const source = emitGoOptions(await fs.readJson(opts.jsii.path), opts.golang.packageName);
+            if (source) {
+              await fs.writeFile(path.join(opts.golang.outdir, opts.golang.packageName, `${opts.golang.packageName}_options.go`), source);
+            }
+          }
         });
       }
+
+      allEmitted.push(...emitted);
     }
+
+    if (isTypescript && !dryRun) {
+      await this.save(code, outdir, typescriptFiles, options.enumsAsUnions);
+    }
+
+    // hooks run once all files are written, since saving the code of a module
+    // rewrites the files of the previous modules
+    if (!dryRun) {
+      await runCodegenHooks(options.codegenHooks ?? [], allEmitted);
+    }
+
+    if (dryRun) {
+      printDryRunSummary(summaries);
+    }
+
+    return allEmitted;
+  }
+
+  /**
+   * Saves the generated TypeScript code. Union types are only emitted into
+   * TypeScript output since jsii cannot compile them.
+   *
+   * The code maker writes all files generated so far on each save, so the
+   * files are post-processed after the last save into a directory.
+   *
+   * @param fileNames the files to post-process
+   */
+  private async save(code: CodeMaker, dir: string, fileNames: string[], unions: boolean = false) {
+    await code.save(dir);
+
+    for (const fileName of fileNames) {
+      const file = path.join(dir, fileName);
+      const source = this.transformTypeScript(await fs.readFile(file, 'utf-8'));
+      await fs.writeFile(file, unions ? enumsToUnions(source) : source);
+    }
+  }
+
+  /**
+   * Returns the Go module path of the output directory, based on the module
+   * declared in the closest go.mod file.
+   */
+  private inferGoModuleName(outdir: string) {
+    const { userModuleName, userModulePath } = this.getGoModuleName(outdir);
+    const relativeDir = path.relative(userModulePath, outdir);
+    return `${userModuleName}/${relativeDir}`;
   }
 
   /**
@@ -0,0 +1,186 @@
+import * as path from 'path';
+import * as fs from 'fs-extra';
+import * as yaml from 'yaml';
+import { logger } from '../logger';
+import { mapConcurrently } from '../util';
+import { Language } from './base';
+import { importDispatch, ImportDispatchOptions } from './dispatch';
+
+/**
+ * The default number of sources of an import manifest that are imported at
+ * the same time.
+ */
+export const DEFAULT_IMPORT_CONCURRENCY = 4;
+
+const ENTRY_KEYS = ['source', 'name', 'language', 'output', 'includeCrd', 'excludeCrd'];
+
+/**
+ * A source listed in an import manifest.
+ */
+export interface ImportManifestEntry {
+  /**
+   * The import spec (e.g. "k8s", "crd.yaml" or "helm:https://domain/CHART").
+   */
+  readonly source: string;
+
+  /**
+   * The module name prefix (like `NAME:=SPEC`).
+   *
+   * @default - derived from the source
+   */
+  readonly name?: string;
+
+  /**
+   * Overrides the language of the import.
+   *
+   * @default - the language of the command
+   */
+  readonly language?: Language;
+
+  /**
+   * The output directory, relative to the output directory of the command.
+   *
+   * @default - the output directory of the command
+   */
+  readonly output?: string;
+
+  /**
+   * Overrides the "--include-crd" patterns of the command.
+   */
+  readonly includeCrd?: string[];
+
+  /**
+   * Overrides the "--exclude-crd" patterns of the command.
+   */
+  readonly excludeCrd?: string[];
+}
+
+/**
+ * A file that lists the sources imported by `cdk8s import --from-file`.
+ */
+export interface ImportManifest {
+  readonly imports: ImportManifestEntry[];
+}
+
+/**
+ * The outcome of importing a source of an import manifest.
+ */
+export interface ImportBatchResult {
+  readonly entry: ImportManifestEntry;
+
+  /**
+   * The files and directories that were emitted.
+   */
+  readonly emitted: string[];
+
+  /**
+   * The error of a failed import.
+   */
+  readonly error?: Error;
+}
+
+/**
+ * Reads an import manifest (YAML or JSON) and validates all of its entries.
+ */
+export async function readImportManifest(file: string): Promise<ImportManifest> {
+  const invalid = (reason: string) => new Error(`Invalid import manifest ${file}: ${reason}`);
+
+  let manifest;
+  try {
+    manifest = yaml.parse(await fs.readFile(file, 'utf-8'));
+  } catch (e) {
+    throw invalid(`${e}`);
+  }
+
+  if (!Array.isArray(manifest?.imports) || manifest.imports.length === 0) {
+    throw invalid('"imports" must be a non-empty list of sources');
+  }
+
+  const languages: string[] = Object.values(Language);
+  const imports = manifest.imports.map((entry: any, i: number): ImportManifestEntry => {
+    const at = `imports[${i}]`;
+
+    if (typeof(entry) === 'string') {
+      return { source: entry };
+    }
+
+    if (typeof(entry) !== 'object' || entry === null) {
+      throw invalid(`${at} must be a source or an object with a "source"`);
+    }
+
+    const unknown = Object.keys(entry).filter(key => !ENTRY_KEYS.includes(key));
+    if (unknown.length > 0) {
+      throw invalid(`${at} has unknown keys ${unknown.map(k => `"${k}"`).join(', ')}. Supported keys are ${ENTRY_KEYS.join(', ')}`);
+    }
+
+    if (typeof(entry.source) !== 'string' || !entry.source) {
+      throw invalid(`${at}.source must be a non-empty string`);
+    }
+
+    for (const key of ['name', 'output']) {
+      if (entry[key] !== undefined && typeof(entry[key]) !== 'string') {
+        throw invalid(`${at}.${key} must be a string`);
+      }
+    }
+
+    if (entry.language !== undefined && !languages.includes(entry.language)) {
+      throw invalid(`${at}.language must be one of ${languages.join(', ')} (got "${entry.language}")`);
+    }
+
+    for (const key of ['includeCrd', 'excludeCrd']) {
+      if (entry[key] !== undefined && (!Array.isArray(entry[key]) || entry[key].some((p: any) => typeof(p) !== 'string'))) {
+        throw invalid(`${at}.${key} must be a list of strings`);
+      }
+    }
+
+    return entry;
+  });
+
+  return { imports };
+}
+
+/**
+ * Imports all sources of an import manifest, running at most `concurrency`
+ * imports at a time. A failed import does not stop the others.
+ *
+ * @returns the outcome of each import, in the order of the manifest
+ */
+export async function importBatch(manifest: ImportManifest, argv: any, options: ImportDispatchOptions, concurrency: number = DEFAULT_IMPORT_CONCURRENCY): Promise<ImportBatchResult[]> {
+  return mapConcurrently(manifest.imports, concurrency, async (entry): Promise<ImportBatchResult> => {
+    const entryArgv = {
+      ...argv,
+      includeCrd: entry.includeCrd ?? argv.includeCrd,
+      excludeCrd: entry.excludeCrd ?? argv.excludeCrd,
+    };
+
+    const entryOptions: ImportDispatchOptions = {
+      ...options,
+      targetLanguage: entry.language ?? options.targetLanguage,
+      outdir: entry.output ? path.join(options.outdir, entry.output) : options.outdir,
+    };
+
+    try {
+      const emitted = await importDispatch([{ source: entry.source, moduleNamePrefix: entry.name }], entryArgv, entryOptions);
+      return { entry, emitted };
+    } catch (e) {
+      return { entry, emitted: [], error: e instanceof Error ? e : new Error(`${e}`) };
+    }
+  });
+}
+
+/**
+ * Prints the outcome of all imports of a manifest.
+ */
+export function printImportBatchSummary(results: ImportBatchResult[]) {
+  const failed = results.filter(r => r.error);
+
+  logger.info('');
+  logger.info(`Imported ${results.length - failed.length} of ${results.length} sources:`);
+  for (const { entry, emitted, error } of results) {
+    if (error) {
+      logger.error(`  failed  ${entry.source}: ${error.message}`);
+    } else {
+      logger.info(`  ok      ${entry.source} => ${emitted.map(p => path.relative(process.cwd(), p)).join(', ')}`);
+    }
+  }
+}
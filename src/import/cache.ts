@@ -0,0 +1,102 @@
+import { createHash } from 'crypto';
+import * as os from 'os';
+import * as path from 'path';
+import * as fs from 'fs-extra';
+
+// eslint-disable-next-line @typescript-eslint/no-require-imports
+const pkg = require('../../package.json');
+
+/**
+ * Where imports are cached by default.
+ */
+export const DEFAULT_IMPORT_CACHE_DIR = path.join(os.homedir(), '.cdk8s', 'import-cache');
+
+// bump to invalidate all existing cache entries (e.g. if the layout changes)
+const CACHE_FORMAT_VERSION = 1;
+
+const INDEX_FILE = 'index.json';
+
+interface CacheIndex {
+  /**
+   * The emitted files and directories, relative to the working directory. The
+   * copy of the i-th path is stored as a file (or directory) named "i".
+   */
+  readonly paths: string[];
+}
+
+/**
+ * Caches the code generated by `cdk8s import`, keyed by the content of the
+ * imported source and the options that affect the generated code.
+ */
+export class ImportCache {
+  constructor(private readonly dir: string) {
+  }
+
+  /**
+   * Returns the cache key of a source.
+   *
+   * @param content the content of the source (after it was fetched)
+   * @param options everything else that affects the generated code (e.g. the
+   * target language)
+   */
+  public key(content: string, options: Record<string, any>): string {
+    const hash = createHash('sha256');
+    hash.update(JSON.stringify({
+      format: CACHE_FORMAT_VERSION,
+      version: pkg.version,
+      options,
+    }));
+    hash.update('\0');
+    hash.update(content);
+    return hash.digest('hex');
+  }
+
+  /**
+   * Copies the cached output of `key` into the working directory.
+   *
+   * @returns the restored paths, or `undefined` if there is no cache entry
+   */
+  public async restore(key: string): Promise<string[] | undefined> {
+    const entry = path.join(this.dir, key);
+    const indexFile = path.join(entry, INDEX_FILE);
+    if (!await fs.pathExists(indexFile)) {
+      return undefined;
+    }
+
+    const index: CacheIndex = await fs.readJson(indexFile);
+    const restored = new Array<string>();
+
+    for (const [i, p] of index.paths.entries()) {
+      const target = path.resolve(p);
+      await fs.copy(path.join(entry, `${i}`), target, { overwrite: true });
+      restored.push(target);
+    }
+
+    return restored;
+  }
+
+  /**
+   * Stores the emitted files and directories under `key`.
+   */
+  public async store(key: string, paths: string[]) {
+    const entry = path.join(this.dir, key);
+
+    // write to a staging directory first so that an interrupted import never
+    // leaves a partial entry behind
+    const staging = `${entry}.${process.pid}.tmp`;
+    await fs.remove(staging);
+
+    const index: CacheIndex = { paths: paths.map(p => path.relative(process.cwd(), path.resolve(p))) };
+    for (const [i, p] of paths.entries()) {
+      await fs.copy(p, path.join(staging, `${i}`));
+    }
+    await fs.writeJson(path.join(staging, INDEX_FILE), index);
+
+    try {
+      await fs.remove(entry);
+      await fs.rename(staging, entry);
+    } finally {
+      await fs.remove(staging);
+    }
+  }
+}
@@ -3,10 +3,157 @@ import { CodeMaker, toPascalCase } from 'codemaker';
 // eslint-disable-next-line import/no-extraneous-dependencies
 import { JSONSchema4 } from 'json-schema';
 import { TypeGenerator } from 'json2jsii';
+import { PropertyRename, renameSchemaProperties } from './rename';
 
 const MANIFEST_STATIC_METHOD = 'manifest';
 const GVK_STATIC = 'GVK';
 
+// schema keywords checked by the validations of `--emit-validations`
+const CONSTRAINT_KEYWORDS = ['minimum', 'maximum', 'exclusiveMinimum', 'exclusiveMaximum', 'multipleOf', 'minLength', 'maxLength',
+  'pattern', 'minItems', 'maxItems', 'uniqueItems', 'minProperties', 'maxProperties', 'required'];
+
+// checks a value against the constraints extracted by `extractConstraints`
+const CHECK_CONSTRAINTS_FUNCTION = [
+  '/**',
+  ' * Checks a value (in its JSON form) against schema constraints.',
+  ' *',
+  ' * @returns a description of each violation',
+  ' */',
+  'function checkConstraints(value: any, constraints: any, path: string): string[] {',
+  '  if (value === undefined || value === null) {',
+  '    return [];',
+  '  }',
+  '',
+  '  const violations = new Array<string>();',
+  '  const violation = (message: string) => violations.push(`${path} ${message}`);',
+  '',
+  '  if (typeof value === \'number\') {',
+  '    if (constraints.minimum !== undefined && (constraints.exclusiveMinimum ? value <= constraints.minimum : value < constraints.minimum)) {',
+  '      violation(`must be ${constraints.exclusiveMinimum ? \'greater than\' : \'at least\'} ${constraints.minimum} (got ${value})`);',
+  '    }',
+  '    if (constraints.maximum !== undefined && (constraints.exclusiveMaximum ? value >= constraints.maximum : value > constraints.maximum)) {',
+  '      violation(`must be ${constraints.exclusiveMaximum ? \'less than\' : \'at most\'} ${constraints.maximum} (got ${value})`);',
+  '    }',
+  '    if (constraints.multipleOf !== undefined && value % constraints.multipleOf !== 0) {',
+  '      violation(`must be a multiple of ${constraints.multipleOf} (got ${value})`);',
+  '    }',
+  '  }',
+  '',
+  '  if (typeof value === \'string\') {',
+  '    if (constraints.minLength !== undefined && value.length < constraints.minLength) {',
+  '      violation(`must be at least ${constraints.minLength} characters long (got "${value}")`);',
+  '    }',
+  '    if (constraints.maxLength !== undefined && value.length > constraints.maxLength) {',
+  '      violation(`must be at most ${constraints.maxLength} characters long (got "${value}")`);',
+  '    }',
+  '    if (constraints.pattern !== undefined && !new RegExp(constraints.pattern).test(value)) {',
+  '      violation(`must match the pattern "${constraints.pattern}" (got "${value}")`);',
+  '    }',
+  '  }',
+  '',
+  '  if (Array.isArray(value)) {',
+  '    if (constraints.minItems !== undefined && value.length < constraints.minItems) {',
+  '      violation(`must have at least ${constraints.minItems} items (got ${value.length})`);',
+  '    }',
+  '    if (constraints.maxItems !== undefined && value.length > constraints.maxItems) {',
+  '      violation(`must have at most ${constraints.maxItems} items (got ${value.length})`);',
+  '    }',
+  '    if (constraints.uniqueItems && new Set(value.map((item: any) => JSON.stringify(item))).size !== value.length) {',
+  '      violation(\'must not contain duplicate items\');',
+  '    }',
+  '    if (constraints.items) {',
+  '      value.forEach((item: any, i: number) => violations.push(...checkConstraints(item, constraints.items, `${path}[${i}]`)));',
+  '    }',
+  '  } else if (typeof value === \'object\') {',
+  '    const keys = Object.keys(value).filter((key: string) => value[key] !== undefined);',
+  '    for (const key of constraints.required ?? []) {',
+  '      if (!keys.includes(key)) {',
+  '        violation(`must have the property "${key}"`);',
+  '      }',
+  '    }',
+  '    if (constraints.minProperties !== undefined && keys.length < constraints.minProperties) {',
+  '      violation(`must have at least ${constraints.minProperties} properties (got ${keys.length})`);',
+  '    }',
+  '    if (constraints.maxProperties !== undefined && keys.length > constraints.maxProperties) {',
+  '      violation(`must have at most ${constraints.maxProperties} properties (got ${keys.length})`);',
+  '    }',
+  '    for (const key of keys) {',
+  '      const propertyConstraints = constraints.properties?.[key] ?? constraints.additionalProperties;',
+  '      if (propertyConstraints) {',
+  '        violations.push(...checkConstraints(value[key], propertyConstraints, `${path}.${key}`));',
+  '      }',
+  '    }',
+  '  }',
+  '',
+  '  return violations;',
+  '}',
+];
+
+// sets the defaults extracted by `extractDefaults`
+const APPLY_DEFAULTS_FUNCTION = [
+  '/**',
+  ' * Sets the schema defaults of the properties a value (in its JSON form) leaves',
+  ' * undefined, recursively.',
+  ' *',
+  ' * @returns a copy of the value with the defaults',
+  ' */',
+  'function applyDefaults(value: any, defaults: any): any {',
+  '  if (value === undefined || value === null || typeof value !== \'object\') {',
+  '    return value;',
+  '  }',
+  '',
+  '  if (Array.isArray(value)) {',
+  '    return defaults.items ? value.map((item: any) => applyDefaults(item, defaults.items)) : value;',
+  '  }',
+  '',
+  '  const result = { ...value };',
+  '  const properties = defaults.properties ?? {};',
+  '  for (const key of Object.keys(properties)) {',
+  '    const property = properties[key];',
+  '    if (result[key] === undefined && property.default !== undefined) {',
+  '      result[key] = JSON.parse(JSON.stringify(property.default));',
+  '    }',
+  '    if (result[key] !== undefined) {',
+  '      result[key] = applyDefaults(result[key], property);',
+  '    }',
+  '  }',
+  '  if (defaults.additionalProperties) {',
+  '    for (const key of Object.keys(result).filter(k => !(k in properties))) {',
+  '      result[key] = applyDefaults(result[key], defaults.additionalProperties);',
+  '    }',
+  '  }',
+  '',
+  '  return result;',
+  '}',
+];
+
+// restores the names of the properties renamed by `renameSchemaProperties`. a
+// static method, so that it is only emitted into files that rename properties
+const RESTORE_NAMES_METHOD = [
+  '/**',
+  ' * Restores the original field names of the renamed properties of a value',
+  ' * (in its JSON form), recursively.',
+  ' */',
+  'private static restoreNames(value: any, names: any): any {',
+  '  if (value === undefined || value === null || typeof value !== \'object\') {',
+  '    return value;',
+  '  }',
+  '',
+  '  if (Array.isArray(value)) {',
+  '    return names.items ? value.map((item: any) => this.restoreNames(item, names.items)) : value;',
+  '  }',
+  '',
+  '  const properties = names.properties ?? {};',
+  '  const result: any = {};',
+  '  for (const key of Object.keys(value)) {',
+  '    const property = properties[key] ?? names.additionalProperties;',
+  '    result[property?.name ?? key] = property ? this.restoreNames(value[key], property) : value[key];',
+  '  }',
+  '',
+  '  return result;',
+  '}',
+];
+
 export interface ApiObjectDefinition {
   readonly fqn: string;
   readonly group: string;
@@ -34,6 +181,39 @@ export interface ApiObjectDefinition {
    * @default ""
    */
   readonly suffix?: string;
+
+  /**
+   * Marks the construct as deprecated with this message (e.g. for deprecated
+   * CRD versions).
+   *
+   * @default - not deprecated
+   */
+  readonly deprecation?: string;
+
+  /**
+   * Check the constraints of the schema (e.g. `minimum` or `pattern`) in the
+   * constructor. Requires the header to be emitted with `validations`.
+   *
+   * @default false
+   */
+  readonly validations?: boolean;
+
+  /**
+   * Set the `default` of the schema of properties that are left undefined
+   * when the object is rendered. Requires the header to be emitted with
+   * `defaults`.
+   *
+   * @default false
+   */
+  readonly defaults?: boolean;
+
+  /**
+   * Overrides the names of the generated members. The manifest still uses the
+   * original field names.
+   *
+   * @default []
+   */
+  readonly renames?: PropertyRename[];
 }
 
 /**
@@ -41,8 +221,12 @@ export interface ApiObjectDefinition {
  *
  * @param custom - whether the header is being emitted for a custom resource
  * (imported from a CRD) or a core API object
+ * @param validations - whether to emit the function that checks the schema
+ * constraints of constructs with `validations`
+ * @param defaults - whether to emit the function that sets the schema defaults
+ * of constructs with `defaults`
  */
-export function emitHeader(code: CodeMaker, custom: boolean) {
+export function emitHeader(code: CodeMaker, custom: boolean, validations: boolean = false, defaults: boolean = false) {
   code.line('// generated by cdk8s');
   if (custom) {
     code.line('import { ApiObject, ApiObjectMetadata, GroupVersionKind } from \'cdk8s\';');
@@ -51,6 +235,114 @@ export function emitHeader(code: CodeMaker, custom: boolean) {
   }
   code.line('import { Construct } from \'constructs\';');
   code.line();
+
+  if (validations) {
+    CHECK_CONSTRAINTS_FUNCTION.forEach(line => code.line(line));
+    code.line();
+  }
+
+  if (defaults) {
+    APPLY_DEFAULTS_FUNCTION.forEach(line => code.line(line));
+    code.line();
+  }
+}
+
+/**
+ * Returns the constraints of a schema and its nested schemas (properties,
+ * items and maps), or `undefined` if there are none. Referenced schemas are
+ * not followed.
+ */
+export function extractConstraints(schema: JSONSchema4 | undefined): any {
+  if (!schema || typeof(schema) !== 'object' || schema.$ref) {
+    return undefined;
+  }
+
+  const constraints: any = { };
+  for (const key of CONSTRAINT_KEYWORDS) {
+    const value = schema[key];
+    if (value === undefined || value === false || (key === 'required' && (!Array.isArray(value) || value.length === 0))) {
+      continue;
+    }
+    constraints[key] = value;
+  }
+
+  const properties: Record<string, any> = { };
+  for (const [name, property] of Object.entries(schema.properties ?? { })) {
+    const c = extractConstraints(property);
+    if (c) {
+      properties[name] = c;
+    }
+  }
+  if (Object.keys(properties).length > 0) {
+    constraints.properties = properties;
+  }
+
+  const items = Array.isArray(schema.items) ? undefined : extractConstraints(schema.items);
+  if (items) {
+    constraints.items = items;
+  }
+
+  // maps only, since the constraints of declared properties are pruned
+  const values = (!schema.properties && typeof(schema.additionalProperties) === 'object') ? extractConstraints(schema.additionalProperties) : undefined;
+  if (values) {
+    constraints.additionalProperties = values;
+  }
+
+  return Object.keys(constraints).length > 0 ? constraints : undefined;
+}
+
+/**
+ * Returns the `default` values of the nested schemas of a schema (properties,
+ * items and maps), or `undefined` if there are none. Referenced schemas are
+ * not followed.
+ */
+export function extractDefaults(schema: JSONSchema4 | undefined): any {
+  if (!schema || typeof(schema) !== 'object' || schema.$ref) {
+    return undefined;
+  }
+
+  const defaults: any = { };
+  if (schema.default !== undefined) {
+    defaults.default = schema.default;
+  }
+
+  const properties: Record<string, any> = { };
+  for (const [name, property] of Object.entries(schema.properties ?? { })) {
+    const d = extractDefaults(property);
+    if (d) {
+      properties[name] = d;
+    }
+  }
+  if (Object.keys(properties).length > 0) {
+    defaults.properties = properties;
+  }
+
+  const items = Array.isArray(schema.items) ? undefined : extractDefaults(schema.items);
+  if (items) {
+    defaults.items = items;
+  }
+
+  const values = typeof(schema.additionalProperties) === 'object' ? extractDefaults(schema.additionalProperties) : undefined;
+  if (values) {
+    defaults.additionalProperties = values;
+  }
+
+  return Object.keys(defaults).length > 0 ? defaults : undefined;
+}
+
+/**
+ * Emits a constant with the constraints or defaults extracted from the schema
+ * of a construct.
+ */
+function emitSchemaConstant(code: CodeMaker, name: string, description: string, value: any) {
+  const lines = JSON.stringify(value, undefined, 2).split('\n');
+  code.line('/**');
+  code.line(` * ${description}`);
+  code.line(' */');
+  code.line(`const ${name}: any = ${lines[0]}`);
+  lines.slice(1, -1).forEach(line => code.line(line));
+  code.line(`${lines[lines.length - 1]};`);
+  code.line();
 }
 
 export function getTypeName(custom: boolean, kind: string, version: string) {
@@ -72,6 +364,35 @@ export function getPropsTypeName(def: ApiObjectDefinition) {
   return TypeGenerator.normalizeTypeName(`${constructName}Props`);
 }
 
+/**
+ * Returns the schema of the props of an API object: the schema of the object
+ * without "apiVersion", "kind" and "status".
+ */
+function propsStructSchema(def: ApiObjectDefinition): JSONSchema4 {
+  const copy: JSONSchema4 = { ...def.schema || {} };
+  const props = copy.properties = copy.properties || {};
+  delete props.apiVersion;
+  delete props.kind;
+  delete props.status;
+  delete copy['x-kubernetes-group-version-kind'];
+
+  copy.required = copy.required || [];
+
+  if (Array.isArray(copy.required)) {
+    copy.required = copy.required.filter(x => x !== 'apiVersion' && x !== 'kind' && x !== 'status');
+  }
+
+  if (def.custom) {
+    // add "metadata" field for all CRDs, overriding any existing typings.
+    // properties are emitted in the order they are declared, so "metadata"
+    // keeps its position or goes first if it's not declared.
+    const metadata = { $ref: '#/definitions/ApiObjectMetadata' };
+    copy.properties = ('metadata' in props) ? { ...props, metadata } : { metadata, ...props };
+  }
+
+  return copy;
+}
+
 export function generateConstruct(typegen: TypeGenerator, def: ApiObjectDefinition) {
   const constructName = getConstructTypeName(def);
 
@@ -81,40 +402,57 @@ export function generateConstruct(typegen: TypeGenerator, def: ApiObjectDefiniti
 
   typegen.emitCustomType(constructName, code => {
     const schema = def.schema;
+    const { schema: propsSchema, names } = renameSchemaProperties(propsStructSchema(def), def.fqn, def.renames ?? [], [getPropsTypeName(def)]);
 
     // `propsTypeName` could also be "any" if we can't parse the schema for some reason
     const propsTypeName = emitPropsStruct();
     const groupPrefix = def.group ? `${def.group}/` : '';
     const hasRequired = schema?.required && Array.isArray(schema.required) && schema.required.length > 0;
     const defaultProps = hasRequired ? '' : ' = {}';
+    const constraints = def.validations ? extractConstraints(propsSchema) : undefined;
+    const defaults = def.defaults ? extractDefaults(propsSchema) : undefined;
+    emitConstraints();
+    emitDefaults();
+    emitNames();
     emitConstruct();
 
     function emitPropsStruct() {
-      const propsSchema = createPropsStructSchema();
       const propsStructName = getPropsTypeName(def);
       return typegen.emitType(propsStructName, propsSchema, def.fqn);
     }
 
-    function createPropsStructSchema() {
-      const copy: JSONSchema4 = { ...def.schema || {} };
-      const props = copy.properties = copy.properties || {};
-      delete props.apiVersion;
-      delete props.kind;
-      delete props.status;
-      delete copy['x-kubernetes-group-version-kind'];
+    function emitConstraints() {
+      if (!constraints) {
+        return;
+      }
 
-      copy.required = copy.required || [];
+      emitSchemaConstant(code, `constraints_${constructName}`, `The schema constraints of "${def.fqn}", checked by the constructor.`, constraints);
+    }
 
-      if (Array.isArray(copy.required)) {
-        copy.required = copy.required.filter(x => x !== 'apiVersion' && x !== 'kind' && x !== 'status');
+    function emitDefaults() {
+      if (!defaults) {
+        return;
       }
 
-      if (def.custom) {
-        // add "metadata" field for all CRDs, overriding any existing typings
-        copy.properties.metadata = { $ref: '#/definitions/ApiObjectMetadata' };
+      emitSchemaConstant(code, `defaults_${constructName}`, `The schema defaults of "${def.fqn}", set when the object is rendered.`, defaults);
+    }
+
+    function emitNames() {
+      if (!names) {
+        return;
       }
 
-      return copy;
+      emitSchemaConstant(code, `names_${constructName}`, `The original field names of the renamed properties of "${def.fqn}".`, names);
+    }
+
+    // the defaults are set in the JSON form (with the new names of renamed
+    // properties), then the field names are restored
+    function renderProps(props: string) {
+      let json = `toJson_${propsTypeName}(${props})`;
+      if (defaults) {
+        json = `applyDefaults(${json}, defaults_${constructName})`;
+      }
+      return names ? `${constructName}.restoreNames(${json}, names_${constructName})` : json;
     }
 
     function emitConstruct() {
@@ -122,6 +460,9 @@ export function generateConstruct(typegen: TypeGenerator, def: ApiObjectDefiniti
       code.line(` * ${def.schema?.description ?? ''}`);
       code.line(' *');
       code.line(` * @schema ${def.fqn}`);
+      if (def.deprecation) {
+        code.line(` * @deprecated ${def.deprecation}`);
+      }
       code.line(' */');
       code.openBlock(`export class ${constructName} extends ApiObject`);
 
@@ -139,6 +480,11 @@ export function generateConstruct(typegen: TypeGenerator, def: ApiObjectDefiniti
 
       emitToJson();
 
+      if (names) {
+        code.line('');
+        RESTORE_NAMES_METHOD.forEach(line => code.line(line));
+      }
+
       code.closeBlock();
     }
 
@@ -168,6 +514,14 @@ export function generateConstruct(typegen: TypeGenerator, def: ApiObjectDefiniti
       code.line('...props,');
       code.close('});');
 
+      if (constraints) {
+        code.line();
+        code.line(`const violations = checkConstraints(toJson_${propsTypeName}(props), constraints_${constructName}, '${def.kind}');`);
+        code.openBlock('if (violations.length > 0)');
+        code.line(`throw new Error(\`Invalid ${def.kind} "\${this.node.path}": \${violations.join(', ')}\`);`);
+        code.closeBlock();
+      }
+
       code.closeBlock();
     }
 
@@ -183,7 +537,7 @@ export function generateConstruct(typegen: TypeGenerator, def: ApiObjectDefiniti
       code.openBlock(`public static ${MANIFEST_STATIC_METHOD}(props: ${propsTypeName}${defaultProps}): any`);
       code.open('return {');
       code.line(`...${constructName}.${GVK_STATIC},`);
-      code.line(`...toJson_${propsTypeName}(props),`);
+      code.line(`...${renderProps('props')},`);
       code.close('};');
       code.closeBlock();
     }
@@ -197,7 +551,226 @@ export function generateConstruct(typegen: TypeGenerator, def: ApiObjectDefiniti
       code.line();
       code.open('return {');
       code.line(`...${constructName}.${GVK_STATIC},`);
-      code.line(`...toJson_${propsTypeName}(resolved),`);
+      code.line(`...${renderProps('resolved')},`);
+      code.close('};');
+      code.closeBlock();
+    }
+  });
+}
+
+/**
+ * Generates a single construct for multiple versions of an API object. The
+ * props are a union of the props of each version, discriminated by a
+ * `version` field that selects the emitted apiVersion. Only for TypeScript,
+ * since jsii does not support type aliases.
+ *
+ * @param fqn the schema name of the construct
+ * @param versions an API object per version, with a distinct `suffix`
+ */
+export function generateUnifiedConstruct(typegen: TypeGenerator, fqn: string, versions: ApiObjectDefinition[]) {
+  const [first] = versions;
+  const constructName = getConstructTypeName({ ...first, suffix: '' });
+  const propsTypeName = TypeGenerator.normalizeTypeName(`${constructName}Props`);
+  const groupPrefix = first.group ? `${first.group}/` : '';
+  const validations = versions.some(v => v.validations);
+  const defaults = versions.some(v => v.defaults);
+
+  if (first.custom) {
+    typegen.emitCustomType('ApiObjectMetadata', () => {});
+  }
+
+  typegen.emitCustomType(constructName, code => {
+    const members = versions.map(def => {
+      const { schema: propsSchema, names } = renameSchemaProperties(propsStructSchema(def), def.fqn, def.renames ?? [], [getPropsTypeName(def)]);
+      return {
+        def,
+        propsTypeName: typegen.emitType(getPropsTypeName(def), propsSchema, def.fqn),
+        constraints: validations ? extractConstraints(propsSchema) : undefined,
+        defaults: defaults ? extractDefaults(propsSchema) : undefined,
+        names,
+      };
+    });
+    const renamed = members.some(m => m.names);
+
+    emitPropsUnion();
+    emitConstraints();
+    emitDefaults();
+    emitNames();
+
+    code.line('/**');
+    code.line(` * ${first.schema?.description ?? ''}`);
+    code.line(' *');
+    code.line(' * Versions (selected by `version`):');
+    for (const { def } of members) {
+      code.line(` * - ${def.version}${def.deprecation ? ` (deprecated: ${def.deprecation})` : ''}`);
+    }
+    code.line(' *');
+    code.line(` * @schema ${fqn}`);
+    code.line(' */');
+    code.openBlock(`export class ${constructName} extends ApiObject`);
+
+    emitGVK();
+    code.line('');
+    emitManifestFactory();
+    code.line('');
+    emitPropsToJson();
+    code.line('');
+
+    code.line('/**');
+    code.line(` * The version of "${fqn}" of this object.`);
+    code.line(' */');
+    code.line(`public readonly version: ${propsTypeName}['version'];`);
+    code.line('');
+
+    emitInitializer();
+    code.line('');
+    emitToJson();
+
+    if (renamed) {
+      code.line('');
+      RESTORE_NAMES_METHOD.forEach(line => code.line(line));
+    }
+
+    code.closeBlock();
+
+    function emitPropsUnion() {
+      code.line('/**');
+      code.line(` * The props of a version of "${fqn}". \`version\` selects the apiVersion of the object.`);
+      code.line(' */');
+      code.line(`export type ${propsTypeName} =`);
+      members.forEach(({ def, propsTypeName: versionProps }, i) => {
+        code.line(`  | ({ readonly version: '${def.version}' } & ${versionProps})${i === members.length - 1 ? ';' : ''}`);
+      });
+      code.line();
+    }
+
+    function emitConstraints() {
+      if (!validations) {
+        return;
+      }
+
+      const byVersion: Record<string, any> = { };
+      for (const { def, constraints } of members) {
+        byVersion[def.version] = constraints ?? { };
+      }
+
+      emitSchemaConstant(code, `constraints_${constructName}`, `The schema constraints of each version of "${fqn}", checked by the constructor.`, byVersion);
+    }
+
+    function emitDefaults() {
+      if (!defaults) {
+        return;
+      }
+
+      const byVersion: Record<string, any> = { };
+      for (const { def, defaults: versionDefaults } of members) {
+        byVersion[def.version] = versionDefaults ?? { };
+      }
+
+      emitSchemaConstant(code, `defaults_${constructName}`, `The schema defaults of each version of "${fqn}", set when the object is rendered.`, byVersion);
+    }
+
+    function emitNames() {
+      if (!renamed) {
+        return;
+      }
+
+      const byVersion: Record<string, any> = { };
+      for (const { def, names } of members) {
+        byVersion[def.version] = names ?? { };
+      }
+
+      emitSchemaConstant(code, `names_${constructName}`, `The original field names of the renamed properties of each version of "${fqn}".`, byVersion);
+    }
+
+    // the defaults are set in the JSON form (with the new names of renamed
+    // properties), then the field names are restored
+    function renderProps(props: string, version: string) {
+      let json = `${constructName}.propsToJson(${props})`;
+      if (defaults) {
+        json = `applyDefaults(${json}, defaults_${constructName}[${version}])`;
+      }
+      return renamed ? `${constructName}.restoreNames(${json}, names_${constructName}[${version}])` : json;
+    }
+
+    function emitGVK() {
+      code.line('/**');
+      code.line(` * Returns the apiVersion and kind of a version of "${fqn}"`);
+      code.line(' */');
+      code.openBlock(`public static gvk(version: ${propsTypeName}['version']): GroupVersionKind`);
+      code.open('return {');
+      code.line(`apiVersion: \`${groupPrefix}\${version}\`,`);
+      code.line(`kind: '${first.kind}',`);
+      code.close('};');
+      code.closeBlock();
+    }
+
+    function emitManifestFactory() {
+      code.line('/**');
+      code.line(` * Renders a Kubernetes manifest for "${fqn}".`);
+      code.line(' *');
+      code.line(' * This can be used to inline resource manifests inside other objects (e.g. as templates).');
+      code.line(' *');
+      code.line(' * @param props initialization props');
+      code.line(' */');
+      code.openBlock(`public static ${MANIFEST_STATIC_METHOD}(props: ${propsTypeName}): any`);
+      code.open('return {');
+      code.line(`...${constructName}.gvk(props.version),`);
+      code.line(`...${renderProps('props', 'props.version')},`);
+      code.close('};');
+      code.closeBlock();
+    }
+
+    function emitPropsToJson() {
+      code.line('/**');
+      code.line(' * Renders the props of the selected version to Kubernetes JSON.');
+      code.line(' */');
+      code.openBlock(`private static propsToJson(props: ${propsTypeName}): any`);
+      code.openBlock('switch (props.version)');
+      for (const { def, propsTypeName: versionProps } of members) {
+        code.line(`case '${def.version}': return toJson_${versionProps}(props);`);
+      }
+      code.closeBlock();
+      code.closeBlock();
+    }
+
+    function emitInitializer() {
+      code.line('/**');
+      code.line(` * Defines a "${fqn}" API object`);
+      code.line(' * @param scope the scope in which to define this object');
+      code.line(' * @param id a scope-local name for the object');
+      code.line(' * @param props initialization props');
+      code.line(' */');
+      code.openBlock(`public constructor(scope: Construct, id: string, props: ${propsTypeName})`);
+
+      code.open('super(scope, id, {');
+      code.line(`...${constructName}.gvk(props.version),`);
+      code.line('...props,');
+      code.close('});');
+      code.line();
+      code.line('this.version = props.version;');
+
+      if (validations) {
+        code.line();
+        code.line(`const violations = checkConstraints(${constructName}.propsToJson(props), constraints_${constructName}[props.version], '${first.kind}');`);
+        code.openBlock('if (violations.length > 0)');
+        code.line(`throw new Error(\`Invalid ${first.kind} "\${this.node.path}": \${violations.join(', ')}\`);`);
+        code.closeBlock();
+      }
+
+      code.closeBlock();
+    }
+
+    function emitToJson() {
+      code.line('/**');
+      code.line(' * Renders the object to Kubernetes JSON.');
+      code.line(' */');
+      code.openBlock('public toJson(): any');
+      code.line('const resolved = super.toJson();');
+      code.line();
+      code.open('return {');
+      code.line(`...${constructName}.gvk(this.version),`);
+      code.line(`...${renderProps('{ ...resolved, version: this.version }', 'this.version')},`);
       code.close('};');
       code.closeBlock();
     }
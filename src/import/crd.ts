@@ -3,11 +3,16 @@ import * as path from 'path';
 import Ajv from 'ajv';
 import { CodeMaker, toPascalCase } from 'codemaker';
 import { TypeGenerator } from 'json2jsii';
+import * as yaml from 'yaml';
 import { ImportSpec } from '../config';
+import { logger } from '../logger';
 import { SafeReviver } from '../reviver';
-import { download, safeParseYaml } from '../util';
+import { download, globFiles, isGlob, matchGlob } from '../util';
 import { GenerateOptions, ImportBase } from './base';
-import { emitHeader, generateConstruct } from './codegen';
+import { ApiObjectDefinition, emitHeader, generateConstruct, generateUnifiedConstruct, getConstructTypeName } from './codegen';
+import { fetchRemoteReferences, ReferenceResolver } from './refs';
+import { intOrString, mapSubSchemas, preserveUnknownFields, typedAdditionalProperties } from './schema';
+import { numberOrStringUnions } from './unions';
 
 const CRD_KIND = 'CustomResourceDefinition';
 
@@ -35,18 +40,105 @@ export interface ManifestObjectDefinition {
   };
 }
 
+export interface ImportCustomResourceDefinitionOptions {
+  /**
+   * Only import CRDs that match these glob patterns. Patterns are matched
+   * against "group/kind" (e.g. "s3.services.k8s.aws/*").
+   *
+   * @default - all CRDs are imported
+   */
+  readonly include?: string[];
+
+  /**
+   * Do not import CRDs that match these glob patterns. Patterns are matched
+   * against "group/kind".
+   *
+   * @default - no CRDs are excluded
+   */
+  readonly exclude?: string[];
+
+  /**
+   * Fetch `$ref`s that point to https URLs and resolve them into the schema.
+   *
+   * @default false
+   */
+  readonly resolveRemoteRefs?: boolean;
+
+  /**
+   * Timeout for fetching a single remote reference (ms).
+   *
+   * @default DEFAULT_REMOTE_REF_TIMEOUT
+   */
+  readonly remoteRefTimeout?: number;
+
+  /**
+   * Retry failed downloads of URLs this many times.
+   *
+   * @default 0
+   */
+  readonly retries?: number;
+
+  /**
+   * The maximum delay between retries of a download (ms).
+   *
+   * @default DEFAULT_DOWNLOAD_RETRY_MAX_DELAY
+   */
+  readonly retryMaxDelay?: number;
+}
+
+/**
+ * The contents of a manifest file.
+ */
+export interface ManifestFile {
+  /**
+   * The path or URL of the file. Relative `$ref`s are resolved against it.
+   */
+  readonly location: string;
+  readonly content: string;
+}
+
+// file extensions considered when importing a directory
+const MANIFEST_EXTENSIONS = ['.yaml', '.yml', '.json'];
+
 // all these APIs are compatible from our perspective.
 const SUPPORTED_API_VERSIONS = [
   'apiextensions.k8s.io/v1beta1',
   'apiextensions.k8s.io/v1',
 ];
 
+export interface CustomResourceDefinitionGenerateOptions extends GenerateOptions {
+  /**
+   * A type generator shared with other custom resource definitions. The caller
+   * is responsible for rendering it.
+   *
+   * @default - a type generator is created and rendered for each version
+   */
+  readonly types?: TypeGenerator;
+
+  /**
+   * Qualifies the names of all generated types in order to avoid collisions
+   * with custom resources of the same kind from other groups.
+   *
+   * @default - names are not qualified
+   */
+  readonly qualifier?: string;
+}
+
+interface CustomResourceDefinitionVersion {
+  readonly name: string;
+  readonly schema?: any;
+  readonly served: boolean;
+  readonly storage: boolean;
+  readonly deprecated: boolean;
+  readonly deprecationWarning?: string;
+}
+
 export class CustomResourceDefinition {
 
-  private readonly kind: string;
-  private readonly versions: { name: string; schema?: any }[];
+  private readonly versions: CustomResourceDefinitionVersion[];
 
   public readonly group: string;
+  public readonly kind: string;
 
   constructor(manifest: ManifestObjectDefinition) {
     const apiVersion = manifest?.apiVersion ?? 'undefined';
@@ -59,9 +151,16 @@ export class CustomResourceDefinition {
     }
 
     if (spec.version) {
-      this.versions = [{ name: spec.version, schema: spec.validation?.openAPIV3Schema }];
+      this.versions = [{ name: spec.version, schema: spec.validation?.openAPIV3Schema, served: true, storage: true, deprecated: false }];
     } else {
-      this.versions = (spec.versions ?? []).map(v => ({ name: v.name, schema: v.schema?.openAPIV3Schema ?? spec.validation?.openAPIV3Schema }));
+      this.versions = (spec.versions ?? []).map(v => ({
+        name: v.name,
+        schema: v.schema?.openAPIV3Schema ?? spec.validation?.openAPIV3Schema,
+        served: v.served ?? true,
+        storage: v.storage ?? false,
+        deprecated: v.deprecated ?? false,
+        deprecationWarning: v.deprecationWarning,
+      }));
     }
 
     if (this.versions.length === 0) {
@@ -76,44 +175,182 @@ export class CustomResourceDefinition {
     return `${this.group}/${this.kind.toLocaleLowerCase()}`;
   }
 
-  public async generateTypeScript(code: CodeMaker, options: GenerateOptions) {
+  public async generateTypeScript(code: CodeMaker, options: CustomResourceDefinitionGenerateOptions) {
+    const qualifier = options.qualifier ?? '';
 
-    for (let i = 0; i < this.versions.length; i++) {
+    if (options.unifyVersions && this.versions.length > 1) {
+      const reason = this.unifyConflict();
+      if (!reason) {
+        this.generateUnifiedTypeScript(code, options);
+        return;
+      }
+      logger.warn(`Cannot unify the versions of ${this.key} (${reason}), generating a construct per version`);
+    }
 
-      const version = this.versions[i];
+    const defs = this.versions.map((version, i): ApiObjectDefinition => {
 
       // to preseve backwards compatiblity, only append a suffix for
       // the second version onwards.
       const suffix = i === 0 ? '' : toPascalCase(version.name);
 
-      const types = new TypeGenerator({});
-
-      generateConstruct(types, {
+      return {
         group: this.group,
         version: version.name,
         kind: this.kind,
-        fqn: `${this.kind}${suffix}`,
-        schema: version.schema,
+        fqn: `${qualifier}${this.kind}${suffix}`,
+        schema: transformSchema(version.schema),
         custom: true,
-        prefix: options.classNamePrefix,
+        prefix: `${options.classNamePrefix ?? ''}${qualifier}`,
         suffix,
+        validations: options.emitValidations,
+        defaults: options.applyDefaults,
+        renames: options.renames,
+      };
+    });
+
+    // deprecated versions point to the version that is persisted
+    const storageIndex = this.versions.findIndex(v => v.storage);
+    const storage = storageIndex === -1 ? undefined : {
+      className: getConstructTypeName(defs[storageIndex]),
+      apiVersion: `${this.group}/${this.versions[storageIndex].name}`,
+    };
+
+    for (let i = 0; i < this.versions.length; i++) {
+      const types = options.types ?? new TypeGenerator({});
+
+      generateConstruct(types, {
+        ...defs[i],
+        deprecation: this.deprecationMessage(this.versions[i], storage),
       });
 
+      if (!options.types) {
+        code.line(types.render());
+      }
+    }
+  }
+
+  /**
+   * Generates a single construct for all versions (see `generateUnifiedConstruct`).
+   */
+  private generateUnifiedTypeScript(code: CodeMaker, options: CustomResourceDefinitionGenerateOptions) {
+    const qualifier = options.qualifier ?? '';
+
+    const defs = this.versions.map((version): ApiObjectDefinition => {
+      const suffix = toPascalCase(version.name);
+      return {
+        group: this.group,
+        version: version.name,
+        kind: this.kind,
+        fqn: `${qualifier}${this.kind}${suffix}`,
+        schema: transformSchema(version.schema),
+        custom: true,
+        prefix: `${options.classNamePrefix ?? ''}${qualifier}`,
+        suffix,
+        validations: options.emitValidations,
+        defaults: options.applyDefaults,
+        renames: options.renames,
+        deprecation: this.deprecationMessage(version),
+      };
+    });
+
+    const types = options.types ?? new TypeGenerator({});
+    generateUnifiedConstruct(types, `${qualifier}${this.kind}`, defs);
+    if (!options.types) {
       code.line(types.render());
     }
   }
+
+  /**
+   * Returns why the versions cannot be unified into a single construct, or
+   * `undefined` if they can. All versions must have a schema with the same
+   * top-level fields (e.g. "spec"), so that code that is written against one
+   * version works with the others.
+   */
+  private unifyConflict(): string | undefined {
+    const fields = (schema: any) => Object.keys(schema?.properties ?? { })
+      .filter(f => !['apiVersion', 'kind', 'metadata', 'status'].includes(f))
+      .sort();
+
+    const missing = this.versions.filter(v => !v.schema).map(v => v.name);
+    if (missing.length > 0) {
+      return `${missing.join(', ')} ${missing.length === 1 ? 'has' : 'have'} no schema`;
+    }
+
+    const versionFields = this.versions.map(v => fields(v.schema));
+    if (versionFields.some(f => f.includes('version'))) {
+      return 'a version has a top-level "version" field';
+    }
+
+    const expected = versionFields[0].join(', ');
+    if (versionFields.some(f => f.join(', ') !== expected)) {
+      return `the versions have different top-level fields: ${this.versions.map((v, i) => `${v.name} (${versionFields[i].join(', ')})`).join(', ')}`;
+    }
+
+    return undefined;
+  }
+
+  private deprecationMessage(version: CustomResourceDefinitionVersion, storage?: { className: string; apiVersion: string }): string | undefined {
+    const apiVersion = `${this.group}/${version.name}`;
+    const recommendation = storage && !version.storage ? ` Use \`${storage.className}\` (${storage.apiVersion}) instead.` : '';
+
+    if (version.deprecated) {
+      const warning = version.deprecationWarning?.replace(/\s+/g, ' ').trim() ?? `${apiVersion} ${this.kind} is deprecated.`;
+      return `${warning}${recommendation}`;
+    }
+
+    if (!version.served) {
+      return `${apiVersion} ${this.kind} is not served by the API server.${recommendation}`;
+    }
+
+    return undefined;
+  }
 }
 
 export class ImportCustomResourceDefinition extends ImportBase {
-  public static async fromSpec(importSpec: ImportSpec): Promise<ImportCustomResourceDefinition> {
+  public static async fromSpec(importSpec: ImportSpec, options: ImportCustomResourceDefinitionOptions = { }): Promise<ImportCustomResourceDefinition> {
     const { source } = importSpec;
-    const manifest = await download(source);
-    return new ImportCustomResourceDefinition(safeParseCrds(manifest));
+    return ImportCustomResourceDefinition.fromManifestFiles(await ImportCustomResourceDefinition.loadFiles(source, options), options);
+  }
+
+  public static async fromManifest(manifest: string, options: ImportCustomResourceDefinitionOptions = { }): Promise<ImportCustomResourceDefinition> {
+    const files = await ImportCustomResourceDefinition.resolveFiles([{ location: path.resolve('.'), content: manifest }], options);
+    return ImportCustomResourceDefinition.fromManifestFiles(files, options);
+  }
+
+  /**
+   * Loads the manifest files of a source (a file, directory or URL) without
+   * parsing them, including remote references if `resolveRemoteRefs` is set.
+   */
+  public static async loadFiles(source: string, options: ImportCustomResourceDefinitionOptions = { }): Promise<ManifestFile[]> {
+    return ImportCustomResourceDefinition.resolveFiles(await loadManifestFiles(source, options), options);
+  }
+
+  /**
+   * Adds the files referenced remotely by `files` if `resolveRemoteRefs` is set.
+   */
+  public static async resolveFiles(files: ManifestFile[], options: ImportCustomResourceDefinitionOptions = { }): Promise<ManifestFile[]> {
+    if (!options.resolveRemoteRefs) {
+      return files;
+    }
+
+    return [...files, ...await fetchRemoteReferences(files, {
+      timeout: options.remoteRefTimeout,
+      retries: options.retries,
+      retryMaxDelay: options.retryMaxDelay,
+    })];
+  }
+
+  /**
+   * Imports the CRDs from multiple files. `$ref`s may point to definitions in
+   * any of the files.
+   */
+  public static fromManifestFiles(files: ManifestFile[], options: ImportCustomResourceDefinitionOptions = { }): ImportCustomResourceDefinition {
+    return new ImportCustomResourceDefinition(safeParseCrdFiles(files), options);
   }
 
   private readonly groups: Record<string, CustomResourceDefinition[]> = { };
 
-  private constructor(manifest: ManifestObjectDefinition[]) {
+  private constructor(manifest: ManifestObjectDefinition[], options: ImportCustomResourceDefinitionOptions) {
     super();
 
     const crds: Record<string, CustomResourceDefinition> = { };
@@ -130,7 +367,7 @@ export class ImportCustomResourceDefinition extends ImportBase {
     }
 
     //sort to ensure consistent ordering for snapshot compare
-    const sortedCrds = Object.values(crds).sort((a: CustomResourceDefinition, b: CustomResourceDefinition) => a.key.localeCompare(b.key));
+    const sortedCrds = filterCrds(Object.values(crds), options).sort((a: CustomResourceDefinition, b: CustomResourceDefinition) => a.key.localeCompare(b.key));
 
     for (const crd of sortedCrds) {
       const g = crd.group;
@@ -143,6 +380,15 @@ export class ImportCustomResourceDefinition extends ImportBase {
     this.groups = groups;
   }
 
+  protected get supportsRenames() {
+    return true;
+  }
+
+  // int-or-string fields are generated as `number | string` in TypeScript
+  protected transformTypeScript(code: string) {
+    return numberOrStringUnions(code);
+  }
+
   public get moduleNames() {
     return Object.keys(this.groups);
   }
@@ -151,13 +397,73 @@ export class ImportCustomResourceDefinition extends ImportBase {
     const crds = this.groups[moduleName];
 
 
-    emitHeader(code, true);
+    emitHeader(code, true, options.emitValidations, options.applyDefaults);
 
     for (const crd of crds) {
-      console.log(`  ${crd.key}`);
+      logger.info(`  ${crd.key}`);
       await crd.generateTypeScript(code, options);
     }
   }
+
+  protected async generateMergedTypeScript(code: CodeMaker, moduleNames: string[], options: GenerateOptions) {
+    const crds = moduleNames.flatMap(moduleName => this.groups[moduleName]);
+
+    // kinds that exist in more than one group are qualified by their group,
+    // otherwise their types would collide within the same file.
+    const kinds: Record<string, number> = { };
+    for (const crd of crds) {
+      const kind = crd.kind.toLocaleLowerCase();
+      kinds[kind] = (kinds[kind] ?? 0) + 1;
+    }
+
+    // a single type generator ensures shared types are only emitted once.
+    const types = new TypeGenerator({});
+
+    emitHeader(code, true, options.emitValidations, options.applyDefaults);
+
+    for (const crd of crds) {
+      logger.info(`  ${crd.key}`);
+      const qualifier = kinds[crd.kind.toLocaleLowerCase()] > 1 ? toPascalCase(crd.group) : undefined;
+      await crd.generateTypeScript(code, { ...options, types, qualifier });
+    }
+
+    code.line(types.render());
+  }
+}
+
+/**
+ * Adapts CRD specific schema extensions before types are generated.
+ */
+function transformSchema(schema: any) {
+  return mapSubSchemas(schema, s => typedAdditionalProperties(intOrString(preserveUnknownFields(s))));
+}
+
+/**
+ * Filters CRDs by their "group/kind" (case-insensitive).
+ */
+function filterCrds(crds: CustomResourceDefinition[], options: ImportCustomResourceDefinitionOptions): CustomResourceDefinition[] {
+  const include = (options.include ?? []).map(p => String(p).toLocaleLowerCase());
+  const exclude = (options.exclude ?? []).map(p => String(p).toLocaleLowerCase());
+  const name = (crd: CustomResourceDefinition) => `${crd.group}/${crd.kind}`;
+  const matches = (patterns: string[], crd: CustomResourceDefinition) => patterns.some(p => matchGlob(p, name(crd).toLocaleLowerCase()));
+  const available = crds.map(name).sort().join(', ');
+
+  // a pattern that matches nothing is most likely a typo
+  for (const pattern of [...include, ...exclude]) {
+    if (!crds.some(crd => matches([pattern], crd))) {
+      throw new Error(`No custom resource definitions match "${pattern}". Available: ${available}`);
+    }
+  }
+
+  const filtered = crds
+    .filter(crd => include.length === 0 || matches(include, crd))
+    .filter(crd => !matches(exclude, crd));
+
+  if (crds.length > 0 && filtered.length === 0) {
+    throw new Error(`All custom resource definitions were excluded. Available: ${available}`);
+  }
+
+  return filtered;
 }
 
 function assert(condition: boolean, message: string) {
@@ -167,33 +473,93 @@ function assert(condition: boolean, message: string) {
 }
 
 
+/**
+ * Reads the manifests of an import source. Directories are searched
+ * recursively for manifest files, and glob patterns (e.g.
+ * "./crds/**\/*.crd.yaml") are expanded to all the files they match.
+ */
+async function loadManifestFiles(source: string, options: ImportCustomResourceDefinitionOptions): Promise<ManifestFile[]> {
+  if (!isUrl(source) && !fs.existsSync(source) && isGlob(source)) {
+    const matched = await globFiles(source);
+    if (matched.length === 0) {
+      throw new Error(`No files match ${source}`);
+    }
+
+    const files = new Array<ManifestFile>();
+    for (const file of matched) {
+      files.push({ location: file, content: await download(file) });
+    }
+    return files;
+  }
+
+  if (!fs.existsSync(source) || !fs.statSync(source).isDirectory()) {
+    const content = await download(source, { retries: options.retries, retryMaxDelay: options.retryMaxDelay });
+    return [{ location: isUrl(source) ? source : path.resolve(source), content }];
+  }
+
+  const files = new Array<ManifestFile>();
+  for (const entry of fs.readdirSync(source, { withFileTypes: true }).sort((a, b) => a.name.localeCompare(b.name))) {
+    const entryPath = path.resolve(source, entry.name);
+    if (entry.isDirectory()) {
+      files.push(...await loadManifestFiles(entryPath, options));
+    } else if (MANIFEST_EXTENSIONS.includes(path.extname(entry.name))) {
+      files.push({ location: entryPath, content: await download(entryPath) });
+    }
+  }
+
+  if (files.length === 0) {
+    throw new Error(`No manifest files (${MANIFEST_EXTENSIONS.join(', ')}) found in ${source}`);
+  }
+
+  return files;
+}
+
+function isUrl(source: string) {
+  return /^[a-z]+:\/\//.test(source);
+}
+
 export function safeParseCrds(manifest: string): ManifestObjectDefinition[] {
+  return safeParseCrdFiles([{ location: path.resolve('.'), content: manifest }]);
+}
+
+export function safeParseCrdFiles(files: ManifestFile[]): ManifestObjectDefinition[] {
   const schemaPath = path.join(__dirname, '..', 'schemas', 'crd.schema.json');
   const schema = JSON.parse(fs.readFileSync(schemaPath, { encoding: 'utf8' }));
   const reviver = new SafeReviver({
     sanitizers: [SafeReviver.DESCRIPTION_SANITIZER, SafeReviver.LEGAL_CHAR_SANITIZER],
   });
 
-  // first parse and strip
-  const objects = safeParseYaml(manifest, reviver);
+  // since the manifest can contain non crds as well (e.g. rendered helm
+  // templates), we first collect all crds and only sanitize and apply a
+  // schema validation on them.
+  const parsed = files.map(file => ({
+    location: file.location,
+    documents: yaml.parseAllDocuments(file.content).map(doc => doc.toJS()),
+  }));
 
-  // since the manifest can contain non crds as well, we first
-  // collect all crds and only apply a schema validation on them.
+  // definitions can be shared between all files
+  const resolver = new ReferenceResolver(parsed);
 
   const crds: any[] = [];
 
-  function collectCRDs(objs: any[]) {
+  function collectCRDs(objs: any[], location: string) {
     for (const obj of objs.filter(o => o)) {
       if (obj.kind === CRD_KIND) {
-        crds.push(obj);
+        crds.push(resolver.resolve(obj, location));
       }
       if (obj.kind === 'List') {
-        collectCRDs(obj.items);
+        collectCRDs(obj.items, location);
       }
     }
   }
 
-  collectCRDs(objects);
+  for (const file of parsed) {
+    collectCRDs(file.documents, file.location);
+  }
+
+  for (const crd of crds) {
+    reviver.sanitize(crd);
+  }
 
   const ajv = new Ajv();
   const validate = ajv.compile(schema);
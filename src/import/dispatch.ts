@@ -1,40 +1,186 @@
+import * as path from 'path';
 import { ImportSpec } from '../config';
+import { logger } from '../logger';
+import { runCodegenHooks } from '../plugins/codegen';
+import { mkdtemp } from '../util';
 import { ImportBase, ImportOptions } from './base';
-import { ImportCustomResourceDefinition } from './crd';
+import { ImportCache } from './cache';
+import { ImportCustomResourceDefinition, ImportCustomResourceDefinitionOptions, ManifestFile } from './crd';
 import { matchCrdsDevUrl } from './crds-dev';
+import { cloneGitRepository, matchGitRepository } from './git';
+import { matchHelmChart, renderHelmChart } from './helm';
 import { ImportKubernetesApi } from './k8s';
+import { matchOciArtifact, pullOciArtifact } from './oci';
+import { ImportProto, loadProtoFiles, matchProtoFile } from './proto';
+
+/**
+ * An import source whose content was fetched but not yet parsed.
+ */
+interface ImportSource {
+  /**
+   * The fetched content of the source. Used as the cache key.
+   */
+  readonly content: string;
+
+  /**
+   * The options of the source that affect the generated code. Part of the
+   * cache key.
+   *
+   * @default - no options
+   */
+  readonly options?: Record<string, any>;
+
+  /**
+   * Parses the content and returns the importer.
+   */
+  load(): Promise<ImportBase>;
+}
+
+export interface ImportDispatchOptions extends ImportOptions {
+  /**
+   * Reuse the output of previous imports of the same content.
+   *
+   * @default - imports are not cached
+   */
+  readonly cache?: ImportCache;
+}
+
+/**
+ * Imports each spec in order.
+ *
+ * @returns the files and directories that were emitted by all imports
+ */
+export async function importDispatch(imports: ImportSpec[], argv: any, options: ImportDispatchOptions): Promise<string[]> {
+  const { cache, ...importOptions } = options;
+  const allEmitted = new Array<string>();
 
-export async function importDispatch(imports: ImportSpec[], argv: any, options: ImportOptions) {
   for (const importSpec of imports) {
-    const importer = await matchImporter(importSpec, argv);
+    const source = await matchImporter(importSpec, argv);
 
-    if (!importer) {
+    if (!source) {
       throw new Error(`unable to determine import type for "${importSpec}"`);
     }
 
-    console.error('Importing resources, this may take a few moments...');
-
-    await importer.import({
+    const specOptions: ImportOptions = {
       moduleNamePrefix: importSpec.moduleNamePrefix,
-      ...options,
+      ...importOptions,
+    };
+
+    // the .jsii file is written outside of the output directory and cannot be
+    // cached. dry runs must not write to the output directory.
+    if (!cache || specOptions.outputJsii || specOptions.dryRun) {
+      logger.info('Importing resources, this may take a few moments...');
+      allEmitted.push(...await (await source.load()).import(specOptions));
+      continue;
+    }
+
+    const key = cache.key(source.content, {
+      ...specOptions,
+      outdir: path.relative(process.cwd(), path.resolve(specOptions.outdir)),
+      codegenHooks: undefined,
+      source: source.options,
     });
+
+    // codegen hooks are not cached, they run against the restored files as well
+    let emitted = await cache.restore(key);
+    if (emitted) {
+      logger.info(`Using cached import of "${importSpec.source}"`);
+    } else {
+      logger.info('Importing resources, this may take a few moments...');
+      emitted = await (await source.load()).import({ ...specOptions, codegenHooks: [] });
+      await cache.store(key, emitted);
+    }
+
+    await runCodegenHooks(specOptions.codegenHooks ?? [], emitted);
+    allEmitted.push(...emitted);
   }
+
+  return allEmitted;
 }
 
-async function matchImporter(importSpec: ImportSpec, argv: any): Promise<ImportBase> {
+async function matchImporter(importSpec: ImportSpec, argv: any): Promise<ImportSource> {
 
   // first check if its a `k8s@` import
   const k8s = await ImportKubernetesApi.match(importSpec, argv);
   if (k8s) {
-    return new ImportKubernetesApi(k8s);
+    const importer = new ImportKubernetesApi(k8s);
+    return {
+      content: JSON.stringify(await importer.loadSchema()),
+      options: { exclude: k8s.exclude },
+      load: async () => importer,
+    };
   }
 
+  const crdOptions: ImportCustomResourceDefinitionOptions = {
+    include: argv.includeCrd,
+    exclude: argv.excludeCrd,
+    resolveRemoteRefs: argv.resolveRemoteRefs,
+    remoteRefTimeout: argv.remoteRefTimeout,
+    retries: argv.retries,
+    retryMaxDelay: argv.retryMaxDelay,
+  };
+
+  const crdSource = (files: ManifestFile[]): ImportSource => ({
+    // local locations are not part of the content since they can be temporary.
+    // the files fetched for remote references are, with their URLs, so that
+    // changes of the remote documents invalidate the cache.
+    content: JSON.stringify(files.map(f => isRemoteLocation(f.location) ? { location: f.location, content: f.content } : f.content)),
+    options: {
+      include: crdOptions.include,
+      exclude: crdOptions.exclude,
+      resolveRemoteRefs: crdOptions.resolveRemoteRefs ?? false,
+    },
+    load: async () => ImportCustomResourceDefinition.fromManifestFiles(files, crdOptions),
+  });
+
   // now check if its a crds.dev import
   const crdsDevUrl = matchCrdsDevUrl(importSpec.source);
   if (crdsDevUrl) {
-    return ImportCustomResourceDefinition.fromSpec({ source: crdsDevUrl, moduleNamePrefix: importSpec.moduleNamePrefix });
+    return crdSource(await ImportCustomResourceDefinition.loadFiles(crdsDevUrl, crdOptions));
+  }
+
+  // now check if its a helm chart
+  const helmChart = matchHelmChart(importSpec.source);
+  if (helmChart) {
+    const manifest = await renderHelmChart(helmChart);
+    return crdSource(await ImportCustomResourceDefinition.resolveFiles([{ location: path.resolve('.'), content: manifest }], crdOptions));
+  }
+
+  // now check if its an oci artifact
+  const ociArtifact = matchOciArtifact(importSpec.source);
+  if (ociArtifact) {
+    let files = new Array<ManifestFile>();
+    await mkdtemp(async workdir => {
+      await pullOciArtifact(ociArtifact, workdir);
+      files = await ImportCustomResourceDefinition.loadFiles(workdir, crdOptions);
+    });
+    return crdSource(files);
+  }
+
+  // now check if its a protobuf file
+  const protoFile = matchProtoFile(importSpec.source);
+  if (protoFile) {
+    const files = await loadProtoFiles(protoFile);
+    return {
+      content: JSON.stringify(files.map(f => f.content)),
+      load: async () => new ImportProto(files),
+    };
+  }
+
+  // now check if its a git repository
+  const gitRepository = matchGitRepository(importSpec.source);
+  if (gitRepository) {
+    let files = new Array<ManifestFile>();
+    await mkdtemp(async workdir => {
+      files = await ImportCustomResourceDefinition.loadFiles(await cloneGitRepository(gitRepository, workdir), crdOptions);
+    });
+    return crdSource(files);
   }
 
   // default to a normal CRD
-  return ImportCustomResourceDefinition.fromSpec(importSpec);
+  return crdSource(await ImportCustomResourceDefinition.loadFiles(importSpec.source, crdOptions));
+}
+
+function isRemoteLocation(location: string) {
+  return /^[a-z]+:\/\//.test(location);
 }
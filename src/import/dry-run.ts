@@ -0,0 +1,71 @@
+import * as path from 'path';
+
+/**
+ * A construct that would be generated.
+ */
+export interface GeneratedConstruct {
+  readonly className: string;
+  readonly apiVersion: string;
+  readonly kind: string;
+}
+
+/**
+ * What an import would generate for a single module.
+ */
+export interface ModuleSummary {
+  readonly moduleName: string;
+
+  /**
+   * The files and directories that would be written.
+   */
+  readonly paths: string[];
+
+  readonly constructs: GeneratedConstruct[];
+
+  /**
+   * The number of properties that are represented as `any` because their
+   * schema cannot be represented (or was excluded).
+   */
+  readonly untypedProperties: number;
+}
+
+/**
+ * Summarizes the TypeScript code generated for a module.
+ */
+export function summarizeModule(moduleName: string, paths: string[], source: string): ModuleSummary {
+  const constructs = new Array<GeneratedConstruct>();
+
+  const pattern = /^export class (\w+) extends ApiObject \{[\s\S]*?apiVersion: '([^']*)',\s*kind: '([^']*)',/gm;
+  let match;
+  while ((match = pattern.exec(source)) !== null) {
+    const [, className, apiVersion, kind] = match;
+    constructs.push({ className, apiVersion, kind });
+  }
+
+  const untypedProperties = (source.match(/^ {2}readonly \w+\??: any;$/gm) ?? []).length;
+
+  return { moduleName, paths, constructs, untypedProperties };
+}
+
+/**
+ * Prints what an import would generate.
+ */
+export function printDryRunSummary(modules: ModuleSummary[]) {
+  console.log('Dry run, no files were written.');
+
+  for (const module of modules) {
+    console.log('');
+    console.log(`${module.moduleName}:`);
+    for (const p of module.paths) {
+      console.log(`  would write ${path.relative(process.cwd(), p)}`);
+    }
+
+    for (const c of module.constructs) {
+      console.log(`  ${c.apiVersion.includes('/') ? c.apiVersion.split('/')[0] : 'core'}/${c.kind} (${c.apiVersion}) => ${c.className}`);
+    }
+
+    if (module.untypedProperties > 0) {
+      console.log(`  warning: ${module.untypedProperties} properties are represented as "any" since their schema is not supported`);
+    }
+  }
+}
@@ -0,0 +1,52 @@
+/**
+ * Replaces the enums emitted by json2jsii with literal union types that have
+ * the same name (e.g. `export type Operator = 'In' | '!=';`). Since enum
+ * members are serialized as their values, the serialization code and the
+ * types of the properties remain valid. Enums with members that are not
+ * string or number literals (e.g. computed members) are kept as enums.
+ *
+ * Union types cannot be represented by jsii, so this is only applicable to
+ * TypeScript output.
+ *
+ * @param code the rendered types
+ * @returns the code with union types instead of enums
+ */
+export function enumsToUnions(code: string): string {
+  const lines = code.split('\n');
+  const output = new Array<string>();
+
+  for (let i = 0; i < lines.length; i++) {
+    const decl = /^export enum (\w+) \{$/.exec(lines[i]);
+    if (!decl) {
+      output.push(lines[i]);
+      continue;
+    }
+
+    const start = i;
+    const values = new Array<string>();
+    let literals = true;
+    for (i++; i < lines.length && lines[i] !== '}'; i++) {
+      // member docs (e.g. "/** value */") just repeat the value
+      if (/^\s*(\/\*\*|\*|$)/.test(lines[i])) {
+        continue;
+      }
+
+      const member = /^ {2}\S+ = ('(?:[^'\\]|\\.)*'|"(?:[^"\\]|\\.)*"|-?\d+(?:\.\d+)?(?:e[+-]?\d+)?),$/i.exec(lines[i]);
+      if (member) {
+        values.push(member[1]);
+      } else {
+        literals = false;
+      }
+    }
+
+    if (!literals || values.length === 0) {
+      output.push(...lines.slice(start, i + 1));
+      continue;
+    }
+
+    output.push(`export type ${decl[1]} =`);
+    output.push(...values.map((v, j) => `  | ${v}${j === values.length - 1 ? ';' : ''}`));
+  }
+
+  return output.join('\n');
+}
@@ -0,0 +1,97 @@
+import * as path from 'path';
+import * as fs from 'fs-extra';
+import { shell } from '../util';
+
+/**
+ *
+ *  git+https://github.com/org/repo.git//config/crd?ref=v1.2.0
+ *  |-^||--------------^--------------||-----^----||----^----|
+ *    |                |                     |          |
+ *    |                |                     |          +-- ref: branch, tag or full commit SHA (optional)
+ *    |                |                     +-- subdirectory (optional)
+ *    |                +-- repository URL
+ *    +-- scheme
+ */
+
+/**
+ * A reference to a directory in a Git repository.
+ */
+export interface GitRepositoryReference {
+  /**
+   * The URL of the repository (without the "git+" prefix).
+   */
+  readonly url: string;
+
+  /**
+   * The directory within the repository to import.
+   *
+   * @default - the root of the repository
+   */
+  readonly subdir?: string;
+
+  /**
+   * A branch, tag or full commit SHA.
+   *
+   * @default - the default branch of the repository
+   */
+  readonly ref?: string;
+}
+
+/**
+ * Matches a "git+" import source
+ *
+ *  - repository reference if found
+ *  - undefined if not
+ *
+ * @param source
+ */
+export function matchGitRepository(source: string): (undefined | GitRepositoryReference) {
+  if (!source.startsWith('git+')) {
+    return undefined;
+  }
+
+  const match = /^git\+((?:https|ssh|file):\/\/[^?]+?)(?:\/\/([^?]+))?(?:\?ref=([^&]+))?$/.exec(source);
+  if (!match) {
+    throw new Error(`Expected Git repository "${source}" to match format "git+https://<host>/<repository>[//<subdir>][?ref=<ref>]".`);
+  }
+
+  const [, url, subdir, ref] = match;
+  if (subdir && subdir.split('/').includes('..')) {
+    throw new Error(`The subdirectory of Git repository "${source}" must be within the repository.`);
+  }
+
+  return { url, subdir: subdir?.replace(/\/+$/, ''), ref };
+}
+
+/**
+ * Shallow clones a repository into `workdir` using `git`.
+ *
+ * @returns the directory to import
+ */
+export async function cloneGitRepository(repo: GitRepositoryReference, workdir: string): Promise<string> {
+  try {
+    if (repo.ref && isCommit(repo.ref)) {
+      // commits cannot be cloned directly, so fetch just that commit
+      await shell('git', ['init', '--quiet'], { cwd: workdir });
+      await shell('git', ['remote', 'add', 'origin', repo.url], { cwd: workdir });
+      await shell('git', ['fetch', '--quiet', '--depth', '1', 'origin', repo.ref], { cwd: workdir });
+      await shell('git', ['checkout', '--quiet', 'FETCH_HEAD'], { cwd: workdir });
+    } else {
+      const ref = repo.ref ? ['--branch', repo.ref] : [];
+      await shell('git', ['clone', '--quiet', '--depth', '1', ...ref, repo.url, workdir]);
+    }
+  } catch (e) {
+    throw new Error(`Unable to clone Git repository "${repo.url}"${repo.ref ? ` at "${repo.ref}"` : ''} (make sure "git" is installed and you have access to the repository): ${e}`);
+  }
+
+  const dir = path.join(workdir, repo.subdir ?? '');
+  if (!await fs.pathExists(dir)) {
+    throw new Error(`Directory "${repo.subdir}" not found in Git repository "${repo.url}"`);
+  }
+
+  return dir;
+}
+
+function isCommit(ref: string) {
+  return /^[0-9a-f]{40}$/.test(ref);
+}
@@ -0,0 +1,243 @@
+/**
+ * How the constructors of the generated Go constructs receive their props.
+ */
+export enum GoOptionsStyle {
+  /**
+   * Only the `NewX(scope, id, props)` constructors generated by jsii.
+   */
+  STRUCT = 'struct',
+
+  /**
+   * Also emit `NewXWithOptions(scope, id, opts...)` constructors and a
+   * `XWithField(...)` option for each field of the props and of their spec.
+   */
+  FUNCTIONAL = 'functional',
+}
+
+// the go packages of the jsii dependencies that generated constructs use
+const GO_IMPORTS: Record<string, string> = {
+  cdk8s: 'github.com/cdk8s-team/cdk8s-core-go/cdk8s/v2',
+  constructs: 'github.com/aws/constructs-go/constructs/v10',
+};
+
+const GO_PRIMITIVES: Record<string, string> = {
+  string: '*string',
+  number: '*float64',
+  boolean: '*bool',
+  date: '*time.Time',
+  json: '*map[string]interface{}',
+  any: 'interface{}',
+};
+
+// pointers to these are set from a plain value (e.g. `WithReplicas(3)`)
+const VALUE_TYPES = ['*string', '*float64', '*bool'];
+
+interface GoField {
+  readonly name: string;
+  readonly type: string;
+}
+
+/**
+ * Renders the go types of jsii type references and collects the packages they
+ * need.
+ */
+class GoTypes {
+  public readonly imports = new Set<string>();
+
+  constructor(private readonly assembly: any) { }
+
+  /**
+   * @returns the go type or `undefined` if it cannot be referenced from the
+   * generated package.
+   */
+  public render(ref: any): string | undefined {
+    if (ref.primitive) {
+      const type = GO_PRIMITIVES[ref.primitive];
+      if (ref.primitive === 'date') {
+        this.imports.add('time');
+      }
+      return type;
+    }
+
+    if (ref.collection) {
+      const element = this.render(ref.collection.elementtype);
+      if (!element) {
+        return undefined;
+      }
+      return ref.collection.kind === 'map' ? `*map[string]${element}` : `*[]${element}`;
+    }
+
+    if (ref.union) {
+      return 'interface{}';
+    }
+
+    if (ref.fqn) {
+      if (ref.fqn.startsWith(`${this.assembly.name}.`)) {
+        const typeName = this.localName(ref.fqn);
+        return this.isStruct(ref.fqn) ? `*${typeName}` : typeName;
+      }
+
+      const dependency = Object.keys(GO_IMPORTS).find(name => ref.fqn.startsWith(`${name}.`));
+      if (!dependency) {
+        return undefined;
+      }
+
+      this.imports.add(GO_IMPORTS[dependency]);
+      // only structs are referenced from other assemblies by generated props
+      return `*${dependency}.${ref.fqn.slice(dependency.length + 1).replace(/\./g, '_')}`;
+    }
+
+    return undefined;
+  }
+
+  /**
+   * The fields of a struct of the assembly, in the order of the jsii assembly.
+   */
+  public fields(fqn: string): GoField[] {
+    const fields = new Array<GoField>();
+    for (const property of this.assembly.types?.[fqn]?.properties ?? []) {
+      const type = this.render(property.type);
+      if (type) {
+        fields.push({ name: goFieldName(property.name), type });
+      }
+    }
+    return fields;
+  }
+
+  /**
+   * The name of the go type of a type of the assembly. Types of namespaces
+   * are prefixed with the namespace (e.g. "Namespace_Type").
+   */
+  public localName(fqn: string): string {
+    return fqn.slice(this.assembly.name.length + 1).replace(/\./g, '_');
+  }
+
+  public isStruct(fqn: string): boolean {
+    const type = this.assembly.types?.[fqn];
+    return type?.kind === 'interface' && type?.datatype === true;
+  }
+}
+
+/**
+ * The name of the go field of a jsii property.
+ */
+function goFieldName(name: string) {
+  return name.charAt(0).toUpperCase() + name.slice(1);
+}
+
+/**
+ * Returns the constructs of an assembly as `[construct fqn, props fqn]`,
+ * sorted by name.
+ */
+function constructsOf(assembly: any, types: GoTypes): Array<[string, string]> {
+  const result = new Array<[string, string]>();
+
+  for (const [fqn, type] of Object.entries<any>(assembly.types ?? {})) {
+    const params = type.initializer?.parameters ?? [];
+    if (type.kind !== 'class' || type.abstract || params.length !== 3) {
+      continue;
+    }
+
+    const [scope, , props] = params;
+    if (scope.type?.fqn === 'constructs.Construct' && props.type?.fqn && types.isStruct(props.type.fqn)) {
+      result.push([fqn, props.type.fqn]);
+    }
+  }
+
+  return result.sort(([a], [b]) => a.localeCompare(b));
+}
+
+function emitOption(lines: string[], option: string, construct: string, propsStruct: string, field: GoField, target: string, init?: string) {
+  const byValue = VALUE_TYPES.includes(field.type);
+
+  lines.push(
+    `// ${option} sets ${target}.`,
+    `func ${option}(value ${byValue ? field.type.slice(1) : field.type}) ${construct}Option {`,
+    `\treturn func(props *${propsStruct}) {`,
+  );
+
+  if (init) {
+    lines.push(
+      '\t\tif props.Spec == nil {',
+      `\t\t\tprops.Spec = &${init}{}`,
+      '\t\t}',
+    );
+  }
+
+  lines.push(
+    `\t\tprops.${target.split('.').slice(1).join('.')} = ${byValue ? '&value' : 'value'}`,
+    '\t}',
+    '}',
+    '',
+  );
+}
+
+/**
+ * Generates functional options for the constructs of a jsii assembly (see
+ * `GoOptionsStyle.FUNCTIONAL`), to be added to the go package that jsii
+ * generated from it. The options only set fields of the props struct that is
+ * passed to the generated `NewX` constructor.
+ *
+ * @returns the go source or `undefined` if the assembly has no constructs
+ */
+export function emitGoOptions(assembly: any, packageName: string): string | undefined {
+  const types = new GoTypes(assembly);
+  const constructs = constructsOf(assembly, types);
+  if (constructs.length === 0) {
+    return undefined;
+  }
+
+  types.imports.add(GO_IMPORTS.constructs);
+
+  const body = new Array<string>();
+  for (const [fqn, propsFqn] of constructs) {
+    const construct = types.localName(fqn);
+    const propsStruct = types.localName(propsFqn);
+    const fields = types.fields(propsFqn);
+
+    body.push(
+      `// ${construct}Option sets fields of the ${propsStruct} of New${construct}WithOptions.`,
+      `type ${construct}Option func(*${propsStruct})`,
+      '',
+      `// New${construct}WithOptions creates a ${construct} from options that are applied to`,
+      `// empty ${propsStruct} in order. Required fields must be set by an option too.`,
+      `func New${construct}WithOptions(scope constructs.Construct, id *string, opts ...${construct}Option) ${construct} {`,
+      `\tprops := &${propsStruct}{}`,
+      '\tfor _, opt := range opts {',
+      '\t\topt(props)',
+      '\t}',
+      `\treturn New${construct}(scope, id, props)`,
+      '}',
+      '',
+    );
+
+    for (const field of fields) {
+      emitOption(body, `${construct}With${field.name}`, construct, propsStruct, field, `${propsStruct}.${field.name}`);
+    }
+
+    // the fields of the spec are flattened into options of the construct
+    const spec = (assembly.types[propsFqn].properties ?? []).find((p: any) => p.name === 'spec');
+    if (spec?.type?.fqn && spec.type.fqn.startsWith(`${assembly.name}.`) && types.isStruct(spec.type.fqn)) {
+      const specStruct = types.localName(spec.type.fqn);
+      const topLevel = new Set(fields.map(f => f.name));
+
+      for (const field of types.fields(spec.type.fqn)) {
+        const name = topLevel.has(field.name) ? `Spec${field.name}` : field.name;
+        emitOption(body, `${construct}With${name}`, construct, propsStruct, field, `${propsStruct}.Spec.${field.name}`, specStruct);
+      }
+    }
+  }
+
+  const header = [
+    '// Code generated by cdk8s import. DO NOT EDIT.',
+    '',
+    `package ${packageName}`,
+    '',
+    'import (',
+    ...Array.from(types.imports).sort().map(i => `\t"${i}"`),
+    ')',
+    '',
+  ];
+
+  return [...header, ...body].join('\n');
+}
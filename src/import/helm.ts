@@ -0,0 +1,97 @@
+import { shell } from '../util';
+
+/**
+ *
+ *              helm:https://charts.example.com/mychart@1.2.3
+ *              |--^-|---------------^-------| |--^--| |-^-|
+ *                 |               |              |      |
+ *  - scheme ------+               |              |      |
+ *  - repo ------------------------+              |      |
+ *  - chart --------------------------------------+      |
+ *  - version -------------------------------------------+
+ */
+
+/**
+ * A reference to a helm chart.
+ */
+export interface HelmChartReference {
+  /**
+   * The URL of the chart repository.
+   *
+   * @default - the chart is a local chart directory/archive or an OCI reference.
+   */
+  readonly repo?: string;
+
+  /**
+   * The chart name, path or OCI reference (e.g. "mychart", "./local-chart.tgz").
+   */
+  readonly chart: string;
+
+  /**
+   * The chart version, passed to helm as-is (e.g. "1.2.3", "v1.2.3" or a
+   * constraint like "~1.2").
+   *
+   * @default - latest version
+   */
+  readonly version?: string;
+}
+
+/**
+ * Matches a "helm:" import source
+ *
+ *  - chart reference if found
+ *  - undefined if not
+ *
+ * @param source
+ */
+export function matchHelmChart(source: string): (undefined | HelmChartReference) {
+  const match = /^helm:(.+?)(?:@([^@/]+))?$/.exec(source);
+  if (!match) {
+    return undefined;
+  }
+
+  const location = match[1];
+  const version = match[2];
+
+  // oci references are passed to helm as-is
+  if (location.startsWith('oci://')) {
+    return { chart: location, version };
+  }
+
+  // https://charts.example.com/mychart => repo and chart name
+  if (/^https?:\/\//.test(location)) {
+    const idx = location.lastIndexOf('/');
+    const repo = location.substring(0, idx);
+    const chart = location.substring(idx + 1);
+    if (!chart || repo.endsWith(':/')) {
+      throw new Error(`Expected helm chart "${location}" to match format "helm:<repo-url>/<chart>[@<version>]".`);
+    }
+
+    return { repo, chart, version };
+  }
+
+  // local chart directory or archive
+  return { chart: location, version };
+}
+
+/**
+ * Renders a helm chart (including the CRDs in its "crds/" directory) and
+ * returns the resulting multi-document manifest.
+ */
+export async function renderHelmChart(ref: HelmChartReference): Promise<string> {
+  const args = ['template', 'cdk8s', ref.chart, '--include-crds'];
+
+  if (ref.repo) {
+    args.push('--repo', ref.repo);
+  }
+
+  if (ref.version) {
+    args.push('--version', ref.version);
+  }
+
+  try {
+    return await shell('helm', args);
+  } catch (e) {
+    throw new Error(`Unable to render helm chart "${ref.chart}" (make sure "helm" is installed): ${e}`);
+  }
+}
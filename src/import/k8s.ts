@@ -6,7 +6,9 @@ import { JSONSchema4 } from 'json-schema';
 
 import { TypeGenerator } from 'json2jsii';
 import { ImportSpec } from '../config';
-import { download } from '../util';
+import { getServerVersion, kubectl, KubectlOptions } from '../kubectl';
+import { logger } from '../logger';
+import { download, DownloadOptions } from '../util';
 import { GenerateOptions, ImportBase } from './base';
 import { ApiObjectDefinition, emitHeader, generateConstruct, getPropsTypeName, getTypeName } from './codegen';
 import { parseApiTypeName, safeParseJsonSchema } from './k8s-util';
@@ -16,6 +18,8 @@ export const DEFAULT_API_VERSION = '1.22.0';
 
 const DEFAULT_CLASS_NAME_PREFIX = 'Kube';
 
+const AGGREGATED_API_PREFIX = 'aggregated:';
+
 export interface ImportKubernetesApiOptions {
   /**
    * The API version to generate.
@@ -28,16 +32,76 @@ export interface ImportKubernetesApiOptions {
    * @default - include all types that derive from the root types.
    */
   readonly exclude?: string[];
+
+  /**
+   * Generate the types from the OpenAPI spec served by a cluster instead of
+   * the published schema of `apiVersion`.
+   *
+   * @default false
+   */
+  readonly fromCluster?: boolean;
+
+  /**
+   * The kubeconfig context of the cluster (only with `fromCluster`).
+   *
+   * @default - the current context
+   */
+  readonly kubeContext?: string;
+
+  /**
+   * Only import the API objects of this aggregated API (e.g.
+   * "metrics.k8s.io/v1beta1"), from the OpenAPI spec served for its group
+   * version. They are emitted into a module named after the group. Requires
+   * `fromCluster`.
+   *
+   * @default - all API objects of the spec are imported into the "k8s" module
+   */
+  readonly aggregatedApi?: string;
+
+  /**
+   * Retry a failed download of the schema this many times.
+   *
+   * @default 0
+   */
+  readonly retries?: number;
+
+  /**
+   * The maximum delay between retries (ms).
+   *
+   * @default DEFAULT_DOWNLOAD_RETRY_MAX_DELAY
+   */
+  readonly retryMaxDelay?: number;
 }
 
 export class ImportKubernetesApi extends ImportBase {
 
   public static async match(importSpec: ImportSpec, argv: any): Promise<ImportKubernetesApiOptions | undefined> {
     const { source } = importSpec;
+    if (source.startsWith(AGGREGATED_API_PREFIX)) {
+      return this.matchAggregatedApi(source.slice(AGGREGATED_API_PREFIX.length), argv);
+    }
+
     if (source !== 'k8s' && !source.startsWith('k8s@')) {
       return undefined;
     }
 
+    if (argv.fromCluster) {
+      if (source !== 'k8s') {
+        throw new Error(`A k8s version ("${source}") cannot be used in combination with --from-cluster`);
+      }
+
+      const clusterOptions = { context: argv.kubeContext };
+      const clusterVersion = await getServerVersion(clusterOptions);
+      logger.info(`Importing k8s v${clusterVersion} from cluster...`);
+
+      return {
+        apiVersion: clusterVersion,
+        exclude: argv.exclude,
+        fromCluster: true,
+        kubeContext: argv.kubeContext,
+      };
+    }
+
     let k8sVersion = source.split('@')[1] ?? DEFAULT_API_VERSION;
 
     const k8sVersionRegex = /^\d+\.\d+\.\d+$/;
@@ -45,31 +109,82 @@ export class ImportKubernetesApi extends ImportBase {
       throw new Error(`Expected k8s version "${k8sVersion}" to match format "<major>.<minor>.<patch>".`);
     }
 
-    console.error(`Importing k8s v${k8sVersion}...`);
+    logger.info(`Importing k8s v${k8sVersion}...`);
 
     return {
       apiVersion: k8sVersion,
       exclude: argv.exclude,
+      retries: argv.retries,
+      retryMaxDelay: argv.retryMaxDelay,
     };
   }
 
+  private static async matchAggregatedApi(groupVersion: string, argv: any): Promise<ImportKubernetesApiOptions> {
+    if (!/^[^/]+\/[^/]+$/.test(groupVersion)) {
+      throw new Error(`Expected aggregated API "${groupVersion}" to match format "<group>/<version>".`);
+    }
+
+    if (!argv.fromCluster) {
+      throw new Error(`The aggregated API "${groupVersion}" can only be imported with --from-cluster`);
+    }
+
+    const clusterVersion = await getServerVersion({ context: argv.kubeContext });
+    logger.info(`Importing aggregated API ${groupVersion} from cluster...`);
+
+    return {
+      apiVersion: clusterVersion,
+      exclude: argv.exclude,
+      fromCluster: true,
+      kubeContext: argv.kubeContext,
+      aggregatedApi: groupVersion,
+    };
+  }
+
+  private schema?: Promise<JSONSchema4>;
+
   constructor(private readonly options: ImportKubernetesApiOptions) {
     super();
   }
 
   public get moduleNames() {
-    return ['k8s'];
+    return [this.moduleName];
+  }
+
+  private get moduleName() {
+    return this.options.aggregatedApi?.split('/')[0] ?? 'k8s';
+  }
+
+  /**
+   * Downloads (or fetches from the cluster) the schema of the API. The schema
+   * is only fetched once.
+   */
+  public async loadSchema(): Promise<JSONSchema4> {
+    if (!this.schema) {
+      const kubectlOptions = { context: this.options.kubeContext };
+      this.schema = this.options.aggregatedApi
+        ? fetchAggregatedApiSchema(this.options.aggregatedApi, kubectlOptions)
+        : this.options.fromCluster
+          ? fetchClusterSchema(kubectlOptions)
+          : downloadSchema(this.options.apiVersion, { retries: this.options.retries, retryMaxDelay: this.options.retryMaxDelay });
+    }
+
+    return this.schema;
   }
 
   protected async generateTypeScript(code: CodeMaker, moduleName: string, options: GenerateOptions) {
-    const schema = await downloadSchema(this.options.apiVersion);
+    const schema = await this.loadSchema();
 
-    if (moduleName !== 'k8s') {
-      throw new Error(`unexpected module name "${moduleName}" when importing k8s types (expected "k8s")`);
+    if (moduleName !== this.moduleName) {
+      throw new Error(`unexpected module name "${moduleName}" when importing k8s types (expected "${this.moduleName}")`);
     }
 
     const prefix = options.classNamePrefix ?? DEFAULT_CLASS_NAME_PREFIX;
-    const topLevelObjects = findApiObjectDefinitions(schema, prefix);
+    const topLevelObjects = findApiObjectDefinitions(schema, prefix)
+      .filter(o => !this.options.aggregatedApi || `${o.group}/${o.version}` === this.options.aggregatedApi);
+
+    if (this.options.aggregatedApi && topLevelObjects.length === 0) {
+      throw new Error(`The OpenAPI spec of the aggregated API "${this.options.aggregatedApi}" does not define any API objects`);
+    }
 
     const typeGenerator = new TypeGenerator({
       definitions: schema.definitions,
@@ -170,13 +285,117 @@ export interface GroupVersionKind {
 
 const X_GROUP_VERSION_KIND = 'x-kubernetes-group-version-kind';
 
-async function downloadSchema(apiVersion: string) {
+/**
+ * Fetches the OpenAPI spec served by a cluster. Uses the v2 spec if available
+ * and falls back to merging the v3 specs of all group versions.
+ */
+async function fetchClusterSchema(options: KubectlOptions): Promise<JSONSchema4> {
+  let output;
+  try {
+    output = await kubectl(['get', '--raw', '/openapi/v2'], options);
+  } catch (e) {
+    logger.info('OpenAPI v2 is not available, falling back to OpenAPI v3...');
+    return fetchClusterSchemaV3(options);
+  }
+
+  return parseOpenApiV2(output);
+}
+
+async function fetchClusterSchemaV3(options: KubectlOptions): Promise<JSONSchema4> {
+  const index = JSON.parse(await kubectl(['get', '--raw', '/openapi/v3'], options));
+  const definitions: Record<string, JSONSchema4> = { };
+
+  for (const groupVersion of Object.values<any>(index.paths ?? { })) {
+    Object.assign(definitions, await fetchOpenApiV3Definitions(groupVersion.serverRelativeURL, options));
+  }
+
+  return { definitions };
+}
+
+/**
+ * Fetches the OpenAPI spec of an aggregated API (e.g. "metrics.k8s.io/v1beta1")
+ * that is served by an extension API server. Uses the v3 spec of the group
+ * version if available and falls back to the v2 spec of the cluster, which
+ * includes the aggregated APIs.
+ */
+async function fetchAggregatedApiSchema(groupVersion: string, options: KubectlOptions): Promise<JSONSchema4> {
+  // discovery is proxied to the extension API server, so this fails if the
+  // server is unreachable (even though the APIService is registered)
+  try {
+    await kubectl(['get', '--raw', `/apis/${groupVersion}`], options);
+  } catch (e) {
+    const status = await getApiServiceStatus(groupVersion, options);
+    throw new Error(`The aggregated API "${groupVersion}" is unreachable${status ? ` (${status})` : ''}. Make sure its APIService is registered and available: ${e}`);
+  }
+
+  let index;
+  try {
+    index = JSON.parse(await kubectl(['get', '--raw', '/openapi/v3'], options));
+  } catch (e) {
+    logger.info('OpenAPI v3 is not available, falling back to OpenAPI v2...');
+    return parseOpenApiV2(await kubectl(['get', '--raw', '/openapi/v2'], options));
+  }
+
+  const spec = index.paths?.[`apis/${groupVersion}`];
+  if (!spec) {
+    throw new Error(`The cluster does not serve an OpenAPI spec for the aggregated API "${groupVersion}"`);
+  }
+
+  return { definitions: await fetchOpenApiV3Definitions(spec.serverRelativeURL, options) };
+}
+
+/**
+ * Returns the reason why the APIService of a group version is not available,
+ * or `undefined` if it is available or its status cannot be determined.
+ */
+async function getApiServiceStatus(groupVersion: string, options: KubectlOptions): Promise<string | undefined> {
+  const [group, version] = groupVersion.split('/');
+  const name = `${version}.${group}`;
+
+  let apiService;
+  try {
+    apiService = JSON.parse(await kubectl(['get', 'apiservice', name, '-o', 'json'], options));
+  } catch (e) {
+    return `APIService "${name}" not found`;
+  }
+
+  const available = (apiService.status?.conditions ?? []).find((c: any) => c.type === 'Available');
+  if (!available || available.status === 'True') {
+    return undefined;
+  }
+
+  return `APIService "${name}" is not available: ${[available.reason, available.message].filter(x => x).join(', ')}`;
+}
+
+function parseOpenApiV2(output: string): JSONSchema4 {
+  // only the definitions are needed (paths contain keys that are not safe)
+  try {
+    return safeParseJsonSchema(JSON.stringify({ definitions: JSON.parse(output).definitions })) as JSONSchema4;
+  } catch (e) {
+    throw new Error(`Unable to parse the OpenAPI spec of the cluster: ${e}`);
+  }
+}
+
+async function fetchOpenApiV3Definitions(serverRelativeURL: string, options: KubectlOptions): Promise<Record<string, JSONSchema4>> {
+  const spec = JSON.parse(await kubectl(['get', '--raw', serverRelativeURL], options));
+
+  // v3 specs reference "#/components/schemas/..." instead of "#/definitions/..."
+  const schemas = JSON.stringify(spec.components?.schemas ?? { }).replace(/"#\/components\/schemas\//g, '"#/definitions/');
+
+  try {
+    return safeParseJsonSchema(`{"definitions":${schemas}}`).definitions ?? { };
+  } catch (e) {
+    throw new Error(`Unable to parse the OpenAPI spec ${serverRelativeURL} of the cluster: ${e}`);
+  }
+}
+
+async function downloadSchema(apiVersion: string, options: DownloadOptions) {
   const url = `https://raw.githubusercontent.com/cdk8s-team/cdk8s/master/kubernetes-schemas/v${apiVersion}/_definitions.json`;
   let output;
   try {
-    output = await download(url);
+    output = await download(url, options);
   } catch (e) {
-    console.error(`Could not find a schema for k8s version ${apiVersion}. The current list of available schemas is at https://github.com/cdk8s-team/cdk8s/tree/master/kubernetes-schemas.`);
+    logger.error(`Could not find a schema for k8s version ${apiVersion}. The current list of available schemas is at https://github.com/cdk8s-team/cdk8s/tree/master/kubernetes-schemas.`);
     throw e;
   }
   try {
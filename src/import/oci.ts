@@ -0,0 +1,99 @@
+import * as path from 'path';
+import * as fs from 'fs-extra';
+import { shell } from '../util';
+
+/**
+ *
+ *              oci://registry.example.com/crds/myapp:v1
+ *              |-^--||--------^---------| |---^----| |^
+ *                |            |               |       |
+ *  - scheme -----+            |               |       |
+ *  - registry ----------------+               |       |
+ *  - repository ------------------------------+       |
+ *  - tag or digest (@sha256:...) ---------------------+
+ */
+
+/**
+ * A reference to an OCI artifact.
+ */
+export interface OciArtifactReference {
+  readonly registry: string;
+  readonly repository: string;
+
+  /**
+   * The tag of the artifact.
+   *
+   * @default "latest" (unless a digest is specified)
+   */
+  readonly tag?: string;
+
+  /**
+   * The digest of the artifact (e.g. "sha256:...").
+   *
+   * @default - the tag is used
+   */
+  readonly digest?: string;
+}
+
+const ARCHIVE_EXTENSIONS = ['.tar', '.tgz', '.tar.gz'];
+
+/**
+ * Matches an "oci://" import source
+ *
+ *  - artifact reference if found
+ *  - undefined if not
+ *
+ * @param source
+ */
+export function matchOciArtifact(source: string): (undefined | OciArtifactReference) {
+  if (!source.startsWith('oci://')) {
+    return undefined;
+  }
+
+  const match = /^oci:\/\/([^/]+)\/([a-z0-9._\/-]+?)(?::([A-Za-z0-9_][A-Za-z0-9._-]*))?(?:@(sha256:[a-f0-9]{64}))?$/.exec(source);
+  if (!match) {
+    throw new Error(`Expected OCI artifact "${source}" to match format "oci://<registry>/<repository>[:<tag>|@<digest>]".`);
+  }
+
+  const [, registry, repository, tag, digest] = match;
+  return { registry, repository, tag, digest };
+}
+
+/**
+ * Returns the reference passed to `oras`.
+ */
+export function formatOciArtifact(ref: OciArtifactReference): string {
+  const name = `${ref.registry}/${ref.repository}`;
+  if (ref.digest) {
+    return `${name}@${ref.digest}`;
+  }
+
+  return `${name}:${ref.tag ?? 'latest'}`;
+}
+
+/**
+ * Pulls all layers of an OCI artifact into `outdir` using `oras`. Layers that
+ * are archives are extracted. Credentials are taken from the docker config
+ * (`~/.docker/config.json`) and its credential helpers.
+ */
+export async function pullOciArtifact(ref: OciArtifactReference, outdir: string) {
+  const reference = formatOciArtifact(ref);
+
+  try {
+    await shell('oras', ['pull', reference, '--output', outdir]);
+  } catch (e) {
+    throw new Error(`Unable to pull OCI artifact "${reference}" (make sure "oras" is installed and you are logged in to the registry): ${e}`);
+  }
+
+  for (const file of await fs.readdir(outdir)) {
+    if (!ARCHIVE_EXTENSIONS.some(ext => file.endsWith(ext))) {
+      continue;
+    }
+
+    const archive = path.join(outdir, file);
+    const target = path.join(outdir, `${file}.d`);
+    await fs.mkdirp(target);
+    await shell('tar', ['-xf', archive, '-C', target]);
+    await fs.remove(archive);
+  }
+}
@@ -0,0 +1,678 @@
+import * as path from 'path';
+import { CodeMaker } from 'codemaker';
+import * as fs from 'fs-extra';
+// we just need the types from json-schema
+// eslint-disable-next-line import/no-extraneous-dependencies
+import { JSONSchema4 } from 'json-schema';
+import { TypeGenerator } from 'json2jsii';
+import { logger } from '../logger';
+import { SafeReviver } from '../reviver';
+import { GenerateOptions, ImportBase } from './base';
+import { ApiObjectDefinition, emitHeader, generateConstruct, getPropsTypeName } from './codegen';
+
+/**
+ *
+ *     proto:./api/widget.proto
+ *     |-^-| |------^---------|
+ *       |          |
+ *  - scheme        |
+ *  - file ---------+
+ *
+ * The package of the file determines the API group and version of the
+ * generated constructs (e.g. "package widgets.example.com.v1alpha1;" =>
+ * "widgets.example.com/v1alpha1"). Each top-level message with a "spec" field
+ * is imported as a custom resource whose kind is the message name.
+ */
+const PROTO_SCHEME = 'proto:';
+
+const VERSION_REGEX = /^v\d+((alpha|beta)\d+)?$/;
+
+// imports of the well-known types are mapped to JSON types, see
+// https://protobuf.dev/programming-guides/proto3/#json
+const WELL_KNOWN_PREFIX = 'google/protobuf/';
+
+const SCALAR_TYPES: Record<string, JSONSchema4> = {
+  double: { type: 'number' },
+  float: { type: 'number' },
+  int32: { type: 'integer', format: 'int32' },
+  uint32: { type: 'integer', format: 'int32' },
+  sint32: { type: 'integer', format: 'int32' },
+  fixed32: { type: 'integer', format: 'int32' },
+  sfixed32: { type: 'integer', format: 'int32' },
+  int64: { type: 'integer', format: 'int64' },
+  uint64: { type: 'integer', format: 'int64' },
+  sint64: { type: 'integer', format: 'int64' },
+  fixed64: { type: 'integer', format: 'int64' },
+  sfixed64: { type: 'integer', format: 'int64' },
+  bool: { type: 'boolean' },
+  string: { type: 'string' },
+  bytes: { type: 'string', format: 'byte' },
+};
+
+const WELL_KNOWN_TYPES: Record<string, JSONSchema4> = {
+  'google.protobuf.Timestamp': { type: 'string', format: 'date-time' },
+  'google.protobuf.Duration': { type: 'string' },
+  'google.protobuf.FieldMask': { type: 'string' },
+  'google.protobuf.Struct': { type: 'object', additionalProperties: { } },
+  'google.protobuf.Value': { },
+  'google.protobuf.ListValue': { type: 'array', items: { } },
+  'google.protobuf.Any': { type: 'object', additionalProperties: { } },
+  'google.protobuf.Empty': { type: 'object' },
+  'google.protobuf.DoubleValue': SCALAR_TYPES.double,
+  'google.protobuf.FloatValue': SCALAR_TYPES.float,
+  'google.protobuf.Int32Value': SCALAR_TYPES.int32,
+  'google.protobuf.UInt32Value': SCALAR_TYPES.uint32,
+  'google.protobuf.Int64Value': SCALAR_TYPES.int64,
+  'google.protobuf.UInt64Value': SCALAR_TYPES.uint64,
+  'google.protobuf.BoolValue': SCALAR_TYPES.bool,
+  'google.protobuf.StringValue': SCALAR_TYPES.string,
+  'google.protobuf.BytesValue': SCALAR_TYPES.bytes,
+};
+
+/**
+ * Matches a "proto:" import source.
+ *
+ * @returns the path of the .proto file or `undefined` if the source is not a
+ * protobuf import.
+ */
+export function matchProtoFile(source: string): string | undefined {
+  if (!source.startsWith(PROTO_SCHEME)) {
+    return undefined;
+  }
+
+  const file = source.slice(PROTO_SCHEME.length);
+  if (!file) {
+    throw new Error(`Invalid protobuf import "${source}". Expected "${PROTO_SCHEME}<file>.proto"`);
+  }
+
+  return file;
+}
+
+/**
+ * A .proto file that was read but not yet parsed.
+ */
+export interface ProtoSourceFile {
+  readonly file: string;
+  readonly content: string;
+}
+
+/**
+ * Reads a .proto file and (transitively) the files it imports. Imports are
+ * resolved relative to the directory of `file` and then relative to the
+ * directory of the importing file. Imports that cannot be resolved
+ * (e.g. of options-only files) are skipped with a warning. Their types are
+ * represented as `any`.
+ *
+ * @returns the files, starting with `file`
+ */
+export async function loadProtoFiles(file: string): Promise<ProtoSourceFile[]> {
+  const root = path.dirname(path.resolve(file));
+  const files = new Array<ProtoSourceFile>();
+  const seen = new Set<string>();
+
+  const load = async (location: string) => {
+    if (seen.has(location)) {
+      return;
+    }
+    seen.add(location);
+
+    const content = await fs.readFile(location, 'utf-8');
+    files.push({ file: location, content });
+
+    for (const imported of parseProto(content, location).imports) {
+      if (imported.startsWith(WELL_KNOWN_PREFIX)) {
+        continue;
+      }
+
+      const candidates = [path.join(root, imported), path.join(path.dirname(location), imported)];
+      const resolved = candidates.find(c => fs.existsSync(c));
+      if (!resolved) {
+        logger.warn(`unable to resolve import "${imported}" of ${path.relative(process.cwd(), location)}, its types are represented as "any"`);
+        continue;
+      }
+
+      await load(resolved);
+    }
+  };
+
+  await load(path.resolve(file));
+  return files;
+}
+
+/**
+ * A field of a protobuf message.
+ */
+export interface ProtoField {
+  readonly name: string;
+  readonly type: string;
+  readonly label?: 'optional' | 'required' | 'repeated';
+
+  /**
+   * The key type of `map<K, V>` fields (`type` is the value type).
+   */
+  readonly keyType?: string;
+  readonly jsonName?: string;
+  readonly comment?: string;
+}
+
+export interface ProtoMessage {
+  readonly name: string;
+
+  /**
+   * The fully qualified name without the leading "." (e.g. "pkg.Outer.Inner").
+   */
+  readonly fullName: string;
+  readonly fields: ProtoField[];
+  readonly comment?: string;
+
+  /**
+   * Whether the message is declared at the top level of the file.
+   */
+  readonly topLevel: boolean;
+}
+
+export interface ProtoEnum {
+  readonly fullName: string;
+  readonly values: string[];
+  readonly comment?: string;
+}
+
+/**
+ * The declarations of a .proto file. Nested messages and enums are flattened.
+ */
+export interface ProtoFile {
+  readonly package?: string;
+  readonly imports: string[];
+  readonly messages: ProtoMessage[];
+  readonly enums: ProtoEnum[];
+}
+
+interface Token {
+  readonly value: string;
+  readonly line: number;
+
+  /**
+   * The comment on the lines before the token.
+   */
+  readonly comment?: string;
+
+  /**
+   * Whether the token is a string literal (`value` is unquoted).
+   */
+  readonly string?: boolean;
+}
+
+const WORD_REGEX = /[\w.+-]+/y;
+
+function tokenize(text: string, file: string): Token[] {
+  const tokens = new Array<Token>();
+  let comments = new Array<string>();
+  let line = 1;
+  let lastLine = 0;
+
+  const push = (token: Omit<Token, 'line' | 'comment'>) => {
+    tokens.push({ ...token, line, comment: comments.length > 0 ? comments.join('\n') : undefined });
+    comments = [];
+    lastLine = line;
+  };
+
+  for (let i = 0; i < text.length;) {
+    const char = text[i];
+
+    if (char === '\n') {
+      line++;
+      i++;
+    } else if (/\s/.test(char)) {
+      i++;
+    } else if (text.startsWith('//', i)) {
+      const end = text.indexOf('\n', i) === -1 ? text.length : text.indexOf('\n', i);
+      // trailing comments (after a declaration on the same line) are ignored
+      if (line !== lastLine) {
+        comments.push(text.slice(i + 2, end).trim());
+      }
+      i = end;
+    } else if (text.startsWith('/*', i)) {
+      const end = text.indexOf('*/', i + 2);
+      if (end === -1) {
+        throw new Error(`${file}:${line}: unterminated comment`);
+      }
+      const comment = text.slice(i + 2, end);
+      comments.push(...comment.split('\n').map(l => l.replace(/^\s*\*?\s?/, '').trimEnd()).filter(l => l));
+      line += comment.split('\n').length - 1;
+      i = end + 2;
+    } else if (char === '"' || char === '\'') {
+      let value = '';
+      let j = i + 1;
+      for (; j < text.length && text[j] !== char; j++) {
+        if (text[j] === '\n') {
+          throw new Error(`${file}:${line}: unterminated string`);
+        }
+        value += text[j] === '\\' ? text[++j] : text[j];
+      }
+      push({ value, string: true });
+      i = j + 1;
+    } else {
+      WORD_REGEX.lastIndex = i;
+      const word = WORD_REGEX.exec(text);
+      const value = word ? word[0] : char;
+      push({ value });
+      i += value.length;
+    }
+  }
+
+  return tokens;
+}
+
+/**
+ * Parses the declarations of a .proto file (proto2 or proto3). Services,
+ * extensions and options (except "json_name") are ignored.
+ */
+export function parseProto(text: string, file: string): ProtoFile {
+  const tokens = tokenize(text, file);
+  let pos = 0;
+
+  const result = {
+    package: undefined as string | undefined,
+    imports: new Array<string>(),
+    messages: new Array<ProtoMessage>(),
+    enums: new Array<ProtoEnum>(),
+  };
+
+  const fail = (message: string): never => {
+    const token = tokens[Math.min(pos, tokens.length - 1)];
+    throw new Error(`${file}:${token?.line ?? 1}: ${message}`);
+  };
+
+  const peek = () => tokens[pos]?.value;
+  const next = (): Token => tokens[pos++] ?? fail('unexpected end of file');
+  const expect = (value: string) => {
+    const token = next();
+    if (token.value !== value || token.string) {
+      fail(`expected "${value}" but found "${token.value}"`);
+    }
+  };
+
+  const skipStatement = () => {
+    while (next().value !== ';');
+  };
+
+  const skipBlock = () => {
+    while (next().value !== '{');
+    for (let depth = 1; depth > 0;) {
+      const value = next().value;
+      depth += value === '{' ? 1 : value === '}' ? -1 : 0;
+    }
+  };
+
+  // [json_name = "x", deprecated = true, (custom.option) = { ... }]
+  const parseFieldOptions = (): Record<string, string> => {
+    const options: Record<string, string> = { };
+    if (peek() !== '[') {
+      return options;
+    }
+
+    next();
+    while (peek() !== ']') {
+      const name = new Array<string>();
+      while (peek() !== '=') {
+        name.push(next().value);
+      }
+      next();
+
+      if (peek() === '{') {
+        for (let depth = 0; ;) {
+          const value = next().value;
+          depth += value === '{' ? 1 : value === '}' ? -1 : 0;
+          if (depth === 0) {
+            break;
+          }
+        }
+      } else {
+        options[name.join('')] = next().value;
+      }
+
+      if (peek() === ',') {
+        next();
+      }
+    }
+    next();
+
+    return options;
+  };
+
+  const parseField = (fields: ProtoField[], label?: ProtoField['label']) => {
+    // the comment is attached to the label if there is one
+    const comment = (label ? tokens[pos - 1] : tokens[pos])?.comment;
+    let type = next().value;
+    let keyType: string | undefined;
+
+    if (type === 'group') {
+      fail('groups are not supported');
+    }
+
+    if (type === 'map' && peek() === '<') {
+      next();
+      keyType = next().value;
+      expect(',');
+      type = next().value;
+      expect('>');
+    }
+
+    const name = next().value;
+    expect('=');
+    next();
+    const options = parseFieldOptions();
+    expect(';');
+
+    fields.push({ name, type, label, keyType, jsonName: options.json_name, comment });
+  };
+
+  const packageScope = () => result.package ? `${result.package}.` : '';
+
+  const parseEnum = (scope: string) => {
+    const comment = tokens[pos - 1].comment;
+    const fullName = `${scope}${next().value}`;
+    const values = new Array<string>();
+
+    expect('{');
+    while (peek() !== '}') {
+      const value = next().value;
+      if (value === 'option' || value === 'reserved') {
+        skipStatement();
+      } else if (value !== ';') {
+        values.push(value);
+        expect('=');
+        next();
+        parseFieldOptions();
+        expect(';');
+      }
+    }
+    next();
+
+    result.enums.push({ fullName, values, comment });
+  };
+
+  const parseMessage = (scope: string) => {
+    const comment = tokens[pos - 1].comment;
+    const name = next().value;
+    const fullName = `${scope}${name}`;
+    const fields = new Array<ProtoField>();
+
+    // the message is added before its nested messages
+    result.messages.push({ name, fullName, fields, comment, topLevel: scope === packageScope() });
+
+    expect('{');
+    while (peek() !== '}') {
+      switch (peek()) {
+        case ';':
+          next();
+          break;
+        case 'message':
+          next();
+          parseMessage(`${fullName}.`);
+          break;
+        case 'enum':
+          next();
+          parseEnum(`${fullName}.`);
+          break;
+        case 'oneof':
+          // fields of a oneof are regular optional fields in JSON
+          next();
+          next();
+          expect('{');
+          while (peek() !== '}') {
+            if (peek() === 'option') {
+              skipStatement();
+            } else {
+              parseField(fields);
+            }
+          }
+          next();
+          break;
+        case 'option':
+        case 'reserved':
+        case 'extensions':
+          skipStatement();
+          break;
+        case 'extend':
+          skipBlock();
+          break;
+        case 'optional':
+        case 'required':
+        case 'repeated':
+          parseField(fields, next().value as ProtoField['label']);
+          break;
+        default:
+          parseField(fields);
+      }
+    }
+    next();
+  };
+
+  while (pos < tokens.length) {
+    const token = next();
+    switch (token.value) {
+      case ';':
+        break;
+      case 'syntax':
+      case 'edition':
+      case 'option':
+        skipStatement();
+        break;
+      case 'package':
+        result.package = next().value;
+        expect(';');
+        break;
+      case 'import': {
+        let imported = next();
+        if (!imported.string) {
+          imported = next(); // "public" or "weak"
+        }
+        result.imports.push(imported.value);
+        expect(';');
+        break;
+      }
+      case 'message':
+        parseMessage(packageScope());
+        break;
+      case 'enum':
+        parseEnum(packageScope());
+        break;
+      case 'service':
+      case 'extend':
+        skipBlock();
+        break;
+      default:
+        fail(`unexpected "${token.value}"`);
+    }
+  }
+
+  return result;
+}
+
+/**
+ * Returns the JSON name of a field (e.g. "max_replicas" => "maxReplicas").
+ */
+export function jsonName(field: ProtoField): string {
+  return field.jsonName ?? field.name.replace(/_([a-z0-9])/g, (_, c) => c.toUpperCase());
+}
+
+/**
+ * Imports constructs for the messages of a .proto file.
+ */
+export class ImportProto extends ImportBase {
+
+  private readonly entry: ProtoFile;
+  private readonly group: string;
+  private readonly version: string;
+  private readonly messages = new Map<string, ProtoMessage>();
+  private readonly enums = new Map<string, ProtoEnum>();
+  private readonly typeNames = new Map<string, string>();
+
+  /**
+   * @param files the .proto file to import, followed by the files it imports
+   */
+  constructor(files: ProtoSourceFile[]) {
+    super();
+
+    const parsed = files.map(f => parseProto(f.content, f.file));
+    this.entry = parsed[0];
+
+    const source = path.relative(process.cwd(), files[0].file);
+    const segments = this.entry.package?.split('.') ?? [];
+    const version = segments.pop();
+    if (!version || !VERSION_REGEX.test(version) || segments.length === 0) {
+      throw new Error(`The package of ${source} must be "<group>.<version>" (e.g. "widgets.example.com.v1"), but is "${this.entry.package ?? ''}"`);
+    }
+
+    this.group = segments.join('.');
+    this.version = version;
+
+    for (const file of parsed) {
+      const packagePrefix = file.package ? `${file.package}.` : '';
+      for (const decl of [...file.messages, ...file.enums]) {
+        if ('fields' in decl) {
+          this.messages.set(decl.fullName, decl);
+        } else {
+          this.enums.set(decl.fullName, decl);
+        }
+
+        // the name of a type is its (nested) name without the package
+        const typeName = qualifiedTypeName(decl.fullName.slice(packagePrefix.length));
+        const taken = Array.from(this.typeNames.values()).includes(typeName);
+        this.typeNames.set(decl.fullName, taken ? qualifiedTypeName(decl.fullName) : typeName);
+      }
+    }
+
+    if (this.kinds.length === 0) {
+      throw new Error(`No custom resources found in ${source}. Top-level messages with a "spec" field are imported as custom resources (kind = message name)`);
+    }
+  }
+
+  public get moduleNames() {
+    return [this.group];
+  }
+
+  /**
+   * The top-level messages of the imported file with a "spec" field.
+   */
+  private get kinds(): ProtoMessage[] {
+    return this.entry.messages.filter(m => m.topLevel && m.fields.some(f => jsonName(f) === 'spec'));
+  }
+
+  protected async generateTypeScript(code: CodeMaker, moduleName: string, options: GenerateOptions) {
+    if (moduleName !== this.group) {
+      throw new Error(`unexpected module name "${moduleName}" when importing ${this.entry.package} (expected "${this.group}")`);
+    }
+
+    const definitions: Record<string, JSONSchema4> = { };
+    for (const message of this.messages.values()) {
+      definitions[message.fullName] = this.messageSchema(message);
+    }
+    for (const e of this.enums.values()) {
+      definitions[e.fullName] = withDescription({ type: 'string', enum: e.values }, e.comment);
+    }
+
+    // comments are emitted as JSDoc, they must not terminate it
+    new SafeReviver({ allowlistedKeys: ['$ref'], sanitizers: [SafeReviver.DESCRIPTION_SANITIZER] }).sanitize(definitions);
+
+    const types = new TypeGenerator({
+      definitions,
+      renderTypeName: (def: string) => this.typeNames.get(def) ?? qualifiedTypeName(def),
+    });
+
+    const defs = this.kinds.map((message): ApiObjectDefinition => ({
+      custom: true,
+      fqn: message.fullName,
+      group: this.group,
+      version: this.version,
+      kind: message.name,
+      schema: definitions[message.fullName],
+      prefix: options.classNamePrefix ?? '',
+    }));
+
+    // references to custom resources use their props type (see k8s imports)
+    for (const def of defs) {
+      types.addDefinition(def.fqn, { $ref: `#/definitions/${getPropsTypeName(def)}` });
+    }
+
+    for (const def of defs) {
+      generateConstruct(types, def);
+    }
+
+    emitHeader(code, true);
+    code.line(types.render());
+  }
+
+  private messageSchema(message: ProtoMessage): JSONSchema4 {
+    const properties: Record<string, JSONSchema4> = { };
+    const required = new Array<string>();
+
+    for (const field of message.fields) {
+      const name = jsonName(field);
+      let schema = this.typeSchema(field.type, message, field);
+
+      if (field.keyType) {
+        // map keys are always strings in JSON
+        schema = { type: 'object', additionalProperties: schema };
+      } else if (field.label === 'repeated') {
+        schema = { type: 'array', items: schema };
+      }
+
+      properties[name] = withDescription(schema, field.comment);
+      if (field.label === 'required') {
+        required.push(name);
+      }
+    }
+
+    const schema: JSONSchema4 = { type: 'object', properties };
+    if (required.length > 0) {
+      schema.required = required;
+    }
+
+    return withDescription(schema, message.comment);
+  }
+
+  /**
+   * Resolves the type of a field in the scope of its message, following the
+   * protobuf scoping rules (innermost scope first).
+   */
+  private typeSchema(type: string, message: ProtoMessage, field: ProtoField): JSONSchema4 {
+    const scalar = SCALAR_TYPES[type];
+    if (scalar) {
+      return scalar;
+    }
+
+    const candidates = new Array<string>();
+    if (type.startsWith('.')) {
+      candidates.push(type.slice(1));
+    } else {
+      const scope = message.fullName.split('.');
+      for (let i = scope.length; i >= 0; i--) {
+        candidates.push([...scope.slice(0, i), type].join('.'));
+      }
+    }
+
+    for (const candidate of candidates) {
+      if (WELL_KNOWN_TYPES[candidate]) {
+        return WELL_KNOWN_TYPES[candidate];
+      }
+
+      if (this.messages.has(candidate) || this.enums.has(candidate)) {
+        return { $ref: `#/definitions/${candidate}` };
+      }
+    }
+
+    logger.warn(`unknown type "${type}" of field "${field.name}" in message "${message.fullName}" is represented as "any"`);
+    return { };
+  }
+}
+
+// "Outer.Inner" => "OuterInner", "pkg.v1.Widget" => "PkgV1Widget"
+function qualifiedTypeName(name: string): string {
+  return name.split('.').map(s => `${s.charAt(0).toUpperCase()}${s.slice(1)}`).join('');
+}
+
+function withDescription(schema: JSONSchema4, description?: string): JSONSchema4 {
+  return description ? { ...schema, description } : schema;
+}
@@ -0,0 +1,245 @@
+import * as path from 'path';
+import { URL } from 'url';
+import * as yaml from 'yaml';
+import { download } from '../util';
+import { ManifestFile } from './crd';
+
+/**
+ * The default timeout for fetching remote references (ms).
+ */
+export const DEFAULT_REMOTE_REF_TIMEOUT = 10_000;
+
+// remote documents are fetched once per run
+const remoteDocuments = new Map<string, Promise<string>>();
+
+/**
+ * The parsed documents of a single manifest file.
+ */
+export interface ParsedManifestFile {
+  /**
+   * The absolute path or URL of the file.
+   */
+  readonly location: string;
+
+  /**
+   * The documents in the file.
+   */
+  readonly documents: any[];
+}
+
+export interface FetchRemoteReferencesOptions {
+  /**
+   * Timeout for fetching a single document (ms).
+   *
+   * @default DEFAULT_REMOTE_REF_TIMEOUT
+   */
+  readonly timeout?: number;
+
+  /**
+   * Retry failed downloads this many times.
+   *
+   * @default 0
+   */
+  readonly retries?: number;
+
+  /**
+   * The maximum delay between retries (ms).
+   *
+   * @default DEFAULT_DOWNLOAD_RETRY_MAX_DELAY
+   */
+  readonly retryMaxDelay?: number;
+}
+
+/**
+ * Fetches all remote documents that are referenced (transitively) from the
+ * given files and are not one of them. Only https URLs are allowed.
+ *
+ * @returns the fetched files
+ */
+export async function fetchRemoteReferences(files: ManifestFile[], options: FetchRemoteReferencesOptions = { }): Promise<ManifestFile[]> {
+  const timeout = options.timeout ?? DEFAULT_REMOTE_REF_TIMEOUT;
+  const known = new Set(files.map(f => f.location));
+  const fetched = new Array<ManifestFile>();
+  const queue = [...files];
+
+  while (queue.length > 0) {
+    const file = queue.shift()!;
+    const documents = yaml.parseAllDocuments(file.content).map(doc => doc.toJS());
+
+    for (const ref of collectReferences(documents)) {
+      const [refFile] = splitReference(ref);
+      if (!refFile) {
+        continue;
+      }
+
+      const target = resolveLocation(file.location, refFile);
+      if (known.has(target) || !/^[a-z]+:\/\//.test(target)) {
+        continue;
+      }
+
+      if (!target.startsWith('https://')) {
+        throw new Error(`Cannot resolve reference "${ref}" in ${file.location}: only https URLs can be fetched`);
+      }
+
+      known.add(target);
+
+      if (!remoteDocuments.has(target)) {
+        remoteDocuments.set(target, download(target, { timeout, retries: options.retries, retryMaxDelay: options.retryMaxDelay }));
+      }
+
+      let content;
+      try {
+        content = await remoteDocuments.get(target)!;
+      } catch (e) {
+        throw new Error(`Unable to fetch reference "${ref}" in ${file.location}: ${e}`);
+      }
+
+      const remote = { location: target, content };
+      fetched.push(remote);
+      queue.push(remote);
+    }
+  }
+
+  return fetched;
+}
+
+function collectReferences(obj: any, refs: string[] = []): string[] {
+  if (Array.isArray(obj)) {
+    obj.forEach(item => collectReferences(item, refs));
+  } else if (typeof(obj) === 'object' && obj !== null) {
+    if (typeof(obj.$ref) === 'string') {
+      refs.push(obj.$ref);
+    }
+    Object.values(obj).forEach(value => collectReferences(value, refs));
+  }
+  return refs;
+}
+
+/**
+ * Resolves a (relative) reference against the location of the file that
+ * contains it.
+ */
+export function resolveLocation(base: string, ref: string): string {
+  if (/^https?:\/\//.test(ref)) {
+    return ref;
+  }
+
+  if (/^https?:\/\//.test(base)) {
+    return new URL(ref, base).toString();
+  }
+
+  return path.resolve(path.dirname(base), ref);
+}
+
+/**
+ * Resolves JSON schema `$ref`s across a set of manifest files by inlining the
+ * referenced definitions.
+ *
+ * References can either be local to the document that contains them
+ * (`#/definitions/Foo`) or point to a document in one of the other files,
+ * relative to the referencing file (`shared.yaml#/definitions/Foo`).
+ */
+export class ReferenceResolver {
+
+  private readonly files: Record<string, any[]> = { };
+
+  constructor(files: ParsedManifestFile[]) {
+    for (const file of files) {
+      this.files[file.location] = file.documents;
+    }
+  }
+
+  /**
+   * Returns a copy of `obj` with all references resolved.
+   *
+   * @param obj the object to resolve
+   * @param location the location of the file that contains `obj`
+   * @param document the document within the file that contains `obj`. local
+   * references are resolved against it.
+   */
+  public resolve(obj: any, location: string, document: any = obj): any {
+    return this.resolveObject(obj, location, document, []);
+  }
+
+  private resolveObject(obj: any, location: string, document: any, stack: string[]): any {
+    if (Array.isArray(obj)) {
+      return obj.map(item => this.resolveObject(item, location, document, stack));
+    }
+
+    if (typeof(obj) !== 'object' || obj === null) {
+      return obj;
+    }
+
+    if (typeof(obj.$ref) === 'string') {
+      const { $ref, ...siblings } = obj;
+      const resolved = this.resolveReference($ref, location, document, stack);
+      return { ...resolved, ...this.resolveObject(siblings, location, document, stack) };
+    }
+
+    const result: any = { };
+    for (const [key, value] of Object.entries(obj)) {
+      result[key] = this.resolveObject(value, location, document, stack);
+    }
+    return result;
+  }
+
+  private resolveReference(ref: string, location: string, document: any, stack: string[]): any {
+    const [file, pointer] = splitReference(ref);
+    const targetLocation = file ? resolveLocation(location, file) : location;
+    const key = `${targetLocation}#${pointer}`;
+
+    if (stack.includes(key)) {
+      throw new Error(`Circular $ref detected: ${[...stack, key].join(' -> ')}`);
+    }
+
+    let target;
+    let targetDocument;
+    if (file) {
+      const documents = this.files[targetLocation];
+      if (!documents) {
+        const hint = targetLocation.startsWith('https://') ? ' (use --resolve-remote-refs to fetch remote references)' : '';
+        throw new Error(`Cannot resolve reference "${ref}" in ${location}: ${targetLocation} is not one of the imported files${hint}`);
+      }
+
+      targetDocument = documents.find(doc => resolvePointer(doc, pointer) !== undefined);
+      target = targetDocument !== undefined ? resolvePointer(targetDocument, pointer) : undefined;
+    } else {
+      targetDocument = document;
+      target = resolvePointer(document, pointer);
+    }
+
+    if (target === undefined) {
+      throw new Error(`Cannot resolve reference "${ref}" in ${location}`);
+    }
+
+    return this.resolveObject(target, targetLocation, targetDocument, [...stack, key]);
+  }
+}
+
+function splitReference(ref: string): [string, string] {
+  const idx = ref.indexOf('#');
+  if (idx === -1) {
+    return [ref, ''];
+  }
+
+  return [ref.substring(0, idx), ref.substring(idx + 1)];
+}
+
+function resolvePointer(doc: any, pointer: string): any {
+  if (pointer === '' || pointer === '/') {
+    return doc === null ? undefined : doc;
+  }
+
+  // see https://datatracker.ietf.org/doc/html/rfc6901
+  const tokens = pointer.replace(/^\//, '').split('/').map(t => t.replace(/~1/g, '/').replace(/~0/g, '~'));
+
+  let current = doc;
+  for (const token of tokens) {
+    if (typeof(current) !== 'object' || current === null || !(token in current)) {
+      return undefined;
+    }
+    current = current[token];
+  }
+
+  return current;
+}
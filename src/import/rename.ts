@@ -0,0 +1,159 @@
+import { toCamelCase, toPascalCase } from 'codemaker';
+import * as fs from 'fs-extra';
+// we just need the types from json-schema
+// eslint-disable-next-line import/no-extraneous-dependencies
+import { JSONSchema4 } from 'json-schema';
+import { TypeGenerator } from 'json2jsii';
+import * as yaml from 'yaml';
+
+/**
+ * Overrides the name of a generated member.
+ */
+export interface PropertyRename {
+  /**
+   * The type that declares the property, either as the generated interface name
+   * (e.g. "IssuerSpecAcme") or as it appears in the `@schema` tag of the
+   * interface. Nested types are named after the (renamed) property that
+   * contains them.
+   *
+   * @default - the property is renamed in all types
+   */
+  readonly type?: string;
+
+  /**
+   * The name of the property in the schema (i.e. the Kubernetes field name).
+   */
+  readonly property: string;
+
+  /**
+   * The name of the generated member (camelCase).
+   */
+  readonly name: string;
+}
+
+/**
+ * Parses rename rules with the syntax "[TYPE#]PROPERTY=NAME".
+ */
+export function parseRenames(rules: string[]): PropertyRename[] {
+  return rules.map(rule => {
+    const eq = rule.lastIndexOf('=');
+    if (eq === -1) {
+      throw new Error(`Invalid rename "${rule}". Expected format "[TYPE#]PROPERTY=NAME"`);
+    }
+
+    return parseRename(rule.slice(0, eq), rule.slice(eq + 1));
+  });
+}
+
+/**
+ * Loads rename rules from a YAML or JSON file that maps "[TYPE#]PROPERTY" to
+ * the name of the generated member.
+ */
+export function loadRenames(file: string): PropertyRename[] {
+  const mapping = yaml.parse(fs.readFileSync(file, 'utf-8')) ?? { };
+  if (typeof(mapping) !== 'object' || Array.isArray(mapping)) {
+    throw new Error(`Expected ${file} to contain a mapping of "[TYPE#]PROPERTY" to "NAME"`);
+  }
+
+  return Object.entries(mapping).map(([from, to]) => parseRename(from, String(to)));
+}
+
+function parseRename(from: string, name: string): PropertyRename {
+  const hash = from.lastIndexOf('#');
+  const type = hash === -1 ? undefined : from.slice(0, hash);
+  const property = from.slice(hash + 1);
+
+  if (!property || type === '') {
+    throw new Error(`Invalid rename "${from}=${name}". Expected format "[TYPE#]PROPERTY=NAME"`);
+  }
+
+  if (!/^[a-z][A-Za-z0-9]*$/.test(name)) {
+    throw new Error(`Invalid rename "${from}=${name}". The name must be camelCase (e.g. "privateKey")`);
+  }
+
+  return { type, property, name };
+}
+
+/**
+ * A schema with renamed properties.
+ */
+export interface RenamedSchema {
+  readonly schema: JSONSchema4;
+
+  /**
+   * The original names of the renamed properties, by their new name (e.g.
+   * `{ properties: { spec: { properties: { key: { name: 'private_key' } } } } }`),
+   * or `undefined` if no property was renamed.
+   */
+  readonly names?: any;
+}
+
+/**
+ * Renames the properties of a struct schema and its nested schemas
+ * (properties, items and maps) before its types are generated, so that the
+ * generated members have the new names. The manifest must still use the
+ * original field names, which are returned in `names`. Referenced schemas are
+ * not followed.
+ *
+ * @param fqn the schema name of the struct (e.g. "Issuer"). Nested structs are
+ * named after the property that contains them (e.g. "IssuerSpec").
+ * @param aliases other names of the struct that renames can refer to (e.g.
+ * "IssuerProps")
+ */
+export function renameSchemaProperties(schema: JSONSchema4, fqn: string, renames: PropertyRename[], aliases: string[] = []): RenamedSchema {
+  if (renames.length === 0 || !schema || typeof(schema) !== 'object' || schema.$ref) {
+    return { schema };
+  }
+
+  const types = [fqn, TypeGenerator.normalizeTypeName(fqn), ...aliases];
+  const copy: JSONSchema4 = { ...schema };
+  const names: any = { };
+
+  if (copy.properties) {
+    const members = Object.keys(copy.properties).map(key => toCamelCase(key));
+    const properties: Record<string, JSONSchema4> = { };
+    const renamed: Record<string, any> = { };
+
+    for (const [key, property] of Object.entries(copy.properties)) {
+      const rename = renames.find(r => r.property === key && (!r.type || types.includes(r.type)));
+      const name = (rename && rename.name !== toCamelCase(key)) ? rename.name : key;
+      if (name !== key && members.includes(name)) {
+        throw new Error(`Cannot rename "${TypeGenerator.normalizeTypeName(fqn)}#${key}" to "${name}": a member with this name already exists`);
+      }
+
+      const nested = renameSchemaProperties(property, `${fqn}${toPascalCase(name)}`, renames);
+      properties[name] = nested.schema;
+
+      if (name !== key || nested.names) {
+        renamed[name] = name === key ? nested.names : { ...nested.names, name: key };
+      }
+    }
+
+    copy.properties = properties;
+    if (Object.keys(renamed).length > 0) {
+      names.properties = renamed;
+      if (Array.isArray(copy.required)) {
+        copy.required = copy.required.map(key => Object.keys(renamed).find(name => renamed[name].name === key) ?? key);
+      }
+    }
+  }
+
+  // the items of arrays and the values of maps are named after the property
+  if (copy.items && !Array.isArray(copy.items)) {
+    const items = renameSchemaProperties(copy.items, fqn, renames);
+    copy.items = items.schema;
+    if (items.names) {
+      names.items = items.names;
+    }
+  }
+
+  if (copy.additionalProperties && typeof(copy.additionalProperties) === 'object') {
+    const values = renameSchemaProperties(copy.additionalProperties, fqn, renames);
+    copy.additionalProperties = values.schema;
+    if (values.names) {
+      names.additionalProperties = values.names;
+    }
+  }
+
+  return { schema: copy, names: Object.keys(names).length > 0 ? names : undefined };
+}
@@ -0,0 +1,129 @@
+// we just need the types from json-schema
+// eslint-disable-next-line import/no-extraneous-dependencies
+import { JSONSchema4 } from 'json-schema';
+
+const PRESERVE_UNKNOWN_FIELDS = 'x-kubernetes-preserve-unknown-fields';
+const INT_OR_STRING = 'x-kubernetes-int-or-string';
+
+// keys that hold a single sub-schema
+const SCHEMA_KEYS = ['items', 'additionalProperties', 'not'];
+
+// keys that hold an array of sub-schemas
+const SCHEMA_ARRAY_KEYS = ['allOf', 'anyOf', 'oneOf'];
+
+// keys that hold a map of sub-schemas
+const SCHEMA_MAP_KEYS = ['properties', 'patternProperties', 'definitions'];
+
+/**
+ * Returns a copy of `schema` where `transform` was applied to every nested
+ * schema (depth first). The root schema itself is not transformed.
+ */
+export function mapSubSchemas(schema: JSONSchema4 | undefined, transform: (schema: JSONSchema4) => JSONSchema4): JSONSchema4 | undefined {
+  if (!schema || typeof(schema) !== 'object') {
+    return schema;
+  }
+
+  const visit = (s: any) => (s && typeof(s) === 'object' && !Array.isArray(s)) ? transform(mapSubSchemas(s, transform)!) : s;
+  const copy: any = { ...schema };
+
+  for (const key of SCHEMA_KEYS) {
+    if (key in copy) {
+      copy[key] = Array.isArray(copy[key]) ? copy[key].map(visit) : visit(copy[key]);
+    }
+  }
+
+  for (const key of SCHEMA_ARRAY_KEYS) {
+    if (Array.isArray(copy[key])) {
+      copy[key] = copy[key].map(visit);
+    }
+  }
+
+  for (const key of SCHEMA_MAP_KEYS) {
+    if (copy[key] && typeof(copy[key]) === 'object') {
+      copy[key] = Object.fromEntries(Object.entries(copy[key]).map(([k, v]) => [k, visit(v)]));
+    }
+  }
+
+  return copy;
+}
+
+/**
+ * Represents free-form objects (`x-kubernetes-preserve-unknown-fields`) as
+ * open maps (e.g. `{ [key: string]: any }`) so that arbitrary values can be
+ * specified. Objects that also declare properties keep their typed struct.
+ */
+export function preserveUnknownFields(schema: JSONSchema4): JSONSchema4 {
+  if (schema[PRESERVE_UNKNOWN_FIELDS] !== true || schema.properties) {
+    return schema;
+  }
+
+  if (schema.type !== undefined && schema.type !== 'object') {
+    return schema;
+  }
+
+  const copy: JSONSchema4 = {
+    ...schema,
+    type: 'object',
+    additionalProperties: (typeof(schema.additionalProperties) === 'object') ? schema.additionalProperties : {},
+  };
+  delete copy[PRESERVE_UNKNOWN_FIELDS];
+  return copy;
+}
+
+/**
+ * Represents `x-kubernetes-int-or-string` values as a union of an integer and a
+ * string so that a union type is generated instead of a plain `string`,
+ * `number` or `any`: `number | string` in TypeScript (see
+ * `numberOrStringUnions`) and a class with `fromNumber` and `fromString`
+ * methods in the other languages. The value is serialized as-is, preserving
+ * its JSON type.
+ */
+export function intOrString(schema: JSONSchema4): JSONSchema4 {
+  if (schema[INT_OR_STRING] !== true) {
+    return schema;
+  }
+
+  const types = (schema.anyOf ?? []).map(s => s.type);
+  if (types.length === 2 && types.includes('integer') && types.includes('string')) {
+    return schema;
+  }
+
+  const copy: JSONSchema4 = {
+    ...schema,
+    anyOf: [{ type: 'integer' }, { type: 'string' }],
+  };
+  delete copy.type;
+  delete copy.format;
+  delete copy.oneOf;
+  return copy;
+}
+
+/**
+ * Represents objects whose values share a schema (e.g.
+ * `map[string]ResourceQuantity`) as maps of the value type (e.g.
+ * `{ [key: string]: Quantity }`), even if the schema of the object omits
+ * `type` or declares no `properties`. Values that are objects are generated as
+ * a named struct. Objects that also declare properties keep their typed struct.
+ */
+export function typedAdditionalProperties(schema: JSONSchema4): JSONSchema4 {
+  const values = schema.additionalProperties;
+  if (!values || typeof(values) !== 'object' || Array.isArray(values) || Object.keys(values).length === 0) {
+    return schema;
+  }
+
+  if (Object.keys(schema.properties ?? {}).length > 0) {
+    return schema;
+  }
+
+  if (schema.type !== undefined && schema.type !== 'object') {
+    return schema;
+  }
+
+  const copy: JSONSchema4 = {
+    ...schema,
+    type: 'object',
+    additionalProperties: (values.properties && values.type === undefined) ? { ...values, type: 'object' } : values,
+  };
+  delete copy.properties;
+  return copy;
+}
@@ -0,0 +1,104 @@
+/**
+ * Replaces the union classes that json2jsii emits for integer or string
+ * values (e.g. `x-kubernetes-int-or-string` fields, used via
+ * `Port.fromNumber(80)`) with `number | string` types that have the same name.
+ * The serialization code passes the values through as-is, preserving their
+ * JSON type.
+ *
+ * Union types cannot be represented by jsii, so this is only applicable to
+ * TypeScript output.
+ *
+ * @param code the rendered types
+ * @returns the code with `number | string` types instead of union classes
+ */
+export function numberOrStringUnions(code: string): string {
+  const lines = code.split('\n');
+  const unions = new Set<string>();
+  const output = new Array<string>();
+
+  for (let i = 0; i < lines.length; i++) {
+    const decl = /^export class (\w+) \{$/.exec(lines[i]);
+    const body = decl ? lines.slice(i + 1, i + 10) : [];
+    if (!decl || !isNumberOrStringUnion(decl[1], body)) {
+      output.push(lines[i]);
+      continue;
+    }
+
+    unions.add(decl[1]);
+    output.push(`export type ${decl[1]} = number | string;`);
+    i += body.length;
+  }
+
+  if (unions.size === 0) {
+    return code;
+  }
+
+  return passThroughValues(output, unions).join('\n');
+}
+
+/**
+ * Whether the body of a class is exactly the one json2jsii emits for a union
+ * of a number and a string (in either order).
+ */
+function isNumberOrStringUnion(name: string, body: string[]) {
+  const from = (method: string, type: string) => [
+    `  public static ${method}(value: ${type}): ${name} {`,
+    `    return new ${name}(value);`,
+    '  }',
+  ];
+  const constructorLines = [
+    /^ {2}private constructor\(public readonly value: (any|number \| string|string \| number)\) \{$/,
+    /^ {2}\}$/,
+    /^\}$/,
+  ];
+
+  const methods = body.slice(0, 6).join('\n');
+  return (methods === [...from('fromNumber', 'number'), ...from('fromString', 'string')].join('\n')
+    || methods === [...from('fromString', 'string'), ...from('fromNumber', 'number')].join('\n'))
+    && constructorLines.every((pattern, i) => pattern.test(body[6 + i] ?? ''));
+}
+
+/**
+ * Removes the `.value` accessors of the union classes from the "toJson_"
+ * functions, based on the property types of the interfaces they serialize.
+ */
+function passThroughValues(lines: string[], unions: Set<string>): string[] {
+  const unionProperties = new Map<string, Set<string>>();
+  const usesUnion = (type: string) => type.split(/\W+/).some(t => unions.has(t));
+
+  let current: Set<string> | undefined;
+  for (const line of lines) {
+    const decl = /^export interface (\w+) \{$/.exec(line);
+    if (decl) {
+      current = new Set();
+      unionProperties.set(decl[1], current);
+      continue;
+    }
+
+    const property = /^ {2}readonly (\w+)\??: (.+);$/.exec(line);
+    if (current && property && usesUnion(property[2])) {
+      current.add(property[1]);
+    }
+  }
+
+  let properties: Set<string> | undefined;
+  return lines.map(line => {
+    const toJson = /^export function toJson_(\w+)\(/.exec(line);
+    if (toJson) {
+      properties = unionProperties.get(toJson[1]);
+      return line;
+    }
+
+    if (line === '}') {
+      properties = undefined;
+      return line;
+    }
+
+    const property = /^ {4}'[^']*': .*?\bobj\.(\w+)/.exec(line);
+    if (properties && property && properties.has(property[1])) {
+      return line.replace(/(?<!\bobj)\??\.value\b/g, '');
+    }
+
+    return line;
+  });
+}
@@ -0,0 +1,406 @@
+import * as fs from 'fs-extra';
+import * as yaml from 'yaml';
+import { toCamelCase, toSnakeCase } from 'codemaker';
+
+// we just need the types from json-schema
+// eslint-disable-next-line import/no-extraneous-dependencies
+import { JSONSchema4 } from 'json-schema';
+
+import { Language } from '../import/base';
+import { getConstructTypeName } from '../import/codegen';
+import { findApiObjectDefinitions } from '../import/k8s';
+import { getFiles } from '../util';
+
+const MANIFEST_EXTENSIONS = ['.yaml', '.yml', '.json'];
+
+// the prefix of the constructs generated by `cdk8s import k8s`
+const CLASS_NAME_PREFIX = 'Kube';
+
+// types that are generated as unions (e.g. `IntOrString.fromNumber(80)`)
+const UNION_TYPES: Record<string, string> = {
+  'io.k8s.apimachinery.pkg.util.intstr.IntOrString': 'IntOrString',
+  'io.k8s.apimachinery.pkg.api.resource.Quantity': 'Quantity',
+};
+
+// "labels" and "annotations" are maps, everything else is a member
+const METADATA_SCHEMA: JSONSchema4 = {
+  properties: {
+    labels: { additionalProperties: { } },
+    annotations: { additionalProperties: { } },
+  },
+};
+
+// python keywords get a trailing underscore in jsii bindings (e.g. "from_")
+const PYTHON_KEYWORDS = ['and', 'as', 'assert', 'async', 'await', 'break', 'class', 'continue', 'def', 'del', 'elif', 'else', 'except',
+  'finally', 'for', 'from', 'global', 'if', 'import', 'in', 'is', 'lambda', 'nonlocal', 'not', 'or', 'pass', 'raise', 'return', 'try',
+  'while', 'with', 'yield'];
+
+/**
+ * The languages `cdk8s init --from-existing` can generate code for.
+ */
+export const FROM_EXISTING_LANGUAGES = [Language.TYPESCRIPT, Language.PYTHON];
+
+/**
+ * Code that is substituted into the `main` file of a project template.
+ */
+export interface ChartCode {
+  /**
+   * Additional import statements (each preceded by a newline).
+   */
+  readonly imports: string;
+
+  /**
+   * The statements that define the resources in the chart constructor.
+   */
+  readonly resources: string;
+}
+
+/**
+ * Reads all resources from a manifest file or a directory of manifests
+ * (`.yaml`, `.yml` and `.json` files). `List` resources are flattened.
+ */
+export async function readExistingManifests(source: string): Promise<any[]> {
+  const files = (await fs.stat(source)).isDirectory()
+    ? (await getFiles(source, '')).filter(f => MANIFEST_EXTENSIONS.some(ext => f.endsWith(ext))).sort()
+    : [source];
+
+  const resources = new Array<any>();
+  for (const file of files) {
+    for (const doc of yaml.parseAllDocuments(await fs.readFile(file, 'utf-8'))) {
+      const resource = doc.toJS();
+      if (resource == null) {
+        continue;
+      }
+
+      if (typeof(resource) !== 'object' || !resource.apiVersion || !resource.kind) {
+        throw new Error(`${file} contains a document that is not a Kubernetes resource (missing "apiVersion" or "kind")`);
+      }
+
+      resources.push(...resource.kind === 'List' ? resource.items ?? [] : [resource]);
+    }
+  }
+
+  return resources;
+}
+
+/**
+ * Returns the code of the empty chart of a project template.
+ */
+export function emptyChartCode(language: Language): ChartCode {
+  switch (language) {
+    case Language.PYTHON:
+      return { imports: '', resources: '        # define resources here\n' };
+    default:
+      return { imports: '', resources: '    // define resources here\n' };
+  }
+}
+
+/**
+ * Generates (best-effort) code that recreates the resources using the
+ * constructs of `cdk8s import k8s`. Resources without a matching construct
+ * (e.g. custom resources) are defined as an `ApiObject`.
+ *
+ * @param schema the schema of the k8s import, if not available all resources
+ * are defined as an `ApiObject`
+ */
+export function generateChartCode(language: Language, resources: any[], schema?: JSONSchema4): ChartCode {
+  const definitions = schema?.definitions ?? { };
+  const constructs = new Map<string, { name: string; schema: JSONSchema4 }>();
+  for (const def of schema ? findApiObjectDefinitions(schema, CLASS_NAME_PREFIX) : []) {
+    const key = `${def.group ? `${def.group}/` : ''}${def.version}/${def.kind}`;
+    if (!constructs.has(key)) {
+      constructs.set(key, { name: getConstructTypeName(def), schema: def.schema });
+    }
+  }
+
+  const ids = new Set<string>();
+  const statements = new Array<ResourceStatement>();
+
+  for (const resource of resources) {
+    const { apiVersion, kind, metadata } = resource;
+    const id = uniqueId(ids, resource);
+    const construct = constructs.get(`${apiVersion}/${kind}`);
+
+    // the status is not part of the desired state
+    const fields = Object.entries(resource).filter(([k]) => !['apiVersion', 'kind', 'status'].includes(k));
+
+    if (construct) {
+      statements.push({ id, className: construct.name, props: toValue(Object.fromEntries(fields), construct.schema, definitions) });
+    } else {
+      statements.push({
+        id,
+        apiVersion,
+        kind,
+        metadata: metadata ? toValue(metadata, METADATA_SCHEMA, definitions) : undefined,
+        fields: fields.filter(([k]) => k !== 'metadata').map(([k, v]) => [k, toValue(v, undefined, definitions)]),
+      });
+    }
+  }
+
+  switch (language) {
+    case Language.TYPESCRIPT:
+      return renderTypeScript(statements);
+    case Language.PYTHON:
+      return renderPython(statements);
+    default:
+      throw new Error(`--from-existing is not supported for ${language} (supported: ${FROM_EXISTING_LANGUAGES.join(', ')})`);
+  }
+}
+
+type Value =
+  | { readonly type: 'literal'; readonly value: any }
+  | { readonly type: 'struct'; readonly members: Array<[string, Value]> }
+  | { readonly type: 'map'; readonly entries: Array<[string, Value]> }
+  | { readonly type: 'list'; readonly items: Value[] }
+  | { readonly type: 'union'; readonly typeName: string; readonly fromNumber: boolean; readonly value: any };
+
+type ResourceStatement = ConstructStatement | ApiObjectStatement;
+
+interface ConstructStatement {
+  readonly id: string;
+  readonly className: string;
+  readonly props: Value;
+}
+
+interface ApiObjectStatement {
+  readonly id: string;
+  readonly apiVersion: string;
+  readonly kind: string;
+  readonly metadata?: Value;
+  readonly fields: Array<[string, Value]>;
+}
+
+function uniqueId(ids: Set<string>, resource: any): string {
+  const kind = String(resource.kind).toLocaleLowerCase();
+  const base = resource.metadata?.name ?? kind;
+
+  let id = ids.has(base) ? `${base}-${kind}` : base;
+  for (let i = 2; ids.has(id); i++) {
+    id = `${base}-${kind}-${i}`;
+  }
+
+  ids.add(id);
+  return id;
+}
+
+/**
+ * Converts a JSON value to the representation of the generated types
+ * described by `schema`. Objects without properties in the schema remain
+ * maps (e.g. labels).
+ */
+function toValue(value: any, schema: JSONSchema4 | undefined, definitions: Record<string, JSONSchema4>): Value {
+  const ref = schema?.$ref?.replace('#/definitions/', '');
+  if (ref && UNION_TYPES[ref] && (typeof(value) === 'number' || typeof(value) === 'string')) {
+    return { type: 'union', typeName: UNION_TYPES[ref], fromNumber: typeof(value) === 'number', value };
+  }
+
+  const resolved = ref ? definitions[ref] : schema;
+
+  if (Array.isArray(value)) {
+    const items = (resolved && typeof(resolved.items) === 'object' && !Array.isArray(resolved.items)) ? resolved.items : undefined;
+    return { type: 'list', items: value.map(v => toValue(v, items, definitions)) };
+  }
+
+  if (typeof(value) === 'object' && value !== null) {
+    const entries = Object.entries(value).filter(([, v]) => v !== undefined);
+
+    const properties = resolved?.properties;
+    if (properties) {
+      return {
+        type: 'struct',
+        members: entries.map(([k, v]) => [memberName(k), toValue(v, properties[k], definitions)]),
+      };
+    }
+
+    const values = (resolved && typeof(resolved.additionalProperties) === 'object') ? resolved.additionalProperties : undefined;
+    return { type: 'map', entries: entries.map(([k, v]) => [k, toValue(v, values, definitions)]) };
+  }
+
+  return { type: 'literal', value };
+}
+
+// e.g. "x-kubernetes-list-type" => "xKubernetesListType" and "$ref" => "ref"
+function memberName(property: string) {
+  return toCamelCase(property.replace(/^[^A-Za-z0-9]+/, ''));
+}
+
+function isConstruct(statement: ResourceStatement): statement is ConstructStatement {
+  return 'className' in statement;
+}
+
+function collectUnions(value: Value | undefined, unions: Set<string>) {
+  switch (value?.type) {
+    case 'union':
+      unions.add(value.typeName);
+      break;
+    case 'struct':
+      value.members.forEach(([, v]) => collectUnions(v, unions));
+      break;
+    case 'map':
+      value.entries.forEach(([, v]) => collectUnions(v, unions));
+      break;
+    case 'list':
+      value.items.forEach(v => collectUnions(v, unions));
+      break;
+  }
+}
+
+// "$" is escaped so that the code can be substituted into the template as-is
+function stringLiteral(value: string, quote: string) {
+  const escaped = JSON.stringify(value).slice(1, -1).replace(/\$/g, '\\u0024');
+  return quote === '"' ? `"${escaped}"` : `'${escaped.replace(/\\"/g, '"').replace(/'/g, '\\\'')}'`;
+}
+
+function renderTypeScript(statements: ResourceStatement[]): ChartCode {
+  const indent = (level: number) => '  '.repeat(level);
+
+  const key = (k: string) => /^[A-Za-z_][A-Za-z0-9_]*$/.test(k) ? k : stringLiteral(k, '\'');
+
+  const render = (value: Value, level: number): string => {
+    switch (value.type) {
+      case 'literal':
+        return typeof(value.value) === 'string' ? stringLiteral(value.value, '\'') : JSON.stringify(value.value);
+      case 'union':
+        return `${value.typeName}.${value.fromNumber ? 'fromNumber' : 'fromString'}(${render({ type: 'literal', value: value.value }, level)})`;
+      case 'list':
+        if (value.items.length === 0) {
+          return '[]';
+        }
+        return `[\n${value.items.map(v => `${indent(level + 1)}${render(v, level + 1)},\n`).join('')}${indent(level)}]`;
+      case 'struct':
+      case 'map': {
+        const entries = value.type === 'struct' ? value.members : value.entries;
+        if (entries.length === 0) {
+          return '{}';
+        }
+        return `{\n${entries.map(([k, v]) => `${indent(level + 1)}${key(k)}: ${render(v, level + 1)},\n`).join('')}${indent(level)}}`;
+      }
+    }
+  };
+
+  const types = new Set<string>();
+  let apiObjects = false;
+  const code = new Array<string>();
+
+  for (const statement of statements) {
+    const id = stringLiteral(statement.id, '\'');
+
+    if (isConstruct(statement)) {
+      types.add(statement.className);
+      collectUnions(statement.props, types);
+      code.push(`${indent(2)}new ${statement.className}(this, ${id}, ${render(statement.props, 2)});\n`);
+    } else {
+      apiObjects = true;
+      statement.fields.forEach(([, v]) => collectUnions(v, types));
+      const props: Value = {
+        type: 'map',
+        entries: [
+          ['apiVersion', { type: 'literal', value: statement.apiVersion }],
+          ['kind', { type: 'literal', value: statement.kind }],
+          ...statement.metadata ? [['metadata', statement.metadata] as [string, Value]] : [],
+          ...statement.fields,
+        ],
+      };
+      code.push(`${indent(2)}new ApiObject(this, ${id}, ${render(props, 2)});\n`);
+    }
+  }
+
+  const imports = new Array<string>();
+  if (apiObjects) {
+    imports.push('\nimport { ApiObject } from \'cdk8s\';');
+  }
+  if (types.size > 0) {
+    imports.push(`\nimport { ${[...types].sort().join(', ')} } from './imports/k8s';`);
+  }
+
+  return { imports: imports.join(''), resources: code.join('\n') };
+}
+
+function renderPython(statements: ResourceStatement[]): ChartCode {
+  const indent = (level: number) => '    '.repeat(level);
+
+  const pythonName = (name: string) => {
+    const snake = toSnakeCase(name);
+    return PYTHON_KEYWORDS.includes(snake) ? `${snake}_` : snake;
+  };
+
+  const render = (value: Value, level: number): string => {
+    switch (value.type) {
+      case 'literal':
+        if (typeof(value.value) === 'string') {
+          return stringLiteral(value.value, '"');
+        }
+        if (typeof(value.value) === 'boolean') {
+          return value.value ? 'True' : 'False';
+        }
+        return value.value === null ? 'None' : JSON.stringify(value.value);
+      case 'union':
+        return `k8s.${value.typeName}.${value.fromNumber ? 'from_number' : 'from_string'}(${render({ type: 'literal', value: value.value }, level)})`;
+      case 'list':
+        if (value.items.length === 0) {
+          return '[]';
+        }
+        return `[\n${value.items.map(v => `${indent(level + 1)}${render(v, level + 1)},\n`).join('')}${indent(level)}]`;
+      case 'struct':
+      case 'map': {
+        // structs are passed as dicts keyed by the python member names
+        const entries = value.type === 'struct' ? value.members.map(([k, v]) => [pythonName(k), v] as [string, Value]) : value.entries;
+        if (entries.length === 0) {
+          return '{}';
+        }
+        return `{\n${entries.map(([k, v]) => `${indent(level + 1)}${stringLiteral(k, '"')}: ${render(v, level + 1)},\n`).join('')}${indent(level)}}`;
+      }
+    }
+  };
+
+  const args = (entries: Array<[string, Value]>, level: number) =>
+    entries.map(([k, v]) => `${indent(level)}${k}=${render(v, level)},\n`).join('');
+
+  const variables = new Set<string>();
+  let constructs = false;
+  let apiObjects = false;
+  const code = new Array<string>();
+
+  for (const statement of statements) {
+    const id = stringLiteral(statement.id, '"');
+
+    if (isConstruct(statement)) {
+      constructs = true;
+      const props = statement.props.type === 'struct' ? statement.props.members.map(([k, v]) => [pythonName(k), v] as [string, Value]) : [];
+      code.push(`${indent(2)}k8s.${statement.className}(\n${indent(3)}self,\n${indent(3)}${id},\n${args(props, 3)}${indent(2)})\n`);
+    } else {
+      apiObjects = true;
+      const props: Array<[string, Value]> = [
+        ['api_version', { type: 'literal', value: statement.apiVersion }],
+        ['kind', { type: 'literal', value: statement.kind }],
+        ...statement.metadata ? [['metadata', statement.metadata] as [string, Value]] : [],
+      ];
+
+      if (statement.fields.length === 0) {
+        code.push(`${indent(2)}ApiObject(\n${indent(3)}self,\n${indent(3)}${id},\n${args(props, 3)}${indent(2)})\n`);
+        continue;
+      }
+
+      // fields other than metadata are not part of the ApiObject props in python
+      const base = pythonName(statement.id.replace(/[^A-Za-z0-9]+/g, '_')).replace(/^(?=[0-9])/, '_');
+      let variable = base;
+      for (let i = 2; variables.has(variable); i++) {
+        variable = `${base}_${i}`;
+      }
+      variables.add(variable);
+
+      const patches = statement.fields.map(([k, v]) => `${indent(2)}${variable}.add_json_patch(JsonPatch.add(${stringLiteral(`/${k.replace(/~/g, '~0').replace(/\//g, '~1')}`, '"')}, ${render(v, 2)}))\n`);
+      code.push(`${indent(2)}${variable} = ApiObject(\n${indent(3)}self,\n${indent(3)}${id},\n${args(props, 3)}${indent(2)})\n${patches.join('')}`);
+    }
+  }
+
+  const imports = new Array<string>();
+  if (apiObjects) {
+    imports.push('\nfrom cdk8s import ApiObject, JsonPatch');
+  }
+  if (constructs) {
+    imports.push('\nfrom imports import k8s');
+  }
+
+  return { imports: imports.join(''), resources: code.join('\n') };
+}
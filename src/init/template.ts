@@ -0,0 +1,118 @@
+import { promises } from 'fs';
+import * as path from 'path';
+import * as fs from 'fs-extra';
+import * as yaml from 'yaml';
+import { Language } from '../import/base';
+import { cloneGitRepository, matchGitRepository } from '../import/git';
+import { downloadBuffer, shell } from '../util';
+
+const ARCHIVE_EXTENSIONS = ['.tgz', '.tar.gz', '.tar'];
+
+/**
+ * The file every template must contain. Its `language` determines the language
+ * of the project.
+ */
+export const TEMPLATE_CONFIG_FILE = 'cdk8s.yaml';
+
+/**
+ * A project template that was fetched from a URL.
+ */
+export interface RemoteTemplate {
+  /**
+   * The directory of the template files, including `.hooks.sscaff.js`.
+   */
+  readonly dir: string;
+
+  /**
+   * The `language` of the `cdk8s.yaml` of the template.
+   */
+  readonly language: Language;
+}
+
+/**
+ * Fetches a project template into `workdir`. The URL is a Git repository
+ * (`git+https://domain/REPOSITORY.git[//SUBDIR][?ref=REF]`) or an archive
+ * (`.tgz`, `.tar.gz` or `.tar`). If an archive only contains a single
+ * directory (e.g. the archives of GitHub releases), it is the template.
+ */
+export async function fetchTemplate(url: string, workdir: string): Promise<RemoteTemplate> {
+  const repo = matchGitRepository(url);
+  if (repo) {
+    const dir = await cloneGitRepository(repo, workdir);
+    await fs.remove(path.join(workdir, '.git'));
+    return validateTemplate(url, dir);
+  }
+
+  const file = url.split(/[?#]/)[0];
+  const extension = ARCHIVE_EXTENSIONS.find(ext => file.endsWith(ext));
+  if (!extension) {
+    throw new Error(`Unsupported template ${url}. Expected a Git repository (git+https://...) or an archive (${ARCHIVE_EXTENSIONS.join(', ')})`);
+  }
+
+  const archive = path.join(workdir, `template${extension}`);
+  await fs.writeFile(archive, await downloadBuffer(url));
+
+  // the files of the template must not be written outside of its directory
+  const entries = (await shell('tar', ['-tf', archive])).split('\n').filter(e => e.trim());
+  const unsafe = entries.find(e => path.isAbsolute(e) || e.split(/[\\/]/).includes('..'));
+  if (unsafe) {
+    throw new Error(`Invalid template ${url}: "${unsafe}" is outside of the template`);
+  }
+
+  const dir = path.join(workdir, 'template');
+  await fs.mkdirp(dir);
+  await shell('tar', ['-xf', archive, '-C', dir]);
+  await fs.remove(archive);
+
+  const files = await fs.readdir(dir);
+  if (files.length === 1 && (await fs.stat(path.join(dir, files[0]))).isDirectory()) {
+    return validateTemplate(url, path.join(dir, files[0]));
+  }
+
+  return validateTemplate(url, dir);
+}
+
+/**
+ * Checks that a directory is a project template: it contains a `cdk8s.yaml`
+ * with a supported `language` and no symbolic links.
+ */
+export async function validateTemplate(url: string, dir: string): Promise<RemoteTemplate> {
+  const invalid = (reason: string) => new Error(`Invalid template ${url}: ${reason}`);
+
+  const configFile = path.join(dir, TEMPLATE_CONFIG_FILE);
+  if (!await fs.pathExists(configFile)) {
+    throw invalid(`missing ${TEMPLATE_CONFIG_FILE}`);
+  }
+
+  let config;
+  try {
+    config = yaml.parse(await fs.readFile(configFile, 'utf-8'));
+  } catch (e) {
+    throw invalid(`${TEMPLATE_CONFIG_FILE} is not valid YAML: ${e}`);
+  }
+
+  const languages: string[] = Object.values(Language);
+  if (!languages.includes(config?.language)) {
+    throw invalid(`"language" of ${TEMPLATE_CONFIG_FILE} must be one of ${languages.join(', ')} (got "${config?.language}")`);
+  }
+
+  const links = async (d: string): Promise<string[]> => {
+    const result = new Array<string>();
+    for (const entry of await promises.readdir(d, { withFileTypes: true })) {
+      const entryPath = path.join(d, entry.name);
+      if (entry.isSymbolicLink()) {
+        result.push(path.relative(dir, entryPath));
+      } else if (entry.isDirectory()) {
+        result.push(...await links(entryPath));
+      }
+    }
+    return result;
+  };
+
+  const symlinks = await links(dir);
+  if (symlinks.length > 0) {
+    throw invalid(`symbolic links are not supported (${symlinks.join(', ')})`);
+  }
+
+  return { dir, language: config.language };
+}
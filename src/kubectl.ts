@@ -0,0 +1,95 @@
+import { DEFAULT_NAMESPACE } from './synth/namespaces';
+import { shell } from './util';
+
+/**
+ * Options for running kubectl.
+ */
+export interface KubectlOptions {
+  /**
+   * The kubeconfig context to use.
+   *
+   * @default - the current context
+   */
+  readonly context?: string;
+}
+
+/**
+ * Runs kubectl and returns its output.
+ */
+export async function kubectl(args: string[], options: KubectlOptions = { }): Promise<string> {
+  const contextArgs = options.context ? ['--context', options.context] : [];
+  try {
+    return await shell('kubectl', [...contextArgs, ...args]);
+  } catch (e) {
+    throw new Error(`Unable to run kubectl (make sure it is installed and configured): ${e}`);
+  }
+}
+
+/**
+ * Returns the names of the contexts in the kubeconfig.
+ */
+export async function listContexts(): Promise<string[]> {
+  const output = await kubectl(['config', 'get-contexts', '-o', 'name']);
+  return output.split('\n').map(line => line.trim()).filter(line => line);
+}
+
+/**
+ * Returns the namespace of the kubeconfig context, which objects without a
+ * namespace are created in.
+ */
+export async function getContextNamespace(options: KubectlOptions = { }): Promise<string> {
+  const output = await kubectl(['config', 'view', '--minify', '-o', 'jsonpath={..namespace}'], options);
+  return output.trim() || DEFAULT_NAMESPACE;
+}
+
+/**
+ * Returns the Kubernetes version of the cluster (e.g. "1.24.3").
+ */
+export async function getServerVersion(options: KubectlOptions = { }): Promise<string> {
+  const output = JSON.parse(await kubectl(['version', '-o', 'json'], options));
+  const gitVersion: string | undefined = output.serverVersion?.gitVersion;
+  const match = gitVersion ? /^v?(\d+\.\d+\.\d+)/.exec(gitVersion) : null;
+  if (!match) {
+    throw new Error(`Unable to determine the version of the cluster (got "${gitVersion}")`);
+  }
+
+  return match[1];
+}
+
+/**
+ * Returns the kubectl resource type of an API object (e.g. "Deployment.v1.apps").
+ */
+export function resourceType(apiVersion: string, kind: string) {
+  const [group, version] = apiVersion.includes('/') ? apiVersion.split('/') : ['', apiVersion];
+  return group ? `${kind}.${version}.${group}` : kind;
+}
+
+/**
+ * Fetches an object from the cluster.
+ *
+ * @returns `undefined` if the object does not exist.
+ */
+export async function getLiveObject(resource: any, options: KubectlOptions = { }): Promise<any | undefined> {
+  const args = ['get', resourceType(resource.apiVersion, resource.kind), resource.metadata?.name, '-o', 'json', '--ignore-not-found'];
+  if (resource.metadata?.namespace) {
+    args.push('-n', resource.metadata.namespace);
+  }
+
+  const output = await kubectl(args, options);
+  return output.trim() ? JSON.parse(output) : undefined;
+}
+
+/**
+ * Lists the objects of a type in the cluster (across all namespaces).
+ *
+ * @param selector an optional label selector
+ */
+export async function listLiveObjects(apiVersion: string, kind: string, selector?: string, options: KubectlOptions = { }): Promise<any[]> {
+  const args = ['get', resourceType(apiVersion, kind), '--all-namespaces', '-o', 'json'];
+  if (selector) {
+    args.push('-l', selector);
+  }
+
+  const output = JSON.parse(await kubectl(args, options));
+  return output.items ?? [];
+}
@@ -0,0 +1,66 @@
+/**
+ * The verbosity of the messages of the CLI.
+ */
+export enum LogLevel {
+  ERROR = 'error',
+  WARN = 'warn',
+  INFO = 'info',
+  DEBUG = 'debug',
+}
+
+/**
+ * Receives the messages that pass the log level.
+ */
+export type LogSink = (level: LogLevel, message: string) => void;
+
+// in order of verbosity
+const LEVELS = [LogLevel.ERROR, LogLevel.WARN, LogLevel.INFO, LogLevel.DEBUG];
+
+// stdout is reserved for the results of commands (e.g. `synth --stdout`)
+const STDERR_SINK: LogSink = (_level, message) => console.error(message);
+
+let currentLevel = LogLevel.INFO;
+let currentSink = STDERR_SINK;
+
+/**
+ * Sets the most verbose level of messages that are logged.
+ */
+export function setLogLevel(level: LogLevel) {
+  if (!LEVELS.includes(level)) {
+    throw new Error(`Invalid log level "${level}". Supported levels are ${LEVELS.join(', ')}`);
+  }
+
+  currentLevel = level;
+}
+
+export function getLogLevel(): LogLevel {
+  return currentLevel;
+}
+
+/**
+ * Replaces where messages are written to (STDERR by default).
+ *
+ * @returns the previous sink
+ */
+export function setLogSink(sink: LogSink): LogSink {
+  const previous = currentSink;
+  currentSink = sink;
+  return previous;
+}
+
+function log(level: LogLevel, message: string) {
+  if (LEVELS.indexOf(level) <= LEVELS.indexOf(currentLevel)) {
+    currentSink(level, message);
+  }
+}
+
+/**
+ * Logs the human-facing progress of commands. Use `console.log` (STDOUT) only
+ * for the results of a command.
+ */
+export const logger = {
+  error: (message: string) => log(LogLevel.ERROR, message),
+  warn: (message: string) => log(LogLevel.WARN, `warning: ${message}`),
+  info: (message: string) => log(LogLevel.INFO, message),
+  debug: (message: string) => log(LogLevel.DEBUG, message),
+};
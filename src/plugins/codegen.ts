@@ -0,0 +1,84 @@
+import * as path from 'path';
+import * as fs from 'fs-extra';
+import { requirePlugin } from './modules';
+
+/**
+ * A function that post-processes a file generated by `cdk8s import`.
+ *
+ * @param file the path of the generated file, relative to the project directory
+ * @param contents the generated contents
+ * @returns the contents to write
+ */
+export type CodegenHook = (file: string, contents: string) => string | Promise<string>;
+
+// generated files that are not source code (e.g. the jsii assemblies bundled
+// with python and go packages)
+const BINARY_EXTENSIONS = ['.tgz'];
+
+/**
+ * Loads the hooks listed under `codegenHooks` in cdk8s.yaml. Each module must
+ * export a `transform` function (or a default export). Relative paths are
+ * resolved from the project directory.
+ */
+export function loadCodegenHooks(modules: string[]): CodegenHook[] {
+  return modules.map(loadCodegenHook);
+}
+
+/**
+ * Runs the hooks, in order, against all files in the given paths (files or
+ * directories) and writes back the transformed contents.
+ */
+export async function runCodegenHooks(hooks: CodegenHook[], paths: string[]) {
+  if (hooks.length === 0) {
+    return;
+  }
+
+  // the paths can be nested (e.g. the packages of python modules)
+  const files = new Set((await Promise.all(paths.map(listFiles))).flat());
+  for (const file of files) {
+    if (BINARY_EXTENSIONS.includes(path.extname(file))) {
+      continue;
+    }
+
+    const relativePath = path.relative(process.cwd(), file);
+    const original = await fs.readFile(file, 'utf-8');
+
+    let contents = original;
+    for (const hook of hooks) {
+      contents = await hook(relativePath, contents);
+      if (typeof(contents) !== 'string') {
+        throw new Error(`Codegen hook returned ${typeof(contents)} instead of a string for ${relativePath}`);
+      }
+    }
+
+    if (contents !== original) {
+      await fs.writeFile(file, contents);
+    }
+  }
+}
+
+function loadCodegenHook(module: string): CodegenHook {
+  const mod = requirePlugin(module);
+  const transform = typeof mod === 'function' ? mod : (mod.transform ?? mod.default);
+  if (typeof transform !== 'function') {
+    throw new Error(`Codegen hook "${module}" must export a "transform" function`);
+  }
+
+  return transform;
+}
+
+async function listFiles(p: string): Promise<string[]> {
+  if (!await fs.pathExists(p)) {
+    return [];
+  }
+
+  if (!(await fs.stat(p)).isDirectory()) {
+    return [p];
+  }
+
+  const files = new Array<string>();
+  for (const entry of (await fs.readdir(p)).sort()) {
+    files.push(...await listFiles(path.join(p, entry)));
+  }
+  return files;
+}
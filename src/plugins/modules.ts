@@ -0,0 +1,10 @@
+/**
+ * Loads a plugin module. Relative paths and package names are resolved from
+ * the project directory.
+ */
+export function requirePlugin(module: string): any {
+  const modulePath = require.resolve(module, { paths: [process.cwd()] });
+
+  // eslint-disable-next-line @typescript-eslint/no-require-imports
+  return require(modulePath);
+}
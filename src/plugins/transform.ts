@@ -0,0 +1,102 @@
+import * as path from 'path';
+import * as fs from 'fs-extra';
+import { logger } from '../logger';
+import { resourceKey } from '../synth/diff';
+import { Manifest } from '../synth/manifests';
+import { requirePlugin } from './modules';
+
+// the modules of a plugin directory that are loaded
+const PLUGIN_EXTENSIONS = ['.js', '.cjs'];
+
+/**
+ * A function that transforms the synthesized resources of all charts before
+ * they are written (e.g. to inject labels or rewrite image registries).
+ *
+ * @param resources all synthesized resources, in the order of their manifests
+ * @returns the resources to write, or nothing to write `resources` (which can
+ * be modified in place)
+ */
+export type SynthTransform = (resources: any[]) => any[] | void | Promise<any[] | void>;
+
+/**
+ * A synth transform loaded from a plugin directory.
+ */
+export interface SynthPlugin {
+  /**
+   * The file name of the module.
+   */
+  readonly name: string;
+  readonly transform: SynthTransform;
+}
+
+/**
+ * Loads the synth transforms of a plugin directory (`--plugin-dir` or
+ * `pluginDir` in cdk8s.yaml) in the order of their file names. Each module
+ * exports a `transform` function (or a default export). Other modules (e.g.
+ * helpers of the plugins) are ignored.
+ */
+export async function loadSynthPlugins(dir: string): Promise<SynthPlugin[]> {
+  if (!await fs.pathExists(dir)) {
+    throw new Error(`Plugin directory ${dir} does not exist`);
+  }
+
+  const plugins = new Array<SynthPlugin>();
+  for (const name of (await fs.readdir(dir)).sort()) {
+    if (!PLUGIN_EXTENSIONS.includes(path.extname(name))) {
+      continue;
+    }
+
+    const mod = requirePlugin(path.resolve(dir, name));
+    const transform = typeof mod === 'function' ? mod : (mod.transform ?? mod.default);
+    if (typeof transform !== 'function') {
+      logger.debug(`Ignoring ${name} in ${dir}: it does not export a "transform" function`);
+      continue;
+    }
+
+    plugins.push({ name, transform });
+  }
+
+  return plugins;
+}
+
+/**
+ * Runs the plugins, in order, over the resources of all manifests. Resources
+ * stay in their manifest (matched by object or by apiVersion, kind,
+ * namespace and name). New resources are added to the manifest of the
+ * resource before them, or to the first manifest.
+ *
+ * @returns the transformed manifests
+ */
+export async function runSynthPlugins(plugins: SynthPlugin[], manifests: Manifest[]): Promise<Manifest[]> {
+  if (plugins.length === 0 || manifests.length === 0) {
+    return manifests;
+  }
+
+  const objects = new Map<any, number>();
+  const keys = new Map<string, number>();
+  manifests.forEach((manifest, i) => {
+    for (const resource of manifest.resources) {
+      objects.set(resource, i);
+      keys.set(resourceKey(resource), i);
+    }
+  });
+
+  let resources = manifests.flatMap(m => m.resources);
+  for (const plugin of plugins) {
+    const result = await plugin.transform(resources);
+    if (result !== undefined && !Array.isArray(result)) {
+      throw new Error(`Synth plugin "${plugin.name}" returned ${typeof(result)} instead of an array of resources`);
+    }
+    resources = result ?? resources;
+  }
+
+  const contents = manifests.map(() => new Array<any>());
+  let previous = 0;
+  for (const resource of resources) {
+    const index = objects.get(resource) ?? keys.get(resourceKey(resource)) ?? previous;
+    contents[index].push(resource);
+    previous = index;
+  }
+
+  return manifests.map((manifest, i) => ({ ...manifest, resources: contents[i] }));
+}
@@ -0,0 +1,171 @@
+import { ValidationConfig } from '../config';
+import { logger } from '../logger';
+import { requirePlugin } from './modules';
+
+/**
+ * The severity of a validation violation, from lowest to highest.
+ */
+export enum ValidationSeverity {
+  LOW = 'low',
+  MEDIUM = 'medium',
+  HIGH = 'high',
+  CRITICAL = 'critical',
+}
+
+const SEVERITIES = [
+  ValidationSeverity.LOW,
+  ValidationSeverity.MEDIUM,
+  ValidationSeverity.HIGH,
+  ValidationSeverity.CRITICAL,
+];
+
+/**
+ * A resource that violates a validation rule.
+ */
+export interface ValidationViolatingResource {
+  /**
+   * The manifest file the resource was synthesized to.
+   */
+  readonly manifestPath: string;
+
+  /**
+   * The name of the resource.
+   *
+   * @default - the violation applies to the entire manifest
+   */
+  readonly resourceName?: string;
+}
+
+/**
+ * A violation reported by a validation plugin.
+ */
+export interface ValidationViolation {
+  readonly ruleName: string;
+  readonly message: string;
+  readonly severity: ValidationSeverity;
+
+  /**
+   * How to fix the violation.
+   *
+   * @default - no fix is suggested
+   */
+  readonly fix?: string;
+
+  /**
+   * @default []
+   */
+  readonly resources?: ValidationViolatingResource[];
+}
+
+/**
+ * Passed to validation plugins.
+ */
+export interface ValidationContext {
+  /**
+   * Paths of all synthesized manifests.
+   */
+  readonly manifests: string[];
+
+  /**
+   * Reports a violation.
+   */
+  report(violation: ValidationViolation): void;
+}
+
+/**
+ * The interface validation plugins must implement. Plugin classes are
+ * constructed with the `properties` from their `cdk8s.yaml` entry.
+ */
+export interface Validation {
+  validate(context: ValidationContext): void | Promise<void>;
+}
+
+/**
+ * The violations reported by a single plugin.
+ */
+export interface ValidationReport {
+  readonly plugin: string;
+  readonly violations: ValidationViolation[];
+}
+
+/**
+ * Runs all configured validation plugins against the given manifests.
+ */
+export async function validateManifests(configs: ValidationConfig[], manifests: string[]): Promise<ValidationReport[]> {
+  const reports = new Array<ValidationReport>();
+
+  for (const config of configs) {
+    const plugin = loadValidation(config);
+    const violations = new Array<ValidationViolation>();
+
+    await plugin.validate({
+      manifests,
+      report: violation => {
+        if (!SEVERITIES.includes(violation.severity)) {
+          throw new Error(`Validation plugin "${pluginName(config)}" reported an invalid severity "${violation.severity}" (expected one of: ${SEVERITIES.join(', ')})`);
+        }
+        violations.push(violation);
+      },
+    });
+
+    reports.push({ plugin: pluginName(config), violations });
+  }
+
+  return reports;
+}
+
+/**
+ * Returns all violations with a severity at or above the threshold.
+ */
+export function findViolations(reports: ValidationReport[], threshold: ValidationSeverity): ValidationViolation[] {
+  const minimum = SEVERITIES.indexOf(threshold);
+  if (minimum === -1) {
+    throw new Error(`Invalid validation severity "${threshold}" (expected one of: ${SEVERITIES.join(', ')})`);
+  }
+
+  return reports
+    .flatMap(report => report.violations)
+    .filter(violation => SEVERITIES.indexOf(violation.severity) >= minimum);
+}
+
+/**
+ * Prints validation reports in a human readable format.
+ */
+export function printValidationReports(reports: ValidationReport[]) {
+  for (const report of reports) {
+    if (report.violations.length === 0) {
+      logger.info(`Validation plugin "${report.plugin}": no violations`);
+      continue;
+    }
+
+    logger.info(`Validation plugin "${report.plugin}": ${report.violations.length} violation(s)`);
+    for (const violation of report.violations) {
+      logger.info(`  [${violation.severity}] ${violation.ruleName}: ${violation.message}`);
+      for (const resource of violation.resources ?? []) {
+        logger.info(`    - ${resource.manifestPath}${resource.resourceName ? ` (${resource.resourceName})` : ''}`);
+      }
+      if (violation.fix) {
+        logger.info(`    fix: ${violation.fix}`);
+      }
+    }
+  }
+}
+
+function loadValidation(config: ValidationConfig): Validation {
+  const mod = requirePlugin(config.package);
+  const ctor = mod[config.class];
+  if (typeof ctor !== 'function') {
+    throw new Error(`Unable to find class "${config.class}" in validation plugin "${config.package}"`);
+  }
+
+  const plugin = new ctor(config.properties ?? {});
+  if (typeof plugin.validate !== 'function') {
+    throw new Error(`Validation plugin "${pluginName(config)}" does not implement a "validate" method`);
+  }
+
+  return plugin;
+}
+
+function pluginName(config: ValidationConfig) {
+  return `${config.package}#${config.class}`;
+}
@@ -31,9 +31,10 @@ export class SafeReviver {
   // the string we use as the stripped value
   public static readonly STRIPPED_VALUE = '__stripped_by_cdk8s__';
 
-  // remove characters from descriptions that might terminate the comment
+  // remove characters from descriptions that might terminate the comment.
+  // CRD deprecation warnings are also emitted as comments.
   public static readonly DESCRIPTION_SANITIZER: Sanitizer = (path: string[], value: string) => {
-    if (path.length > 0 && path[path.length - 1] === 'description') {
+    if (path.length > 0 && ['description', 'deprecationWarning'].includes(path[path.length - 1])) {
       return { applied: true, sanitized: value.replace(/\*\//g, '_/') };
     } else {
       return { applied: false };
@@ -0,0 +1,59 @@
+import * as path from 'path';
+import * as fs from 'fs-extra';
+import { isCompressed, Manifest } from './manifests';
+
+export enum ApplyMode {
+  CLIENT_SIDE = 'client-side',
+  SERVER_SIDE = 'server-side',
+}
+
+export const DEFAULT_FIELD_MANAGER = 'cdk8s';
+
+export const APPLY_SCRIPT = 'apply.sh';
+
+const LAST_APPLIED_ANNOTATION = 'kubectl.kubernetes.io/last-applied-configuration';
+
+/**
+ * Prepares manifests for server-side apply by removing the annotation
+ * used by client-side apply, which is redundant (and can result in ownership
+ * conflicts) when fields are tracked by the API server.
+ */
+export function prepareServerSideApply(manifests: Manifest[]) {
+  for (const manifest of manifests) {
+    for (const resource of manifest.resources) {
+      const annotations = resource.metadata?.annotations;
+      if (!annotations || !(LAST_APPLIED_ANNOTATION in annotations)) {
+        continue;
+      }
+
+      delete annotations[LAST_APPLIED_ANNOTATION];
+      if (Object.keys(annotations).length === 0) {
+        delete resource.metadata.annotations;
+      }
+    }
+  }
+}
+
+/**
+ * Writes a script that applies all manifests with server-side apply, in the
+ * order they were synthesized.
+ */
+export async function writeApplyScript(outdir: string, manifests: Manifest[], fieldManager: string) {
+  if (!/^[A-Za-z0-9._:-]+$/.test(fieldManager)) {
+    throw new Error(`Invalid field manager "${fieldManager}". Must only contain alphanumeric characters, ".", "_", ":" and "-".`);
+  }
+
+  const lines = [
+    '#!/bin/sh',
+    '# generated by cdk8s',
+    'set -e',
+    'cd "$(dirname "$0")"',
+    ...manifests.map(m => isCompressed(m.file)
+      ? `gunzip -c "${m.file}" | kubectl apply --server-side --field-manager=${fieldManager} -f -`
+      : `kubectl apply --server-side --field-manager=${fieldManager} -f "${m.file}"`),
+  ];
+
+  const script = path.join(outdir, APPLY_SCRIPT);
+  await fs.writeFile(script, lines.join('\n') + '\n');
+  await fs.chmod(script, 0o755);
+}
@@ -0,0 +1,35 @@
+import * as path from 'path';
+import * as fs from 'fs-extra';
+import { getFiles } from '../util';
+
+/**
+ * Returns the id of the chart a file or directory in the output directory
+ * was synthesized from (e.g. "0000-my-chart.k8s.yaml" => "my-chart"). Files
+ * in other output formats (e.g. "my-chart.k8s.json.gz") are supported as well.
+ */
+export function chartId(entry: string): string {
+  return path.basename(entry).replace(/\.k8s\.(yaml|json|jsonl)(\.gz)?$/, '').replace(/^\d{4}-/, '');
+}
+
+/**
+ * Removes the output of all charts except `id` from the output directory.
+ *
+ * @returns the remaining manifest files
+ */
+export async function filterChart(outdir: string, id: string): Promise<string[]> {
+  const entries = await fs.readdir(outdir);
+  const ids = entries.map(chartId);
+
+  if (!ids.includes(id)) {
+    const available = Array.from(new Set(ids)).sort().map(i => `"${i}"`).join(', ');
+    throw new Error(`No chart with id "${id}" found. Available charts: ${available || '<none>'}`);
+  }
+
+  for (const entry of entries) {
+    if (chartId(entry) !== id) {
+      await fs.remove(path.join(outdir, entry));
+    }
+  }
+
+  return getFiles(outdir);
+}
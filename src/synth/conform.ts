@@ -0,0 +1,275 @@
+import * as os from 'os';
+import * as path from 'path';
+import Ajv from 'ajv';
+import * as fs from 'fs-extra';
+import { ImportCustomResourceDefinition, safeParseCrdFiles } from '../import/crd';
+import { DEFAULT_API_VERSION } from '../import/k8s';
+import { logger } from '../logger';
+import { download, DownloadError, isGlob } from '../util';
+import { resourceKey } from './diff';
+import { Manifest } from './manifests';
+
+/**
+ * Where the JSON schemas of the Kubernetes API are cached by default.
+ */
+export const DEFAULT_SCHEMA_CACHE_DIR = path.join(os.homedir(), '.cdk8s', 'schema-cache');
+
+/**
+ * The JSON schemas of the Kubernetes API used by kubeconform.
+ */
+export const KUBERNETES_SCHEMA_LOCATION = 'https://raw.githubusercontent.com/yannh/kubernetes-json-schema/master';
+
+export interface ConformOptions {
+  /**
+   * The Kubernetes version of the schemas of built-in resources (e.g.
+   * "1.22.0").
+   */
+  readonly kubernetesVersion: string;
+
+  /**
+   * The directory downloaded schemas are cached in.
+   *
+   * @default DEFAULT_SCHEMA_CACHE_DIR
+   */
+  readonly cacheDir?: string;
+
+  /**
+   * Custom resource definitions in addition to the synthesized ones. Their
+   * schemas are used to validate the custom resources.
+   *
+   * @default []
+   */
+  readonly crds?: any[];
+}
+
+/**
+ * A synthesized resource that does not conform to its schema.
+ */
+export interface ConformViolation {
+  /**
+   * The manifest of the resource, relative to the output directory.
+   */
+  readonly file: string;
+
+  /**
+   * "apiVersion/kind/namespace/name" of the resource.
+   */
+  readonly resource: string;
+  readonly errors: string[];
+}
+
+export interface ConformReport {
+  /**
+   * The number of resources that conform to their schema.
+   */
+  readonly valid: number;
+  readonly violations: ConformViolation[];
+
+  /**
+   * The custom resources without a schema, which are not validated.
+   */
+  readonly skipped: string[];
+}
+
+/**
+ * Returns the Kubernetes version of a project: the version of the "k8s"
+ * import (e.g. "k8s@1.25.0") in `imports` of cdk8s.yaml.
+ */
+export function kubernetesVersion(imports: string[] = []): string {
+  for (const spec of imports) {
+    const match = /^(?:.+:=)?k8s(?:@(.+))?$/.exec(spec);
+    if (match) {
+      return match[1] ?? DEFAULT_API_VERSION;
+    }
+  }
+
+  return DEFAULT_API_VERSION;
+}
+
+/**
+ * Returns the CRDs imported from local files, directories and glob patterns
+ * by `imports` of cdk8s.yaml. Other sources (e.g. URLs) are not fetched.
+ */
+export async function importedCrds(imports: string[] = []): Promise<any[]> {
+  const crds = new Array<any>();
+
+  for (const spec of imports) {
+    const source = spec.split(':=').pop()!;
+    if (!fs.existsSync(source) && !isGlob(source)) {
+      continue;
+    }
+
+    try {
+      crds.push(...safeParseCrdFiles(await ImportCustomResourceDefinition.loadFiles(source)));
+    } catch (e) {
+      logger.warn(`Cannot use the custom resource definitions of "${source}" to validate custom resources: ${e}`);
+    }
+  }
+
+  return crds;
+}
+
+/**
+ * Returns the name of the schema file of a built-in resource, like
+ * kubeconform (e.g. "deployment-apps-v1.json").
+ */
+export function schemaFile(apiVersion: string, kind: string): string {
+  const [group, version] = apiVersion.includes('/') ? apiVersion.split('/') : ['', apiVersion];
+  const suffix = group ? `-${group.split('.')[0]}-${version}` : `-${version}`;
+  return `${kind.toLowerCase()}${suffix}.json`;
+}
+
+// custom resources are defined by groups that are not part of kubernetes
+function isBuiltIn(apiVersion: string) {
+  const group = apiVersion.includes('/') ? apiVersion.split('/')[0] : '';
+  return !group.includes('.') || group.endsWith('.k8s.io');
+}
+
+/**
+ * Returns the schemas of the custom resources defined by CRDs by
+ * "apiVersion/kind".
+ */
+function crdSchemas(crds: any[]): Map<string, any> {
+  const schemas = new Map<string, any>();
+
+  for (const crd of crds) {
+    const spec = crd.spec ?? { };
+    const versions = spec.versions ?? (spec.version ? [{ name: spec.version }] : []);
+    for (const version of versions) {
+      const schema = version.schema?.openAPIV3Schema ?? spec.validation?.openAPIV3Schema;
+      if (schema) {
+        schemas.set(`${spec.group}/${version.name}/${spec.names?.kind}`, schema);
+      }
+    }
+  }
+
+  return schemas;
+}
+
+/**
+ * Downloads the schema of a built-in resource, or reads it from the cache.
+ *
+ * @returns the schema or `undefined` if there is no schema of the resource
+ * (i.e. the download is not found)
+ * @throws if the schema cannot be downloaded for another reason (e.g. a
+ * network error), since the resource cannot be validated
+ */
+async function fetchSchema(apiVersion: string, kind: string, options: ConformOptions): Promise<any | undefined> {
+  const dir = `v${options.kubernetesVersion}-standalone-strict`;
+  const file = schemaFile(apiVersion, kind);
+  const cached = path.join(options.cacheDir ?? DEFAULT_SCHEMA_CACHE_DIR, dir, file);
+
+  if (await fs.pathExists(cached)) {
+    return fs.readJson(cached);
+  }
+
+  const url = `${KUBERNETES_SCHEMA_LOCATION}/${dir}/${file}`;
+  let content;
+  try {
+    content = await download(url);
+  } catch (e) {
+    if (e instanceof DownloadError && e.statusCode === 404) {
+      logger.debug(`No schema of ${apiVersion}/${kind}: ${e}`);
+      return undefined;
+    }
+
+    throw new Error(`Cannot download the schema of ${apiVersion}/${kind} from ${url}: ${e instanceof Error ? e.message : e}`);
+  }
+
+  await fs.outputFile(cached, content);
+  return JSON.parse(content);
+}
+
+/**
+ * Validates the synthesized resources against the JSON schemas of the
+ * Kubernetes version, like kubeconform. Custom resources are validated against
+ * the schema of their CRD, if it is synthesized or in `options.crds`, and
+ * skipped otherwise.
+ */
+export async function conformManifests(manifests: Manifest[], options: ConformOptions): Promise<ConformReport> {
+  const resources = manifests.flatMap(m => m.resources);
+  const crds = crdSchemas([
+    ...options.crds ?? [],
+    ...resources.filter(r => r.apiVersion?.startsWith('apiextensions.k8s.io/') && r.kind === 'CustomResourceDefinition'),
+  ]);
+
+  // the schemas use keywords and formats of the Kubernetes API (e.g.
+  // "x-kubernetes-int-or-string" or "int32") that ajv does not know
+  const ajv = new Ajv({ strict: false, allErrors: true, validateFormats: false, validateSchema: false });
+  const validators = new Map<string, ((resource: any) => string[]) | undefined>();
+
+  const validator = async (apiVersion: string, kind: string) => {
+    const key = `${apiVersion}/${kind}`;
+    if (validators.has(key)) {
+      return validators.get(key);
+    }
+
+    const schema = crds.get(key) ?? (isBuiltIn(apiVersion) ? await fetchSchema(apiVersion, kind, options) : undefined);
+    if (!schema) {
+      validators.set(key, undefined);
+      return undefined;
+    }
+
+    // the meta schema of kubernetes schemas is not known to ajv
+    const validate = ajv.compile({ ...schema, $schema: undefined });
+    const fn = (resource: any) => {
+      validate(resource);
+      return (validate.errors ?? []).map(e => `${e.instancePath || '/'} ${e.message}`);
+    };
+    validators.set(key, fn);
+    return fn;
+  };
+
+  const violations = new Array<ConformViolation>();
+  const skipped = new Array<string>();
+  let valid = 0;
+
+  for (const manifest of manifests) {
+    for (const resource of manifest.resources) {
+      const key = resourceKey(resource);
+
+      if (!resource.apiVersion || !resource.kind) {
+        violations.push({ file: manifest.file, resource: key, errors: ['"apiVersion" and "kind" are required'] });
+        continue;
+      }
+
+      const validate = await validator(resource.apiVersion, resource.kind);
+      if (!validate) {
+        if (!isBuiltIn(resource.apiVersion)) {
+          skipped.push(key);
+          continue;
+        }
+
+        violations.push({ file: manifest.file, resource: key, errors: [`there is no schema of ${resource.apiVersion}/${resource.kind} in Kubernetes ${options.kubernetesVersion}`] });
+        continue;
+      }
+
+      const errors = validate(resource);
+      if (errors.length > 0) {
+        violations.push({ file: manifest.file, resource: key, errors });
+      } else {
+        valid++;
+      }
+    }
+  }
+
+  return { valid, violations, skipped };
+}
+
+/**
+ * Prints the resources that do not conform to their schema.
+ */
+export function printConformReport(report: ConformReport) {
+  for (const violation of report.violations) {
+    logger.error(`${violation.file}: ${violation.resource} is invalid:`);
+    for (const error of violation.errors) {
+      logger.error(`  - ${error}`);
+    }
+  }
+
+  for (const resource of report.skipped) {
+    logger.warn(`${resource} was not validated (no schema of its custom resource definition)`);
+  }
+
+  logger.info(`Conform: ${report.valid} valid, ${report.violations.length} invalid, ${report.skipped.length} skipped`);
+}
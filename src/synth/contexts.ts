@@ -0,0 +1,34 @@
+import { listContexts } from '../kubectl';
+
+/**
+ * The environment variable with the logical name of the selected context.
+ */
+export const CONTEXT_ENV = 'CDK8S_CONTEXT';
+
+/**
+ * The environment variable with the kubeconfig context of the selected context.
+ */
+export const KUBE_CONTEXT_ENV = 'CDK8S_KUBE_CONTEXT';
+
+/**
+ * Resolves a context defined in the "contexts" section of cdk8s.yaml and
+ * verifies that it exists in the kubeconfig.
+ *
+ * @returns the environment variables that expose the context to the app
+ */
+export async function resolveContext(name: string, contexts: Record<string, string>): Promise<Record<string, string>> {
+  const kubeContext = contexts[name];
+  if (!kubeContext) {
+    const available = Object.keys(contexts).sort().map(c => `"${c}"`).join(', ');
+    throw new Error(`Context "${name}" is not defined in the "contexts" section of cdk8s.yaml. Available contexts: ${available || '<none>'}`);
+  }
+
+  if (!(await listContexts()).includes(kubeContext)) {
+    throw new Error(`Context "${name}" refers to kubeconfig context "${kubeContext}", which does not exist in the kubeconfig`);
+  }
+
+  return {
+    [CONTEXT_ENV]: name,
+    [KUBE_CONTEXT_ENV]: kubeContext,
+  };
+}
@@ -0,0 +1,92 @@
+/**
+ * A difference between a desired and a live value.
+ */
+export interface Difference {
+  /**
+   * The path of the value (e.g. "spec.replicas").
+   */
+  readonly path: string;
+  readonly desired: any;
+  readonly live: any;
+}
+
+/**
+ * Returns a key that uniquely identifies a resource:
+ * "apiVersion/kind/namespace/name".
+ */
+export function resourceKey(resource: any) {
+  const namespace = resource.metadata?.namespace ?? '';
+  return `${resource.apiVersion}/${resource.kind}/${namespace}/${resource.metadata?.name ?? ''}`;
+}
+
+/**
+ * Returns the live resources that are not defined by the desired resources
+ * (i.e. that would be pruned). Desired resources without a namespace match
+ * cluster-scoped resources and the resources of `namespace` only.
+ *
+ * @param namespace the namespace that resources without a namespace are
+ * created in (e.g. the namespace of the kubeconfig context)
+ */
+export function findDeletions(desired: any[], live: any[], namespace: string): any[] {
+  const keys = new Set<string>();
+  for (const resource of desired) {
+    keys.add(resourceKey(resource));
+    if (!resource.metadata?.namespace) {
+      keys.add(resourceKey({ ...resource, metadata: { ...resource.metadata, namespace } }));
+    }
+  }
+
+  return live.filter(resource => !keys.has(resourceKey(resource)));
+}
+
+/**
+ * Diffs a desired resource against its live version. Only values defined in
+ * the desired resource are compared, since the cluster populates additional
+ * fields (e.g. "status" or defaults).
+ */
+export function diffResource(desired: any, live: any): Difference[] {
+  return diffValues(normalizeSecret(desired), live, '');
+}
+
+/**
+ * The API server merges the "stringData" of a Secret into its "data" (base64
+ * encoded) and does not return it, so the desired Secret is compared the same
+ * way.
+ */
+function normalizeSecret(resource: any) {
+  if (resource?.apiVersion !== 'v1' || resource?.kind !== 'Secret' || !isObject(resource.stringData)) {
+    return resource;
+  }
+
+  const { stringData, ...normalized } = resource;
+  const data = { ...normalized.data };
+  for (const [key, value] of Object.entries(stringData)) {
+    data[key] = Buffer.from(String(value)).toString('base64');
+  }
+
+  return { ...normalized, data };
+}
+
+function diffValues(desired: any, live: any, path: string): Difference[] {
+  if (isObject(desired) && isObject(live)) {
+    const differences = new Array<Difference>();
+    for (const [key, value] of Object.entries(desired)) {
+      differences.push(...diffValues(value, live[key], path ? `${path}.${key}` : key));
+    }
+    return differences;
+  }
+
+  if (Array.isArray(desired) && Array.isArray(live) && desired.length === live.length) {
+    return desired.flatMap((value, i) => diffValues(value, live[i], `${path}[${i}]`));
+  }
+
+  if (JSON.stringify(desired) !== JSON.stringify(live)) {
+    return [{ path, desired, live }];
+  }
+
+  return [];
+}
+
+function isObject(value: any) {
+  return typeof(value) === 'object' && value !== null && !Array.isArray(value);
+}
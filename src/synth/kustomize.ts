@@ -0,0 +1,20 @@
+import * as path from 'path';
+import * as fs from 'fs-extra';
+import * as yaml from 'yaml';
+import { Manifest } from './manifests';
+
+export const KUSTOMIZATION_FILE = 'kustomization.yaml';
+
+/**
+ * Writes a `kustomization.yaml` that references all manifests, in the order
+ * they were synthesized (i.e. the order of dependencies between charts).
+ */
+export async function writeKustomization(outdir: string, manifests: Manifest[]) {
+  const kustomization = {
+    apiVersion: 'kustomize.config.k8s.io/v1beta1',
+    kind: 'Kustomization',
+    resources: manifests.map(m => m.file.split(path.sep).join(path.posix.sep)),
+  };
+
+  await fs.writeFile(path.join(outdir, KUSTOMIZATION_FILE), `# generated by cdk8s\n${yaml.stringify(kustomization)}`);
+}
@@ -0,0 +1,71 @@
+import * as path from 'path';
+import * as fs from 'fs-extra';
+import { chartId } from './charts';
+import { Manifest } from './manifests';
+
+/**
+ * The number of resources of a single chart above which synthesis warns.
+ */
+export const DEFAULT_CHART_RESOURCES_WARNING = 1000;
+
+export interface ResourceLimits {
+  /**
+   * Fail if all charts together synthesize more resources.
+   *
+   * @default - no limit
+   */
+  readonly maxResources?: number;
+
+  /**
+   * Warn about charts that synthesize more resources. 0 disables the warning.
+   *
+   * @default DEFAULT_CHART_RESOURCES_WARNING
+   */
+  readonly chartResourcesWarning?: number;
+}
+
+/**
+ * Counts the synthesized resources of each chart.
+ *
+ * @returns the warnings about charts with too many resources
+ * @throws if the total exceeds `maxResources`
+ */
+export function checkResourceLimits(manifests: Manifest[], limits: ResourceLimits): string[] {
+  const charts = new Map<string, number>();
+  let total = 0;
+
+  for (const manifest of manifests) {
+    const chart = chartId(manifest.file.split(/[\\/]/)[0]);
+    charts.set(chart, (charts.get(chart) ?? 0) + manifest.resources.length);
+    total += manifest.resources.length;
+
+    if (limits.maxResources !== undefined && total > limits.maxResources) {
+      throw new Error(`Synthesis produced more than ${limits.maxResources} resources (--max-resources). Exceeded in ${manifest.file}`);
+    }
+  }
+
+  const threshold = limits.chartResourcesWarning ?? DEFAULT_CHART_RESOURCES_WARNING;
+  if (threshold <= 0) {
+    return [];
+  }
+
+  return Array.from(charts.entries())
+    .filter(([, count]) => count > threshold)
+    .map(([chart, count]) => `Chart "${chart}" synthesized ${count} resources (more than ${threshold})`);
+}
+
+/**
+ * Sums the sizes of the written manifests.
+ *
+ * @throws if the total exceeds `maxBytes`
+ */
+export async function checkOutputSize(outdir: string, manifests: Manifest[], maxBytes: number) {
+  let total = 0;
+  for (const manifest of manifests) {
+    total += (await fs.stat(path.join(outdir, manifest.file))).size;
+
+    if (total > maxBytes) {
+      throw new Error(`Synthesis produced more than ${maxBytes} bytes of manifests (--max-output-bytes). Exceeded in ${manifest.file}`);
+    }
+  }
+}
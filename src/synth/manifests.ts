@@ -0,0 +1,242 @@
+import { createHash } from 'crypto';
+import * as path from 'path';
+import { promisify } from 'util';
+import * as zlib from 'zlib';
+import { Yaml } from 'cdk8s';
+import * as fs from 'fs-extra';
+import * as yaml from 'yaml';
+import { getFiles } from '../util';
+
+/**
+ * How synthesized resources are serialized.
+ */
+export enum OutputFormat {
+  /**
+   * A multi-document YAML file per chart.
+   */
+  YAML = 'yaml',
+
+  /**
+   * A JSON array of resources per chart.
+   */
+  JSON = 'json',
+
+  /**
+   * Newline-delimited JSON (one resource per line) per chart.
+   */
+  JSON_STREAM = 'json-stream',
+}
+
+const EXTENSIONS: Record<OutputFormat, string> = {
+  [OutputFormat.YAML]: '.k8s.yaml',
+  [OutputFormat.JSON]: '.k8s.json',
+  [OutputFormat.JSON_STREAM]: '.k8s.jsonl',
+};
+
+// the keys that are kept at the top of a resource when sorting its keys
+const RESOURCE_HEADER_KEYS = ['apiVersion', 'kind', 'metadata'];
+
+// appended to the extension of compressed manifests
+const GZIP_EXTENSION = '.gz';
+
+const gzip = promisify(zlib.gzip);
+const gunzip = promisify(zlib.gunzip);
+
+/**
+ * A synthesized manifest file.
+ */
+export interface Manifest {
+  /**
+   * The path of the manifest, relative to the output directory.
+   */
+  readonly file: string;
+
+  /**
+   * The resources in the manifest, in the order they were synthesized.
+   */
+  readonly resources: any[];
+}
+
+/**
+ * Reads all manifests synthesized into a directory. Compressed manifests
+ * (e.g. "chart.k8s.yaml.gz") are decompressed transparently.
+ */
+export async function readManifests(outdir: string, format: OutputFormat = OutputFormat.YAML): Promise<Manifest[]> {
+  const manifests = new Array<Manifest>();
+  const files = [
+    ...await getFiles(outdir, EXTENSIONS[format]),
+    ...await getFiles(outdir, `${EXTENSIONS[format]}${GZIP_EXTENSION}`),
+  ];
+
+  // file names are ordered by the app, so sort to preserve that order
+  for (const file of files.sort()) {
+    manifests.push({
+      file: path.relative(outdir, file),
+      resources: parseResources(await readManifestFile(file), format),
+    });
+  }
+
+  return manifests;
+}
+
+async function readManifestFile(file: string): Promise<string> {
+  const content = await fs.readFile(file);
+  return (isCompressed(file) ? await gunzip(content) : content).toString('utf-8');
+}
+
+function parseResources(content: string, format: OutputFormat): any[] {
+  switch (format) {
+    case OutputFormat.YAML:
+      return yaml.parseAllDocuments(content).map(doc => doc.toJS()).filter(r => r != null);
+    case OutputFormat.JSON:
+      return JSON.parse(content);
+    case OutputFormat.JSON_STREAM:
+      return content.split('\n').filter(line => line.trim()).map(line => JSON.parse(line));
+    default:
+      throw new Error(`Unsupported output format "${format}"`);
+  }
+}
+
+/**
+ * Writes manifests into a directory, overwriting existing files. Manifests
+ * written in a format other than YAML replace the synthesized YAML file (e.g.
+ * "chart.k8s.yaml" becomes "chart.k8s.json"). Compressed manifests are
+ * written as a gzip stream with a ".gz" extension.
+ *
+ * @returns the manifests with the paths they were written to
+ */
+export async function writeManifests(outdir: string, manifests: Manifest[], format: OutputFormat = OutputFormat.YAML, compress: boolean = false): Promise<Manifest[]> {
+  const written = new Array<Manifest>();
+
+  for (const manifest of manifests) {
+    const file = manifestFile(manifest.file, format, compress);
+    const content = serializeResources(manifest.resources, format);
+    await fs.mkdirp(path.dirname(path.join(outdir, file)));
+    await fs.writeFile(path.join(outdir, file), compress ? await gzip(content) : content);
+
+    if (file !== manifest.file) {
+      await fs.remove(path.join(outdir, manifest.file));
+    }
+
+    written.push({ ...manifest, file });
+  }
+
+  return written;
+}
+
+/**
+ * Removes manifest files from a directory, including the directories that
+ * become empty.
+ */
+export async function removeManifests(outdir: string, manifests: Manifest[]) {
+  for (const manifest of manifests) {
+    await fs.remove(path.join(outdir, manifest.file));
+
+    for (let d = path.dirname(manifest.file); d !== '.' && d !== ''; d = path.dirname(d)) {
+      const abs = path.join(outdir, d);
+      if (!await fs.pathExists(abs) || (await fs.readdir(abs)).length > 0) {
+        break;
+      }
+      await fs.remove(abs);
+    }
+  }
+}
+
+/**
+ * Serializes resources in the given format.
+ */
+export function serializeResources(resources: any[], format: OutputFormat): string {
+  switch (format) {
+    case OutputFormat.YAML:
+      return Yaml.stringify(...resources);
+    case OutputFormat.JSON:
+      return `${JSON.stringify(resources, undefined, 2)}\n`;
+    case OutputFormat.JSON_STREAM:
+      return resources.map(r => `${JSON.stringify(r)}\n`).join('');
+    default:
+      throw new Error(`Unsupported output format "${format}"`);
+  }
+}
+
+/**
+ * Returns a copy of a resource with the keys of all objects sorted, so that it
+ * is serialized the same way regardless of the order in which the app set its
+ * fields. "apiVersion", "kind" and "metadata" stay at the top of the resource.
+ * The order of array items is preserved since it is meaningful for most
+ * fields (e.g. the containers of a pod).
+ */
+export function sortKeys(resource: any): any {
+  const sorted = sortObjectKeys(resource);
+  if (!isObject(sorted)) {
+    return sorted;
+  }
+
+  const result: any = { };
+  for (const key of RESOURCE_HEADER_KEYS.filter(k => k in sorted)) {
+    result[key] = sorted[key];
+  }
+
+  return Object.assign(result, sorted);
+}
+
+/**
+ * The annotation with the hash of the content of a resource (see
+ * `addContentHash`).
+ */
+export const CONTENT_HASH_ANNOTATION = 'cdk8s.io/content-hash';
+
+/**
+ * Returns a copy of a resource annotated with the SHA-256 hash of its content
+ * (see `CONTENT_HASH_ANNOTATION`). The hash is computed over the resource
+ * with sorted keys and without the annotation, so it does not depend on the
+ * order in which the app set the fields and only changes with the content.
+ * Arrays are hashed in order.
+ */
+export function addContentHash(resource: any): any {
+  if (!isObject(resource)) {
+    return resource;
+  }
+
+  const annotations = { ...resource.metadata?.annotations };
+  delete annotations[CONTENT_HASH_ANNOTATION];
+
+  const content = { ...resource, metadata: { ...resource.metadata, annotations: Object.keys(annotations).length > 0 ? annotations : undefined } };
+  const hash = createHash('sha256').update(JSON.stringify(sortObjectKeys(content))).digest('hex');
+
+  return { ...resource, metadata: { ...resource.metadata, annotations: { ...annotations, [CONTENT_HASH_ANNOTATION]: hash } } };
+}
+
+function sortObjectKeys(value: any): any {
+  if (Array.isArray(value)) {
+    return value.map(sortObjectKeys);
+  }
+
+  if (!isObject(value)) {
+    return value;
+  }
+
+  const result: any = { };
+  for (const key of Object.keys(value).sort()) {
+    result[key] = sortObjectKeys(value[key]);
+  }
+
+  return result;
+}
+
+function isObject(value: any): boolean {
+  return typeof value === 'object' && value !== null && !Array.isArray(value);
+}
+
+/**
+ * Returns the path of a synthesized YAML manifest in the given format.
+ */
+export function manifestFile(file: string, format: OutputFormat, compress: boolean = false): string {
+  return file.replace(/\.k8s\.yaml(\.gz)?$/, `${EXTENSIONS[format]}${compress ? GZIP_EXTENSION : ''}`);
+}
+
+/**
+ * Returns true if the manifest file is gzip-compressed.
+ */
+export function isCompressed(file: string): boolean {
+  return file.endsWith(GZIP_EXTENSION);
+}
@@ -0,0 +1,113 @@
+import { Manifest, removeManifests, writeManifests } from './manifests';
+
+/**
+ * The namespace of namespaced resources that do not specify one.
+ */
+export const DEFAULT_NAMESPACE = 'default';
+
+/**
+ * The file of the cluster-scoped resources when splitting by namespace.
+ */
+export const CLUSTER_SCOPED_FILE = 'cluster.k8s.yaml';
+
+// "group/kind" of the built-in cluster-scoped resources
+const CLUSTER_SCOPED_KINDS = [
+  '/Namespace',
+  '/Node',
+  '/PersistentVolume',
+  '/ComponentStatus',
+  'rbac.authorization.k8s.io/ClusterRole',
+  'rbac.authorization.k8s.io/ClusterRoleBinding',
+  'apiextensions.k8s.io/CustomResourceDefinition',
+  'apiregistration.k8s.io/APIService',
+  'admissionregistration.k8s.io/MutatingWebhookConfiguration',
+  'admissionregistration.k8s.io/ValidatingWebhookConfiguration',
+  'admissionregistration.k8s.io/ValidatingAdmissionPolicy',
+  'admissionregistration.k8s.io/ValidatingAdmissionPolicyBinding',
+  'storage.k8s.io/StorageClass',
+  'storage.k8s.io/CSIDriver',
+  'storage.k8s.io/CSINode',
+  'storage.k8s.io/VolumeAttachment',
+  'scheduling.k8s.io/PriorityClass',
+  'networking.k8s.io/IngressClass',
+  'node.k8s.io/RuntimeClass',
+  'policy/PodSecurityPolicy',
+  'certificates.k8s.io/CertificateSigningRequest',
+  'flowcontrol.apiserver.k8s.io/FlowSchema',
+  'flowcontrol.apiserver.k8s.io/PriorityLevelConfiguration',
+];
+
+function groupKind(apiVersion: string | undefined, kind: string | undefined) {
+  const group = apiVersion?.includes('/') ? apiVersion.split('/')[0] : '';
+  return `${group}/${kind}`;
+}
+
+/**
+ * Returns the "group/kind" of the cluster-scoped resources: the built-in ones
+ * and the custom resources of the CRDs with a "Cluster" scope among the
+ * resources.
+ */
+export function clusterScopedKinds(resources: any[]): Set<string> {
+  const kinds = new Set(CLUSTER_SCOPED_KINDS);
+
+  for (const r of resources) {
+    if (groupKind(r.apiVersion, r.kind) === 'apiextensions.k8s.io/CustomResourceDefinition' && r.spec?.scope === 'Cluster') {
+      kinds.add(`${r.spec.group}/${r.spec.names?.kind}`);
+    }
+  }
+
+  return kinds;
+}
+
+/**
+ * Returns the namespace a resource is applied to, or `undefined` if it is
+ * cluster-scoped.
+ */
+export function effectiveNamespace(resource: any, clusterScoped: Set<string>, defaultNamespace: string = DEFAULT_NAMESPACE): string | undefined {
+  if (clusterScoped.has(groupKind(resource.apiVersion, resource.kind))) {
+    return undefined;
+  }
+
+  return resource.metadata?.namespace || defaultNamespace;
+}
+
+/**
+ * Returns the file of the resources of a namespace when splitting by
+ * namespace (e.g. "namespace-prod.k8s.yaml").
+ */
+export function namespaceFile(namespace: string | undefined): string {
+  return namespace ? `namespace-${namespace}.k8s.yaml` : CLUSTER_SCOPED_FILE;
+}
+
+/**
+ * Moves the synthesized resources of all charts into a file per namespace
+ * (see `namespaceFile`). Cluster-scoped resources are written to the first
+ * file, followed by the namespaces in the order they were first used.
+ * Resources keep the order they were synthesized in and are not modified, so
+ * resources without a namespace should be applied with `--namespace`.
+ *
+ * @returns the written manifests
+ */
+export async function writeByNamespace(outdir: string, manifests: Manifest[], defaultNamespace: string = DEFAULT_NAMESPACE): Promise<Manifest[]> {
+  const resources = manifests.flatMap(m => m.resources);
+  const clusterScoped = clusterScopedKinds(resources);
+
+  const files = new Map<string, any[]>([[CLUSTER_SCOPED_FILE, []]]);
+  for (const resource of resources) {
+    const file = namespaceFile(effectiveNamespace(resource, clusterScoped, defaultNamespace));
+    const existing = files.get(file);
+    if (existing) {
+      existing.push(resource);
+    } else {
+      files.set(file, [resource]);
+    }
+  }
+
+  await removeManifests(outdir, manifests);
+
+  const split = Array.from(files.entries())
+    .filter(([, r]) => r.length > 0)
+    .map(([file, r]) => ({ file, resources: r }));
+
+  return writeManifests(outdir, split);
+}
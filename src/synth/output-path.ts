@@ -0,0 +1,123 @@
+import * as path from 'path';
+import { chartId } from './charts';
+import { Manifest, removeManifests, writeManifests } from './manifests';
+
+/**
+ * The variables that can be used in the "outputPath" template.
+ */
+export const OUTPUT_PATH_VARIABLES = ['chartId', 'kind', 'name', 'namespace'];
+
+// the extension is determined by the output format
+const YAML_EXTENSION = /(\.k8s)?\.ya?ml$/;
+
+const VARIABLE = /\{([^{}]*)\}/g;
+
+/**
+ * Checks that an "outputPath" template (e.g. "{chartId}/{kind}-{name}.yaml")
+ * only uses known variables and stays within the output directory.
+ */
+export function validateOutputPath(template: string) {
+  const invalid = (reason: string) => new Error(`Invalid outputPath "${template}": ${reason}`);
+
+  if (!template.trim()) {
+    throw invalid('the template is empty');
+  }
+
+  if (path.isAbsolute(template)) {
+    throw invalid('the path must be relative to the output directory');
+  }
+
+  if (template.split(/[\\/]/).includes('..')) {
+    throw invalid('the path must not leave the output directory');
+  }
+
+  if (template.replace(VARIABLE, '').match(/[{}]/)) {
+    throw invalid('unbalanced "{" or "}"');
+  }
+
+  let match;
+  VARIABLE.lastIndex = 0;
+  while ((match = VARIABLE.exec(template))) {
+    if (!OUTPUT_PATH_VARIABLES.includes(match[1])) {
+      throw invalid(`unknown variable "{${match[1]}}". Available variables: ${OUTPUT_PATH_VARIABLES.map(v => `{${v}}`).join(', ')}`);
+    }
+  }
+
+  if (!template.replace(YAML_EXTENSION, '').split(/[\\/]/).pop()) {
+    throw invalid('the template must end with a file name');
+  }
+}
+
+/**
+ * Renders the "outputPath" template for a resource of a chart. The extension
+ * of the template (e.g. ".yaml") is replaced by ".k8s.yaml" so that the file
+ * is treated as a synthesized manifest. Variables without a value (e.g. the
+ * namespace of cluster-scoped resources) are rendered as empty strings.
+ */
+export function renderOutputPath(template: string, chart: string, resource: any): string {
+  const values: Record<string, string> = {
+    chartId: chart,
+    kind: resource?.kind ?? '',
+    name: resource?.metadata?.name ?? '',
+    namespace: resource?.metadata?.namespace ?? '',
+  };
+
+  const rendered = template
+    .replace(YAML_EXTENSION, '')
+    .replace(VARIABLE, (_, variable) => values[variable].replace(/[\\/]/g, '-'));
+
+  // drop empty segments (e.g. "{namespace}/..." of cluster-scoped resources)
+  const file = rendered.split(/[\\/]/).filter(s => s).join('/');
+  if (!file || /[\\/]$/.test(rendered)) {
+    throw new Error(`outputPath "${template}" renders an empty file name for ${resource?.kind} "${values.name}" of chart "${chart}"`);
+  }
+
+  return `${file}.k8s.yaml`;
+}
+
+/**
+ * Moves the synthesized resources into the files of the "outputPath"
+ * template. Templates without `{name}` group resources (e.g. "{namespace}.yaml"
+ * writes a file per namespace), so resources of the same chart that render to
+ * the same path are written to the same file, in the order they were
+ * synthesized. With `{name}`, each resource is expected to get its own file
+ * and such a collision is an error. All paths are rendered (and checked for
+ * collisions) before any file is written.
+ *
+ * @returns the written manifests
+ */
+export async function writeOutputPath(outdir: string, manifests: Manifest[], template: string): Promise<Manifest[]> {
+  validateOutputPath(template);
+
+  const files = new Map<string, { chart: string; resources: any[] }>();
+  const perResource = template.includes('{name}');
+  const describe = (resource: any) => `${resource?.kind} "${resource?.metadata?.namespace ? `${resource.metadata.namespace}/` : ''}${resource?.metadata?.name ?? ''}"`;
+
+  for (const manifest of manifests) {
+    // charts are synthesized as a file or a directory named after their id
+    const chart = chartId(manifest.file.split(/[\\/]/)[0]);
+
+    for (const resource of manifest.resources) {
+      const file = renderOutputPath(template, chart, resource);
+      const existing = files.get(file);
+
+      if (existing && existing.chart !== chart) {
+        throw new Error(`outputPath "${template}" renders "${file}" for resources of charts "${existing.chart}" and "${chart}". Include "{chartId}" to write each chart to separate files`);
+      }
+
+      if (existing && perResource) {
+        throw new Error(`outputPath "${template}" renders "${file}" for ${describe(existing.resources[0])} and ${describe(resource)} of chart "${chart}". Include "{namespace}" or "{kind}" to write them to separate files`);
+      }
+
+      if (existing) {
+        existing.resources.push(resource);
+      } else {
+        files.set(file, { chart, resources: [resource] });
+      }
+    }
+  }
+
+  await removeManifests(outdir, manifests);
+
+  return writeManifests(outdir, Array.from(files.entries()).map(([file, { resources }]) => ({ file, resources })));
+}
@@ -0,0 +1,289 @@
+import { matchGlob } from '../util';
+import { resourceKey } from './diff';
+import { Manifest } from './manifests';
+
+/**
+ * Selects the resources a patch applies to. All fields are glob patterns and
+ * all specified fields must match.
+ */
+export interface PatchTarget {
+  readonly apiVersion?: string;
+  readonly kind?: string;
+  readonly name?: string;
+  readonly namespace?: string;
+}
+
+/**
+ * A JSON Patch (RFC 6902) operation.
+ */
+export interface JsonPatchOperation {
+  readonly op: 'add' | 'remove' | 'replace' | 'move' | 'copy' | 'test';
+  readonly path: string;
+  readonly value?: any;
+  readonly from?: string;
+}
+
+/**
+ * A patch applied to synthesized resources, configured in the "patches"
+ * section of cdk8s.yaml.
+ */
+export interface PatchConfig {
+  readonly target: PatchTarget;
+
+  /**
+   * JSON Patch operations, applied in order.
+   *
+   * @default - no JSON Patch
+   */
+  readonly jsonPatch?: JsonPatchOperation[];
+
+  /**
+   * A strategic merge patch. Objects are merged recursively, `null` removes a
+   * field and lists of objects are merged by their "name" field (e.g.
+   * containers or env variables). Other lists are replaced.
+   *
+   * @default - no strategic merge patch
+   */
+  readonly strategicMerge?: any;
+
+  /**
+   * Skip resources for which a path of the JSON Patch does not exist instead
+   * of failing.
+   *
+   * @default false
+   */
+  readonly optional?: boolean;
+}
+
+/**
+ * Applies the patches, in order, to the matching resources.
+ *
+ * @returns the number of resources that were patched
+ */
+export function applyPatches(manifests: Manifest[], patches: PatchConfig[]): number {
+  const patched = new Set<any>();
+
+  for (const [i, patch] of patches.entries()) {
+    if (!patch.target) {
+      throw new Error(`Patch ${i} does not have a "target"`);
+    }
+
+    if (!patch.jsonPatch && patch.strategicMerge === undefined) {
+      throw new Error(`Patch ${i} must specify "jsonPatch" or "strategicMerge"`);
+    }
+
+    for (const manifest of manifests) {
+      for (const [j, resource] of manifest.resources.entries()) {
+        if (!matchesTarget(resource, patch.target)) {
+          continue;
+        }
+
+        let result = resource;
+        try {
+          if (patch.jsonPatch) {
+            result = applyJsonPatch(result, patch.jsonPatch);
+          }
+        } catch (e) {
+          if (e instanceof PathNotFoundError && patch.optional) {
+            continue;
+          }
+          throw new Error(`Patch ${i} failed for ${resourceKey(resource)} in ${manifest.file}: ${e instanceof Error ? e.message : e}`);
+        }
+
+        if (patch.strategicMerge !== undefined) {
+          result = strategicMerge(result, patch.strategicMerge);
+        }
+
+        manifest.resources[j] = result;
+        patched.add(result);
+      }
+    }
+  }
+
+  return patched.size;
+}
+
+function matchesTarget(resource: any, target: PatchTarget): boolean {
+  const values: Record<keyof PatchTarget, string> = {
+    apiVersion: resource?.apiVersion ?? '',
+    kind: resource?.kind ?? '',
+    name: resource?.metadata?.name ?? '',
+    namespace: resource?.metadata?.namespace ?? '',
+  };
+
+  return (Object.keys(values) as Array<keyof PatchTarget>)
+    .every(key => target[key] === undefined || matchGlob(target[key]!, values[key]));
+}
+
+class PathNotFoundError extends Error {
+  constructor(path: string) {
+    super(`path "${path}" does not exist`);
+  }
+}
+
+/**
+ * Applies JSON Patch (RFC 6902) operations to a copy of `doc`.
+ */
+export function applyJsonPatch(doc: any, operations: JsonPatchOperation[]): any {
+  let result = clone(doc);
+
+  for (const operation of operations) {
+    switch (operation.op) {
+      case 'add':
+        result = setValue(result, operation.path, clone(operation.value), true);
+        break;
+      case 'remove':
+        result = removeValue(result, operation.path);
+        break;
+      case 'replace':
+        getValue(result, operation.path);
+        result = setValue(removeValue(result, operation.path), operation.path, clone(operation.value), true);
+        break;
+      case 'move': {
+        const value = getValue(result, requireFrom(operation));
+        result = setValue(removeValue(result, operation.from!), operation.path, value, true);
+        break;
+      }
+      case 'copy':
+        result = setValue(result, operation.path, clone(getValue(result, requireFrom(operation))), true);
+        break;
+      case 'test':
+        if (JSON.stringify(getValue(result, operation.path)) !== JSON.stringify(operation.value)) {
+          throw new Error(`test failed, "${operation.path}" is not ${JSON.stringify(operation.value)}`);
+        }
+        break;
+      default:
+        throw new Error(`unsupported operation "${(operation as any).op}"`);
+    }
+  }
+
+  return result;
+}
+
+function requireFrom(operation: JsonPatchOperation): string {
+  if (operation.from === undefined) {
+    throw new Error(`"${operation.op}" operation requires "from"`);
+  }
+  return operation.from;
+}
+
+function parsePointer(pointer: string): string[] {
+  if (pointer === '') {
+    return [];
+  }
+
+  if (!pointer.startsWith('/')) {
+    throw new Error(`invalid path "${pointer}", must start with "/"`);
+  }
+
+  return pointer.slice(1).split('/').map(t => t.replace(/~1/g, '/').replace(/~0/g, '~'));
+}
+
+function getValue(doc: any, pointer: string): any {
+  let current = doc;
+  for (const token of parsePointer(pointer)) {
+    if (!isContainer(current) || !(token in current)) {
+      throw new PathNotFoundError(pointer);
+    }
+    current = current[token];
+  }
+  return current;
+}
+
+function setValue(doc: any, pointer: string, value: any, insert: boolean): any {
+  const tokens = parsePointer(pointer);
+  if (tokens.length === 0) {
+    return value;
+  }
+
+  const parent = getValue(doc, `/${tokens.slice(0, -1).map(escape).join('/')}`.replace(/^\/$/, ''));
+  const last = tokens[tokens.length - 1];
+
+  if (Array.isArray(parent)) {
+    const index = last === '-' ? parent.length : Number(last);
+    if (!Number.isInteger(index) || index < 0 || index > parent.length) {
+      throw new PathNotFoundError(pointer);
+    }
+    parent.splice(index, insert ? 0 : 1, value);
+  } else if (isContainer(parent)) {
+    parent[last] = value;
+  } else {
+    throw new PathNotFoundError(pointer);
+  }
+
+  return doc;
+}
+
+function removeValue(doc: any, pointer: string): any {
+  const tokens = parsePointer(pointer);
+  getValue(doc, pointer);
+
+  if (tokens.length === 0) {
+    return undefined;
+  }
+
+  const parent = getValue(doc, `/${tokens.slice(0, -1).map(escape).join('/')}`.replace(/^\/$/, ''));
+  const last = tokens[tokens.length - 1];
+  if (Array.isArray(parent)) {
+    parent.splice(Number(last), 1);
+  } else {
+    delete parent[last];
+  }
+
+  return doc;
+}
+
+function escape(token: string) {
+  return token.replace(/~/g, '~0').replace(/\//g, '~1');
+}
+
+/**
+ * Applies a strategic merge patch to a copy of `doc`.
+ */
+export function strategicMerge(doc: any, patch: any): any {
+  if (!isObject(patch) || !isObject(doc)) {
+    return clone(patch);
+  }
+
+  const result = clone(doc);
+  for (const [key, value] of Object.entries(patch)) {
+    if (value === null) {
+      delete result[key];
+    } else if (Array.isArray(value) && Array.isArray(result[key]) && isNamedList(value) && isNamedList(result[key])) {
+      result[key] = mergeNamedLists(result[key], value);
+    } else {
+      result[key] = strategicMerge(result[key], value);
+    }
+  }
+
+  return result;
+}
+
+function mergeNamedLists(list: any[], patch: any[]): any[] {
+  const result = clone(list);
+  for (const item of patch) {
+    const index = result.findIndex((existing: any) => existing.name === item.name);
+    if (index === -1) {
+      result.push(clone(item));
+    } else {
+      result[index] = strategicMerge(result[index], item);
+    }
+  }
+  return result;
+}
+
+function isNamedList(list: any[]) {
+  return list.every(item => isObject(item) && typeof(item.name) === 'string');
+}
+
+function isObject(value: any) {
+  return typeof(value) === 'object' && value !== null && !Array.isArray(value);
+}
+
+function isContainer(value: any) {
+  return typeof(value) === 'object' && value !== null;
+}
+
+function clone(value: any) {
+  return value === undefined ? undefined : JSON.parse(JSON.stringify(value));
+}
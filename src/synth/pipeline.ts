@@ -0,0 +1,80 @@
+import { runSynthPlugins, SynthPlugin } from '../plugins/transform';
+import { synthApp } from '../util';
+import { ApplyMode, prepareServerSideApply } from './apply';
+import { filterChart } from './charts';
+import { Manifest, readManifests, writeManifests } from './manifests';
+import { applyPatches, PatchConfig } from './patches';
+
+export interface SynthManifestsOptions {
+  /**
+   * Additional environment variables for the app (e.g. of a context).
+   *
+   * @default - no additional variables
+   */
+  readonly env?: Record<string, string>;
+
+  /**
+   * Only keep the manifests of the chart with this construct id.
+   *
+   * @default - all charts
+   */
+  readonly chart?: string;
+
+  /**
+   * The patches of cdk8s.yaml, applied in order.
+   *
+   * @default []
+   */
+  readonly patches?: PatchConfig[];
+
+  /**
+   * The synth plugins, run in order after the patches.
+   *
+   * @default []
+   */
+  readonly plugins?: SynthPlugin[];
+
+  /**
+   * The apply mode the manifests are prepared for.
+   *
+   * @default ApplyMode.CLIENT_SIDE
+   */
+  readonly applyMode?: ApplyMode;
+}
+
+/**
+ * Synthesizes the app into a directory and applies the stages that change the
+ * synthesized resources (the chart filter, patches, plugins and the
+ * preparation for the apply mode), so that `cdk8s synth` and `cdk8s diff`
+ * see the same resources. The manifests are written back to the directory.
+ *
+ * @returns the manifests in the directory, in the order of synthesis
+ */
+export async function synthManifests(command: string, outdir: string, options: SynthManifestsOptions = { }): Promise<Manifest[]> {
+  await synthApp(command, outdir, { env: options.env });
+  if (options.chart) {
+    await filterChart(outdir, options.chart);
+  }
+
+  let manifests = await readManifests(outdir);
+  const patches = options.patches ?? [];
+  const plugins = options.plugins ?? [];
+
+  if (patches.length > 0) {
+    applyPatches(manifests, patches);
+  }
+
+  if (plugins.length > 0) {
+    manifests = await runSynthPlugins(plugins, manifests);
+  }
+
+  if (options.applyMode === ApplyMode.SERVER_SIDE) {
+    prepareServerSideApply(manifests);
+  }
+
+  if (patches.length > 0 || plugins.length > 0 || options.applyMode === ApplyMode.SERVER_SIDE) {
+    await writeManifests(outdir, manifests);
+  }
+
+  return manifests;
+}
@@ -0,0 +1,173 @@
+import * as path from 'path';
+import * as fs from 'fs-extra';
+import { chartId } from './charts';
+import { resourceKey } from './diff';
+import { Manifest } from './manifests';
+
+/**
+ * A synthesized resource.
+ */
+export interface ResourceSummary {
+  readonly apiVersion: string;
+  readonly kind: string;
+  readonly namespace?: string;
+  readonly name?: string;
+
+  /**
+   * The file the resource was written to, relative to the output directory.
+   * Not set when writing to STDOUT.
+   */
+  readonly file?: string;
+}
+
+/**
+ * The resources synthesized by a chart, in the order they were synthesized.
+ */
+export interface ChartSummary {
+  readonly chartId: string;
+  readonly resources: ResourceSummary[];
+}
+
+/**
+ * A machine-readable description of the output of `cdk8s synth`.
+ */
+export interface SynthSummary {
+  readonly charts: ChartSummary[];
+
+  readonly counts: {
+    readonly charts: number;
+    readonly files: number;
+    readonly resources: number;
+
+    /**
+     * The number of resources of each "apiVersion/kind".
+     */
+    readonly kinds: Record<string, number>;
+  };
+
+  /**
+   * Problems of the output that do not fail synthesis (e.g. resources that
+   * are defined more than once).
+   */
+  readonly warnings: string[];
+}
+
+export interface SummarizeOptions {
+  /**
+   * Include the file of each resource.
+   *
+   * @default true
+   */
+  readonly files?: boolean;
+
+  /**
+   * The chart of each resource by "apiVersion/kind/namespace/name" (see
+   * `resourceCharts`), recorded before the resources are moved into other
+   * files (e.g. by "outputPath" or `--split-by-namespace`).
+   *
+   * @default - the chart is derived from the file of the resource
+   */
+  readonly charts?: Map<string, string>;
+
+  /**
+   * Warnings found before the summary is created (e.g. charts with many
+   * resources), listed before the warnings of the summary.
+   *
+   * @default []
+   */
+  readonly warnings?: string[];
+}
+
+// charts are synthesized as a file or a directory named after their id
+function fileChartId(file: string) {
+  return chartId(file.split(/[\\/]/)[0]);
+}
+
+/**
+ * Returns the chart of each resource by "apiVersion/kind/namespace/name",
+ * while the manifests are the files synthesized by the app (i.e. a file or a
+ * directory per chart). Resources that are defined by more than one chart are
+ * attributed to the first.
+ */
+export function resourceCharts(manifests: Manifest[]): Map<string, string> {
+  const charts = new Map<string, string>();
+  for (const manifest of manifests) {
+    for (const resource of manifest.resources) {
+      const key = resourceKey(resource);
+      if (!charts.has(key)) {
+        charts.set(key, fileChartId(manifest.file));
+      }
+    }
+  }
+
+  return charts;
+}
+
+/**
+ * Summarizes the synthesized manifests. Charts are listed in the order they
+ * were synthesized.
+ */
+export function summarizeManifests(manifests: Manifest[], options: SummarizeOptions = { }): SynthSummary {
+  const includeFiles = options.files ?? true;
+  const charts = new Map<string, ResourceSummary[]>();
+  const kinds: Record<string, number> = { };
+  const warnings = [...options.warnings ?? []];
+  const files = new Map<string, string[]>();
+
+  for (const manifest of manifests) {
+    // charts without resources are listed as well
+    if (manifest.resources.length === 0 && !charts.has(fileChartId(manifest.file))) {
+      charts.set(fileChartId(manifest.file), []);
+    }
+
+    for (const resource of manifest.resources) {
+      const key = resourceKey(resource);
+      const chart = options.charts?.get(key) ?? fileChartId(manifest.file);
+      const resources = charts.get(chart) ?? [];
+      charts.set(chart, resources);
+
+      resources.push({
+        apiVersion: resource.apiVersion,
+        kind: resource.kind,
+        namespace: resource.metadata?.namespace,
+        name: resource.metadata?.name,
+        file: includeFiles ? manifest.file : undefined,
+      });
+
+      const kind = `${resource.apiVersion}/${resource.kind}`;
+      kinds[kind] = (kinds[kind] ?? 0) + 1;
+
+      if (!resource.metadata?.name) {
+        warnings.push(`${kind} in ${manifest.file} has no name`);
+        continue;
+      }
+
+      files.set(key, [...files.get(key) ?? [], manifest.file]);
+    }
+  }
+
+  for (const [key, definedIn] of files.entries()) {
+    if (definedIn.length > 1) {
+      warnings.push(`${key} is defined ${definedIn.length} times (in ${Array.from(new Set(definedIn)).join(', ')})`);
+    }
+  }
+
+  return {
+    charts: Array.from(charts.entries()).map(([id, resources]) => ({ chartId: id, resources })),
+    counts: {
+      charts: charts.size,
+      files: manifests.length,
+      resources: manifests.reduce((count, m) => count + m.resources.length, 0),
+      kinds,
+    },
+    warnings,
+  };
+}
+
+/**
+ * Writes the summary as JSON, creating the parent directory if needed.
+ */
+export async function writeSummary(file: string, summary: SynthSummary) {
+  await fs.mkdirp(path.dirname(file));
+  await fs.writeJson(file, summary, { spaces: 2 });
+}
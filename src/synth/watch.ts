@@ -0,0 +1,215 @@
+import * as path from 'path';
+import * as fs from 'fs-extra';
+import { logger } from '../logger';
+import { getFiles, matchGlob } from '../util';
+
+/**
+ * Files that never trigger synthesis, in addition to the output directory.
+ */
+export const DEFAULT_WATCH_EXCLUDE = [
+  '.git/**',
+  'node_modules/**',
+  '**/__pycache__/**',
+  '**/*.d.ts',
+  '**/*.js.map',
+];
+
+export interface WatchOptions {
+  /**
+   * The project directory.
+   */
+  readonly dir: string;
+
+  /**
+   * Glob patterns (relative to `dir`) of the source files to watch.
+   */
+  readonly include: string[];
+
+  /**
+   * Glob patterns (relative to `dir`) of files to ignore.
+   */
+  readonly exclude: string[];
+
+  /**
+   * How long to wait after the last change before synthesizing (ms).
+   *
+   * @default 300
+   */
+  readonly debounce?: number;
+
+  /**
+   * How often to check for changes (ms).
+   *
+   * @default 500
+   */
+  readonly interval?: number;
+}
+
+/**
+ * Maps file paths to a fingerprint of their contents.
+ */
+export type Snapshot = Record<string, string>;
+
+/**
+ * Lists the files in `dir` that match one of the `include` patterns and none
+ * of the `exclude` patterns. Returns paths relative to `dir`.
+ */
+export async function listSourceFiles(dir: string, include: string[], exclude: string[]): Promise<string[]> {
+  const files = new Array<string>();
+
+  const walk = async (subdir: string) => {
+    for (const entry of await fs.readdir(path.join(dir, subdir), { withFileTypes: true })) {
+      const relativePath = path.posix.join(subdir, entry.name);
+
+      if (entry.isDirectory()) {
+        // skip entire directories that are excluded (e.g. node_modules)
+        if (!exclude.some(p => matchGlob(p, `${relativePath}/`))) {
+          await walk(relativePath);
+        }
+        continue;
+      }
+
+      if (include.some(p => matchGlob(p, relativePath)) && !exclude.some(p => matchGlob(p, relativePath))) {
+        files.push(relativePath);
+      }
+    }
+  };
+
+  await walk('');
+  return files.sort();
+}
+
+/**
+ * Takes a snapshot of the modification times of the source files.
+ */
+export async function snapshotSources(options: WatchOptions): Promise<Snapshot> {
+  const snapshot: Snapshot = { };
+  for (const file of await listSourceFiles(options.dir, options.include, options.exclude)) {
+    const stat = await fs.stat(path.join(options.dir, file));
+    snapshot[file] = `${stat.mtimeMs}:${stat.size}`;
+  }
+  return snapshot;
+}
+
+/**
+ * Takes a snapshot of the contents of the synthesized manifests (in any
+ * format, compressed or not).
+ */
+export async function snapshotManifests(outdir: string): Promise<Snapshot> {
+  const snapshot: Snapshot = { };
+  if (!await fs.pathExists(outdir)) {
+    return snapshot;
+  }
+
+  for (const file of await getFiles(outdir, '')) {
+    snapshot[path.relative(outdir, file)] = await fs.readFile(file, 'utf-8');
+  }
+  return snapshot;
+}
+
+export interface SnapshotChanges {
+  readonly added: string[];
+  readonly changed: string[];
+  readonly removed: string[];
+}
+
+/**
+ * Compares two snapshots.
+ */
+export function compareSnapshots(before: Snapshot, after: Snapshot): SnapshotChanges {
+  const added = Object.keys(after).filter(f => !(f in before));
+  const removed = Object.keys(before).filter(f => !(f in after));
+  const changed = Object.keys(after).filter(f => f in before && before[f] !== after[f]);
+  return { added: added.sort(), changed: changed.sort(), removed: removed.sort() };
+}
+
+/**
+ * Returns a one-line summary of the changes.
+ */
+export function formatChanges(changes: SnapshotChanges): string {
+  const all = [
+    ...changes.added.map(f => `+${f}`),
+    ...changes.changed.map(f => `~${f}`),
+    ...changes.removed.map(f => `-${f}`),
+  ];
+
+  return all.length === 0 ? 'no changes' : all.join(' ');
+}
+
+/**
+ * Polls the source files for changes and invokes `onChange` once the files
+ * have stopped changing for `debounce` ms. `onChange` is never invoked
+ * concurrently; changes that happen while it's running are handled once it
+ * completes.
+ *
+ * @returns a function that stops watching
+ */
+export function watchSources(options: WatchOptions, onChange: (changes: SnapshotChanges) => Promise<void>): () => void {
+  const debounce = options.debounce ?? 300;
+  const interval = options.interval ?? 500;
+
+  let last: Snapshot | undefined;
+  let pending: SnapshotChanges | undefined;
+  let pendingSince = 0;
+  let running = false;
+  let stopped = false;
+  let timer: NodeJS.Timeout | undefined;
+
+  const poll = async () => {
+    const current = await snapshotSources(options);
+
+    if (last) {
+      const changes = compareSnapshots(last, current);
+      if (changes.added.length + changes.changed.length + changes.removed.length > 0) {
+        pending = merge(pending, changes);
+        pendingSince = Date.now();
+      }
+    }
+    last = current;
+
+    if (pending && !running && Date.now() - pendingSince >= debounce) {
+      const changes = pending;
+      pending = undefined;
+      running = true;
+      try {
+        await onChange(changes);
+      } finally {
+        running = false;
+      }
+    }
+  };
+
+  const schedule = () => {
+    if (stopped) {
+      return;
+    }
+
+    timer = setTimeout(() => {
+      poll()
+        .catch(e => logger.error(`Error while watching files: ${e}`))
+        .finally(schedule);
+    }, Math.min(interval, debounce));
+  };
+
+  schedule();
+
+  return () => {
+    stopped = true;
+    if (timer) {
+      clearTimeout(timer);
+    }
+  };
+}
+
+function merge(a: SnapshotChanges | undefined, b: SnapshotChanges): SnapshotChanges {
+  if (!a) {
+    return b;
+  }
+
+  const unique = (...lists: string[][]) => Array.from(new Set(lists.flat())).sort();
+  return {
+    added: unique(a.added, b.added),
+    changed: unique(a.changed, b.changed),
+    removed: unique(a.removed, b.removed),
+  };
+}
@@ -5,12 +5,16 @@ import * as https from 'https';
 import * as os from 'os';
 import * as path from 'path';
 import { parse } from 'url';
+import { promisify } from 'util';
 import * as fs from 'fs-extra';
-import * as yaml from 'yaml';
+import { glob, hasMagic } from 'glob';
+import minimatch from 'minimatch';
+import { logger } from './logger';
 import { SafeReviver } from './reviver';
 
 export async function shell(program: string, args: string[] = [], options: SpawnOptions = { }): Promise<string> {
   const command = `"${program} ${args.join(' ')}" at ${path.resolve(options.cwd ?? '.')}`;
+  logger.debug(`Running ${command}`);
   return new Promise((ok, ko) => {
     const child = spawn(program, args, { stdio: ['inherit', 'pipe', 'inherit'], ...options });
     const data = new Array<Buffer>();
@@ -36,32 +40,43 @@ export async function mkdtemp(closure: (dir: string) => Promise<void>) {
   }
 }
 
-export async function synthApp(command: string, outdir: string) {
+export interface SynthAppOptions {
+  /**
+   * Additional environment variables for the app.
+   *
+   * @default - no additional variables
+   */
+  readonly env?: Record<string, string>;
+}
+
+export async function synthApp(command: string, outdir: string, options: SynthAppOptions = { }): Promise<string[]> {
   await shell(command, [], {
     shell: true,
     env: {
       ...process.env,
+      ...options.env,
       CDK8S_OUTDIR: outdir,
     },
   });
 
   if (!await fs.pathExists(outdir)) {
-    console.error(`ERROR: synthesis failed, app expected to create "${outdir}"`);
-    process.exit(1);
+    throw new Error(`synthesis failed, app expected to create "${outdir}"`);
   }
 
   let found = false;
   const yamlFiles = await getFiles(outdir);
   if (yamlFiles?.length) {
     for (const yamlFile of yamlFiles) {
-      console.log(yamlFile);
+      logger.info(yamlFile);
     }
     found = true;
   }
 
   if (!found) {
-    console.error('No manifests synthesized');
+    logger.warn('No manifests synthesized');
   }
+
+  return yamlFiles;
 }
 
 export function safeParseJson(text: string, reviver: SafeReviver): any {
@@ -70,28 +85,92 @@ export function safeParseJson(text: string, reviver: SafeReviver): any {
   return json;
 }
 
-export function safeParseYaml(text: string, reviver: SafeReviver): any[] {
-
-  // parseAllDocuments doesnt accept a reviver
-  // so we first parse normally and than transform
-  // to JS using the reviver.
-  const parsed = yaml.parseAllDocuments(text);
-  const docs = [];
-  for (const doc of parsed) {
-    const json = doc.toJS();
-    reviver.sanitize(json);
-    docs.push(json);
+/**
+ * The default number of times a failed download is retried.
+ */
+export const DEFAULT_DOWNLOAD_RETRIES = 3;
+
+/**
+ * The default maximum delay between retries of a download (ms).
+ */
+export const DEFAULT_DOWNLOAD_RETRY_MAX_DELAY = 10_000;
+
+// the delay before the first retry (ms), doubled for each subsequent retry
+const RETRY_BASE_DELAY = 500;
+
+export interface DownloadOptions {
+  /**
+   * Fail if the request takes longer than this (ms).
+   *
+   * @default - no timeout
+   */
+  readonly timeout?: number;
+
+  /**
+   * Retry this many times on connection errors, timeouts and 5xx responses,
+   * with exponential backoff. 4xx responses are not retried.
+   *
+   * @default 0
+   */
+  readonly retries?: number;
+
+  /**
+   * The maximum delay between retries (ms).
+   *
+   * @default DEFAULT_DOWNLOAD_RETRY_MAX_DELAY
+   */
+  readonly retryMaxDelay?: number;
+}
+
+/**
+ * A download that failed with an HTTP response or an unsupported protocol.
+ */
+export class DownloadError extends Error {
+  /**
+   * @param statusCode the HTTP status code of the response, if any
+   */
+  constructor(message: string, public readonly retryable: boolean, public readonly statusCode?: number) {
+    super(message);
   }
-  return docs;
 }
 
-export async function download(url: string): Promise<string> {
-  let client: typeof http | typeof https;
+export async function download(url: string, options: DownloadOptions = { }): Promise<string> {
+  return (await downloadBuffer(url, options)).toString('utf-8');
+}
+
+/**
+ * Downloads a file without decoding it (e.g. an archive).
+ */
+export async function downloadBuffer(url: string, options: DownloadOptions = { }): Promise<Buffer> {
   const proto = parse(url).protocol;
 
   if (!proto || proto === 'file:') {
-    return fs.readFile(url, 'utf-8');
+    return fs.readFile(url);
+  }
+
+  const retries = options.retries ?? 0;
+  const maxDelay = options.retryMaxDelay ?? DEFAULT_DOWNLOAD_RETRY_MAX_DELAY;
+
+  for (let attempt = 1; ; attempt++) {
+    try {
+      return await downloadOnce(url, options);
+    } catch (e) {
+      const retryable = !(e instanceof DownloadError) || e.retryable;
+      if (!retryable || attempt > retries) {
+        throw attempt > 1 ? new Error(`Failed to download ${url} after ${attempt} attempts: ${e instanceof Error ? e.message : e}`) : e;
+      }
+
+      const delay = Math.min(RETRY_BASE_DELAY * 2 ** (attempt - 1), maxDelay);
+      logger.warn(`Download of ${url} failed (${e instanceof Error ? e.message : e}), retrying in ${delay}ms...`);
+      await new Promise(ok => setTimeout(ok, delay));
+    }
   }
+}
+
+async function downloadOnce(url: string, options: DownloadOptions): Promise<Buffer> {
+  logger.debug(`Downloading ${url}`);
+  let client: typeof http | typeof https;
+  const proto = parse(url).protocol;
 
   switch (proto) {
     case 'https:':
@@ -103,7 +182,7 @@ export async function download(url: string): Promise<string> {
       break;
 
     default:
-      throw new Error(`unsupported protocol ${proto}`);
+      throw new DownloadError(`unsupported protocol ${proto}`, false);
   }
 
   return new Promise((ok, ko) => {
@@ -112,7 +191,7 @@ export async function download(url: string): Promise<string> {
         case 200: {
           const data = new Array<Buffer>();
           res.on('data', chunk => data.push(chunk));
-          res.once('end', () => ok(Buffer.concat(data).toString('utf-8')));
+          res.once('end', () => ok(Buffer.concat(data)));
           res.once('error', ko);
           break;
         }
@@ -120,23 +199,29 @@ export async function download(url: string): Promise<string> {
         case 301:
         case 302: {
           if (res.headers.location) {
-            ok(download(res.headers.location));
+            ok(downloadOnce(res.headers.location, options));
           }
           break;
         }
 
         default: {
-          ko(new Error(`${res.statusMessage}: ${url}`));
+          // only server errors are transient
+          res.resume();
+          ko(new DownloadError(`${res.statusMessage}: ${url}`, (res.statusCode ?? 0) >= 500, res.statusCode));
         }
       }
     });
 
+    if (options.timeout) {
+      req.setTimeout(options.timeout, () => req.destroy(new Error(`Timeout after ${options.timeout}ms: ${url}`)));
+    }
+
     req.once('error', ko);
     req.end();
   });
 }
 
-export async function getFiles(filePath: string): Promise<string[]> {
+export async function getFiles(filePath: string, extension = '.k8s.yaml'): Promise<string[]> {
   // Ensure path is valid
   try {
     await promises.access(filePath);
@@ -149,15 +234,68 @@ export async function getFiles(filePath: string): Promise<string[]> {
 
   // Get files within the current directory
   const files = entries
-    .filter(file => (!file.isDirectory() && file.name.endsWith('.k8s.yaml')))
+    .filter(file => (!file.isDirectory() && file.name.endsWith(extension)))
     .map(file => (filePath + '/' + file.name));
 
   // Get sub-folders within the current folder
   const folders = entries.filter(folder => folder.isDirectory());
 
   for (const folder of folders) {
-    files.push(...await getFiles(`${filePath}/${folder.name}`));
+    files.push(...await getFiles(`${filePath}/${folder.name}`, extension));
   }
 
   return files;
-}
\ No newline at end of file
+}
+
+// dotfiles are matched like any other file (e.g. ".env" by "**/*")
+const GLOB_OPTIONS = { dot: true };
+
+/**
+ * Checks if a value matches a glob pattern. "*" and "?" do not match "/",
+ * while "**" matches any number of directories.
+ */
+export function matchGlob(pattern: string, value: string): boolean {
+  return minimatch(value, pattern, GLOB_OPTIONS);
+}
+
+/**
+ * Checks if a local path is a glob pattern.
+ */
+export function isGlob(source: string): boolean {
+  return hasMagic(source, GLOB_OPTIONS);
+}
+
+/**
+ * Returns the files that match a glob pattern (see `matchGlob`), including
+ * "{a,b}" alternatives. Files matched by more than one alternative are only
+ * returned once.
+ *
+ * @returns the absolute paths of the files, sorted
+ */
+export async function globFiles(pattern: string): Promise<string[]> {
+  const files = await promisify(glob)(pattern.replace(/\\/g, '/'), { ...GLOB_OPTIONS, nodir: true, absolute: true });
+  return files.map(file => path.resolve(file)).sort();
+}
+
+/**
+ * Maps the items with an async function, running at most `concurrency` calls
+ * at a time. Results are returned in the order of the items.
+ */
+export async function mapConcurrently<T, R>(items: T[], concurrency: number, fn: (item: T, index: number) => Promise<R>): Promise<R[]> {
+  if (!Number.isInteger(concurrency) || concurrency < 1) {
+    throw new Error(`Concurrency must be a positive integer (got ${concurrency})`);
+  }
+
+  const results = new Array<R>(items.length);
+  let next = 0;
+
+  const worker = async () => {
+    while (next < items.length) {
+      const index = next++;
+      results[index] = await fn(items[index], index);
+    }
+  };
+
+  await Promise.all(Array.from({ length: Math.min(concurrency, items.length) }, worker));
+  return results;
+}
@@ -14,13 +14,29 @@ exports.pre = () => {
 };
 
 exports.post = options => {
-  execSync(`node "${cli}" import k8s -l go`);
+  const { verbose, env } = output(options);
+
+  execSync(`node "${cli}" import k8s -l go`, { env });
 
   // used to generate go.sum file which tracks hashes of all dependencies
   execSync('go mod tidy');
 
-  execSync('go run .');
+  execSync('go run .', { env });
 
-  console.log(readFileSync('./help', 'utf-8'));
+  if (verbose) {
+    console.error(readFileSync('./help', 'utf-8'));
+  }
 };
 
+// progress is written to STDERR, since STDOUT is reserved for results, and
+// only if the log level of "cdk8s init" includes it. The cdk8s commands run
+// by the hook use the same log level.
+function output(ctx) {
+  const logLevel = ctx.log_level || 'info';
+  const verbose = ['info', 'debug'].includes(logLevel);
+  return {
+    verbose,
+    stdio: ['inherit', verbose ? process.stderr : 'ignore', 'inherit'],
+    env: { ...process.env, CDK8S_LOG_LEVEL: logLevel },
+  };
+}
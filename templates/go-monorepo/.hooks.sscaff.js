@@ -0,0 +1,52 @@
+const { execSync } = require('child_process');
+const { chmodSync, readdirSync } = require('fs');
+const { readFileSync } = require('fs');
+const { platform } = require('os');
+const { dirname, join } = require('path');
+
+const cli = require.resolve('../../bin/cdk8s');
+const clibin = dirname(cli);
+
+exports.pre = () => {
+  try {
+    execSync(`${platform() === 'win32' ? 'where' : 'which'} go`);
+  } catch {
+    console.error(`Unable to find "go". Install from https://golang.org/`);
+    process.exit(1);
+  }
+};
+
+exports.post = options => {
+  const { verbose, env } = output(options);
+
+  // used to generate go.sum file which tracks hashes of all dependencies
+  execSync('go mod tidy', { cwd: 'common' });
+
+  for (const chart of readdirSync('charts')) {
+    const cwd = join('charts', chart);
+    execSync(`node "${cli}" import k8s -l go`, { cwd, env });
+    execSync('go mod tidy', { cwd });
+  }
+
+  chmodSync('synth.sh', '700');
+
+  // synth.sh invokes "cdk8s" for every chart
+  execSync('./synth.sh', { env: { ...env, PATH: `${clibin}:${process.env.PATH}` } });
+
+  if (verbose) {
+    console.error(readFileSync('./help', 'utf-8'));
+  }
+};
+
+// progress is written to STDERR, since STDOUT is reserved for results, and
+// only if the log level of "cdk8s init" includes it. The cdk8s commands run
+// by the hook use the same log level.
+function output(ctx) {
+  const logLevel = ctx.log_level || 'info';
+  const verbose = ['info', 'debug'].includes(logLevel);
+  return {
+    verbose,
+    stdio: ['inherit', verbose ? process.stderr : 'ignore', 'inherit'],
+    env: { ...process.env, CDK8S_LOG_LEVEL: logLevel },
+  };
+}
@@ -0,0 +1,13 @@
+module example.com/{{ $base }}/charts/hello
+
+go 1.18
+
+require (
+	example.com/{{ $base }}/common v0.0.0
+	github.com/aws/constructs-go/constructs/v10 v{{ constructs_version }}
+	github.com/aws/jsii-runtime-go v{{ jsii_version }}
+	github.com/cdk8s-team/cdk8s-core-go/cdk8s/v2 v{{ cdk8s_core_version }}
+	github.com/cdk8s-team/cdk8s-plus-go/cdk8splus22/v2 v{{ cdk8s_plus_version }}
+)
+
+replace example.com/{{ $base }}/common => ../../common
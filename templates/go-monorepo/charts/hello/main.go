@@ -0,0 +1,24 @@
+package main
+
+import (
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/cdk8s-team/cdk8s-core-go/cdk8s/v2"
+
+	"example.com/{{ $base }}/common"
+)
+
+func NewHelloChart(scope constructs.Construct, id string) cdk8s.Chart {
+	chart := common.NewPlatformChart(scope, id, &common.PlatformChartProps{
+		Team: "{{ $base }}",
+	})
+
+	// define resources here
+
+	return chart
+}
+
+func main() {
+	app := cdk8s.NewApp(nil)
+	NewHelloChart(app, "hello")
+	app.Synth()
+}
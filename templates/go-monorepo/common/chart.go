@@ -0,0 +1,31 @@
+// Package common contains the constructs and conventions shared by all charts
+// in this repository.
+package common
+
+import (
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdk8s-team/cdk8s-core-go/cdk8s/v2"
+)
+
+type PlatformChartProps struct {
+	cdk8s.ChartProps
+
+	// The team that owns the chart. Added as a label to all resources.
+	Team string
+}
+
+// NewPlatformChart is the base of all charts in this repository.
+func NewPlatformChart(scope constructs.Construct, id string, props *PlatformChartProps) cdk8s.Chart {
+	cprops := props.ChartProps
+
+	labels := map[string]*string{"team": jsii.String(props.Team)}
+	if cprops.Labels != nil {
+		for k, v := range *cprops.Labels {
+			labels[k] = v
+		}
+	}
+	cprops.Labels = &labels
+
+	return cdk8s.NewChart(scope, jsii.String(id), &cprops)
+}
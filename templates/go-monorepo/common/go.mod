@@ -0,0 +1,9 @@
+module example.com/{{ $base }}/common
+
+go 1.18
+
+require (
+	github.com/aws/constructs-go/constructs/v10 v{{ constructs_version }}
+	github.com/aws/jsii-runtime-go v{{ jsii_version }}
+	github.com/cdk8s-team/cdk8s-core-go/cdk8s/v2 v{{ cdk8s_core_version }}
+)
@@ -0,0 +1,12 @@
+#!/bin/sh
+# synthesizes every chart under "charts/" into "dist/<chart>"
+set -e
+cd "$(dirname "$0")"
+root="$(pwd)"
+
+for cdk8s_yaml in charts/*/cdk8s.yaml; do
+  chart_dir="$(dirname "$cdk8s_yaml")"
+  chart="$(basename "$chart_dir")"
+  echo "Synthesizing chart \"$chart\"" >&2
+  (cd "$chart_dir" && cdk8s synth --output "$root/dist/$chart")
+done
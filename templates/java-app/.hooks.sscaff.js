@@ -16,6 +16,8 @@ exports.pre = (variables) => {
 
 exports.post = options => {
   const { mvn_cdk8s, mvn_cdk8s_plus, cdk8s_core_version, cdk8s_plus_version } = options;
+  const { verbose, env } = output(options);
+
   if (!mvn_cdk8s) {
     throw new Error(`missing context "mvn_cdk8s"`);
   }
@@ -35,9 +37,24 @@ exports.post = options => {
   }
 
   execSync(`mvn install`);
-  execSync(`node "${cli}" import k8s -l java`);
+  execSync(`node "${cli}" import k8s -l java`, { env });
   execSync(`mvn compile`);
-  execSync(`mvn exec:java -Dexec.mainClass="com.mycompany.app.Main"`);
+  execSync(`mvn exec:java -Dexec.mainClass="com.mycompany.app.Main"`, { env });
 
-  console.log(readFileSync('./help', 'utf-8'));
+  if (verbose) {
+    console.error(readFileSync('./help', 'utf-8'));
+  }
 };
+
+// progress is written to STDERR, since STDOUT is reserved for results, and
+// only if the log level of "cdk8s init" includes it. The cdk8s commands run
+// by the hook use the same log level.
+function output(ctx) {
+  const logLevel = ctx.log_level || 'info';
+  const verbose = ['info', 'debug'].includes(logLevel);
+  return {
+    verbose,
+    stdio: ['inherit', verbose ? process.stderr : 'ignore', 'inherit'],
+    env: { ...process.env, CDK8S_LOG_LEVEL: logLevel },
+  };
+}
@@ -23,20 +23,36 @@ exports.post = options => {
     throw new Error(`missing context "pypi_cdk8s_plus"`);
   }
 
+  const { verbose, stdio, env } = output(options);
+
   execSync('pipenv lock --clear')
 
   // this installs the libraries in the Pipfile we provide
-  execSync('pipenv install', { stdio: 'inherit' });
+  execSync('pipenv install', { stdio });
 
   // these are more akward to put in the Pipfile since they can be local wheel files
-  execSync(`pipenv install --pre ${pypi_cdk8s}`, { stdio: 'inherit' });
-  execSync(`pipenv install --pre ${pypi_cdk8s_plus}`, { stdio: 'inherit' });
+  execSync(`pipenv install --pre ${pypi_cdk8s}`, { stdio });
+  execSync(`pipenv install --pre ${pypi_cdk8s_plus}`, { stdio });
 
   chmodSync('main.py', '700');
 
-  execSync(`node "${cli}" import k8s -l python`);
-  execSync(`pipenv run python main.py`);
+  execSync(`node "${cli}" import k8s -l python`, { env });
+  execSync(`pipenv run python main.py`, { env });
 
-  console.log(readFileSync('./help', 'utf-8'));
+  if (verbose) {
+    console.error(readFileSync('./help', 'utf-8'));
+  }
 };
 
+// progress is written to STDERR, since STDOUT is reserved for results, and
+// only if the log level of "cdk8s init" includes it. The cdk8s commands run
+// by the hook use the same log level.
+function output(ctx) {
+  const logLevel = ctx.log_level || 'info';
+  const verbose = ['info', 'debug'].includes(logLevel);
+  return {
+    verbose,
+    stdio: ['inherit', verbose ? process.stderr : 'ignore', 'inherit'],
+    env: { ...process.env, CDK8S_LOG_LEVEL: logLevel },
+  };
+}
@@ -1,14 +1,13 @@
 #!/usr/bin/env python
 from constructs import Construct
-from cdk8s import App, Chart
+from cdk8s import App, Chart{{ chart_imports }}
 
 
 class MyChart(Chart):
     def __init__(self, scope: Construct, id: str):
         super().__init__(scope, id)
 
-        # define resources here
-
+{{ chart_resources }}
 
 app = App()
 MyChart(app, "{{ $base }}")
@@ -0,0 +1,67 @@
+const { execSync } = require('child_process');
+const { chmodSync, readdirSync } = require('fs');
+const { readFileSync } = require('fs');
+const { platform } = require('os');
+const { dirname, join } = require('path');
+
+const cli = require.resolve('../../bin/cdk8s');
+const clibin = dirname(cli);
+
+exports.pre = () => {
+  try {
+    execSync(`${platform() === 'win32' ? 'where' : 'which'} pipenv`);
+  } catch {
+    console.error(`Unable to find "pipenv". Install from https://pipenv.kennethreitz.org`);
+    process.exit(1);
+  }
+};
+
+exports.post = options => {
+  const { pypi_cdk8s, pypi_cdk8s_plus } = options;
+  if (!pypi_cdk8s) {
+    throw new Error(`missing context "pypi_cdk8s"`);
+  }
+  if (!pypi_cdk8s_plus) {
+    throw new Error(`missing context "pypi_cdk8s_plus"`);
+  }
+
+  const { verbose, stdio, env } = output(options);
+
+  execSync('pipenv lock --clear')
+
+  // this installs the libraries in the Pipfile we provide (including the
+  // shared "common" package) into a single environment used by all charts
+  execSync('pipenv install', { stdio });
+
+  // these are more akward to put in the Pipfile since they can be local wheel files
+  execSync(`pipenv install --pre ${pypi_cdk8s}`, { stdio });
+  execSync(`pipenv install --pre ${pypi_cdk8s_plus}`, { stdio });
+
+  chmodSync('synth.py', '700');
+
+  for (const chart of readdirSync('charts')) {
+    const cwd = join('charts', chart);
+    chmodSync(join(cwd, 'main.py'), '700');
+    execSync(`node "${cli}" import k8s -l python`, { cwd, env });
+  }
+
+  // synth.py invokes "cdk8s" for every chart
+  execSync('pipenv run python synth.py', { env: { ...env, PATH: `${clibin}:${process.env.PATH}` } });
+
+  if (verbose) {
+    console.error(readFileSync('./help', 'utf-8'));
+  }
+};
+
+// progress is written to STDERR, since STDOUT is reserved for results, and
+// only if the log level of "cdk8s init" includes it. The cdk8s commands run
+// by the hook use the same log level.
+function output(ctx) {
+  const logLevel = ctx.log_level || 'info';
+  const verbose = ['info', 'debug'].includes(logLevel);
+  return {
+    verbose,
+    stdio: ['inherit', verbose ? process.stderr : 'ignore', 'inherit'],
+    env: { ...process.env, CDK8S_LOG_LEVEL: logLevel },
+  };
+}
@@ -0,0 +1,18 @@
+#!/usr/bin/env python
+from constructs import Construct
+from cdk8s import App
+
+from common import PlatformChart
+
+
+class HelloChart(PlatformChart):
+    def __init__(self, scope: Construct, id: str):
+        super().__init__(scope, id, team="{{ $base }}")
+
+        # define resources here
+
+
+app = App()
+HelloChart(app, "hello")
+
+app.synth()
@@ -0,0 +1,15 @@
+from typing import Mapping, Optional
+
+from constructs import Construct
+from cdk8s import Chart
+
+
+class PlatformChart(Chart):
+    """Base class for all charts in this repository.
+
+    Shared constructs and conventions live in the "common" package. The team
+    that owns the chart is added as a label to all resources.
+    """
+
+    def __init__(self, scope: Construct, id: str, team: str, labels: Optional[Mapping[str, str]] = None):
+        super().__init__(scope, id, labels={"team": team, **(labels or {})})
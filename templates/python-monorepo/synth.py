@@ -0,0 +1,16 @@
+#!/usr/bin/env python
+# synthesizes every chart under "charts/" into "dist/<chart>"
+import os
+import subprocess
+import sys
+
+root = os.path.dirname(os.path.abspath(__file__))
+charts_dir = os.path.join(root, "charts")
+
+for chart in sorted(os.listdir(charts_dir)):
+    chart_dir = os.path.join(charts_dir, chart)
+    if not os.path.exists(os.path.join(chart_dir, "cdk8s.yaml")):
+        continue
+
+    print(f'Synthesizing chart "{chart}"', file=sys.stderr)
+    subprocess.run(["cdk8s", "synth", "--output", os.path.join(root, "dist", chart)], cwd=chart_dir, check=True)
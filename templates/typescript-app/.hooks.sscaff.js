@@ -12,35 +12,49 @@ exports.post = ctx => {
 
   if (!npm_cdk8s) { throw new Error(`missing context "npm_cdk8s"`); }
 
-  installDeps([ npm_cdk8s, npm_cdk8s_plus, `constructs@^${constructs_version}` ]);
+  const { verbose, stdio, env } = output(ctx);
+
+  installDeps([ npm_cdk8s, npm_cdk8s_plus, `constructs@^${constructs_version}` ], false, stdio);
   installDeps([
       '@types/node@14',
       '@types/jest@26',
       'jest@26',
       'ts-jest@26',
       'typescript'
-  ], true);
-
-  const env = { ...process.env };
+  ], true, stdio);
 
   // install cdk8s cli if defined
   if (npm_cdk8s_cli) {
-    installDeps([npm_cdk8s_cli], true);
+    installDeps([npm_cdk8s_cli], true, stdio);
   } else {
     env.PATH = `${clibin}:${process.env.PATH}`;
   }
 
   // import k8s objects
-  execSync('npm run import', { stdio: 'inherit', env });
-  execSync('npm run compile', { stdio: 'inherit', env });
-  execSync('npm run test -- -u', { stdio: 'inherit', env });
-  execSync('npm run synth', { stdio: 'inherit', env });
+  execSync('npm run import', { stdio, env });
+  execSync('npm run compile', { stdio, env });
+  execSync('npm run test -- -u', { stdio, env });
+  execSync('npm run synth', { stdio, env });
 
-  console.log(readFileSync('./help', 'utf-8'));
+  if (verbose) {
+    console.error(readFileSync('./help', 'utf-8'));
+  }
 };
 
-function installDeps(deps, isDev) {
+function installDeps(deps, isDev, stdio) {
   const devDep = isDev ? '-D' : '';
-  execSync(`npm install ${devDep} ${deps.join(' ')}`, { stdio: 'inherit' });
+  execSync(`npm install ${devDep} ${deps.join(' ')}`, { stdio });
 }
 
+// progress is written to STDERR, since STDOUT is reserved for results, and
+// only if the log level of "cdk8s init" includes it. The cdk8s commands run
+// by the hook use the same log level.
+function output(ctx) {
+  const logLevel = ctx.log_level || 'info';
+  const verbose = ['info', 'debug'].includes(logLevel);
+  return {
+    verbose,
+    stdio: ['inherit', verbose ? process.stderr : 'ignore', 'inherit'],
+    env: { ...process.env, CDK8S_LOG_LEVEL: logLevel },
+  };
+}
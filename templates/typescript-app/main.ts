@@ -1,12 +1,11 @@
 import { Construct } from 'constructs';
-import { App, Chart, ChartProps } from 'cdk8s';
+import { App, Chart, ChartProps } from 'cdk8s';{{ chart_imports }}
 
 export class MyChart extends Chart {
   constructor(scope: Construct, id: string, props: ChartProps = { }) {
     super(scope, id, props);
 
-    // define resources here
-
+{{ chart_resources }}
   }
 }
 
@@ -0,0 +1,63 @@
+const { execSync } = require('child_process');
+const { readFileSync } = require('fs');
+const { dirname } = require('path');
+
+const clibin = dirname(require.resolve('../../bin/cdk8s'));
+
+const workspaces = ['packages/constructs', 'charts/hello'];
+
+exports.post = ctx => {
+  const npm_cdk8s = ctx.npm_cdk8s;
+  const npm_cdk8s_plus = ctx.npm_cdk8s_plus;
+  const npm_cdk8s_cli = ctx.npm_cdk8s_cli;
+  const constructs_version = ctx.constructs_version;
+
+  if (!npm_cdk8s) { throw new Error(`missing context "npm_cdk8s"`); }
+
+  const { verbose, stdio, env } = output(ctx);
+
+  // runtime dependencies are installed into each workspace, tools into the root
+  installDeps([ npm_cdk8s, npm_cdk8s_plus, `constructs@^${constructs_version}` ], stdio, workspaces);
+  installDeps([
+      '@types/node@14',
+      '@types/jest@26',
+      'jest@26',
+      'ts-jest@26',
+      'typescript'
+  ], stdio);
+
+  // install cdk8s cli if defined
+  if (npm_cdk8s_cli) {
+    installDeps([npm_cdk8s_cli], stdio);
+  } else {
+    env.PATH = `${clibin}:${process.env.PATH}`;
+  }
+
+  // import k8s objects into every chart
+  execSync('npm run import', { stdio, env });
+  execSync('npm run compile', { stdio, env });
+  execSync('npm run test -- -u', { stdio, env });
+  execSync('npm run synth', { stdio, env });
+
+  if (verbose) {
+    console.error(readFileSync('./help', 'utf-8'));
+  }
+};
+
+function installDeps(deps, stdio, workspaces) {
+  const target = workspaces ? workspaces.map(w => `-w ${w}`).join(' ') : '-D';
+  execSync(`npm install ${target} ${deps.join(' ')}`, { stdio });
+}
+
+// progress is written to STDERR, since STDOUT is reserved for results, and
+// only if the log level of "cdk8s init" includes it. The cdk8s commands run
+// by the hook use the same log level.
+function output(ctx) {
+  const logLevel = ctx.log_level || 'info';
+  const verbose = ['info', 'debug'].includes(logLevel);
+  return {
+    verbose,
+    stdio: ['inherit', verbose ? process.stderr : 'ignore', 'inherit'],
+    env: { ...process.env, CDK8S_LOG_LEVEL: logLevel },
+  };
+}
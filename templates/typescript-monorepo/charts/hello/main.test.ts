@@ -0,0 +1,11 @@
+import {HelloChart} from './main';
+import {Testing} from 'cdk8s';
+
+describe('Placeholder', () => {
+  test('Empty', () => {
+    const app = Testing.app();
+    const chart = new HelloChart(app, 'test-chart', { team: 'test' });
+    const results = Testing.synth(chart)
+    expect(results).toMatchSnapshot();
+  });
+});
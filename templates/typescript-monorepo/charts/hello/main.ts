@@ -0,0 +1,16 @@
+import { Construct } from 'constructs';
+import { App } from 'cdk8s';
+import { PlatformChart, PlatformChartProps } from '@{{ $base }}/constructs';
+
+export class HelloChart extends PlatformChart {
+  constructor(scope: Construct, id: string, props: PlatformChartProps) {
+    super(scope, id, props);
+
+    // define resources here
+
+  }
+}
+
+const app = new App();
+new HelloChart(app, 'hello', { team: '{{ $base }}' });
+app.synth();
@@ -0,0 +1,10 @@
+module.exports = {
+    "roots": [
+        "<rootDir>/packages",
+        "<rootDir>/charts"
+    ],
+    testMatch: [ '**/*.test.ts'],
+    "transform": {
+        "^.+\\.tsx?$": "ts-jest"
+    },
+}
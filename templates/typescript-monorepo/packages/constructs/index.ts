@@ -0,0 +1,25 @@
+import { Construct } from 'constructs';
+import { Chart, ChartProps } from 'cdk8s';
+
+export interface PlatformChartProps extends ChartProps {
+  /**
+   * The team that owns the chart. Added as a label to all resources.
+   */
+  readonly team: string;
+}
+
+/**
+ * Base class for all charts in this repository. Shared constructs and
+ * conventions live in this package.
+ */
+export class PlatformChart extends Chart {
+  constructor(scope: Construct, id: string, props: PlatformChartProps) {
+    super(scope, id, {
+      ...props,
+      labels: {
+        team: props.team,
+        ...props.labels,
+      },
+    });
+  }
+}
@@ -0,0 +1,18 @@
+// synthesizes every chart under "charts/" into "dist/<chart>"
+const { execSync } = require('child_process');
+const { existsSync, readdirSync } = require('fs');
+const { join } = require('path');
+
+const root = join(__dirname, '..');
+const chartsDir = join(root, 'charts');
+const cdk8s = join(root, 'node_modules', '.bin', 'cdk8s');
+
+for (const chart of readdirSync(chartsDir)) {
+  const chartDir = join(chartsDir, chart);
+  if (!existsSync(join(chartDir, 'cdk8s.yaml'))) {
+    continue;
+  }
+
+  console.error(`Synthesizing chart "${chart}"`);
+  execSync(`"${cdk8s}" synth --output "${join(root, 'dist', chart)}"`, { cwd: chartDir, stdio: 'inherit' });
+}
@@ -0,0 +1,85 @@
+import { emitGoOptions } from '../../src/import/go-options';
+
+const props = (name: string, properties: any[]) => ({ kind: 'interface', datatype: true, name, properties });
+
+const assembly = {
+  name: 'widgets.example.com',
+  types: {
+    'widgets.example.com.Widget': {
+      kind: 'class',
+      name: 'Widget',
+      initializer: {
+        parameters: [
+          { name: 'scope', type: { fqn: 'constructs.Construct' } },
+          { name: 'id', type: { primitive: 'string' } },
+          { name: 'props', type: { fqn: 'widgets.example.com.WidgetProps' } },
+        ],
+      },
+    },
+    'widgets.example.com.WidgetProps': props('WidgetProps', [
+      { name: 'metadata', optional: true, type: { fqn: 'cdk8s.ApiObjectMetadata' } },
+      { name: 'spec', type: { fqn: 'widgets.example.com.WidgetSpec' } },
+    ]),
+    'widgets.example.com.WidgetSpec': props('WidgetSpec', [
+      { name: 'replicas', optional: true, type: { primitive: 'number' } },
+      { name: 'ports', optional: true, type: { collection: { kind: 'array', elementtype: { fqn: 'widgets.example.com.WidgetSpecPorts' } } } },
+      { name: 'policy', optional: true, type: { fqn: 'widgets.example.com.WidgetSpecPolicy' } },
+      { name: 'metadata', optional: true, type: { collection: { kind: 'map', elementtype: { primitive: 'string' } } } },
+      { name: 'external', optional: true, type: { fqn: 'other.Type' } },
+    ]),
+    'widgets.example.com.WidgetSpecPorts': props('WidgetSpecPorts', []),
+    'widgets.example.com.WidgetSpecPolicy': { kind: 'enum', name: 'WidgetSpecPolicy' },
+  },
+};
+
+test('emits a constructor that applies the options to the props', () => {
+  const source = emitGoOptions(assembly, 'widgets');
+
+  expect(source).toContain('package widgets\n');
+  expect(source).toContain('\t"github.com/aws/constructs-go/constructs/v10"\n\t"github.com/cdk8s-team/cdk8s-core-go/cdk8s/v2"\n');
+  expect(source).toContain('type WidgetOption func(*WidgetProps)\n');
+  expect(source).toContain([
+    'func NewWidgetWithOptions(scope constructs.Construct, id *string, opts ...WidgetOption) Widget {',
+    '\tprops := &WidgetProps{}',
+    '\tfor _, opt := range opts {',
+    '\t\topt(props)',
+    '\t}',
+    '\treturn NewWidget(scope, id, props)',
+    '}',
+  ].join('\n'));
+});
+
+test('emits an option for each field of the props', () => {
+  const source = emitGoOptions(assembly, 'widgets');
+
+  expect(source).toContain('func WidgetWithMetadata(value *cdk8s.ApiObjectMetadata) WidgetOption {');
+  expect(source).toContain('func WidgetWithSpec(value *WidgetSpec) WidgetOption {');
+});
+
+test('flattens the fields of the spec into options', () => {
+  const source = emitGoOptions(assembly, 'widgets');
+
+  // primitives are passed by value
+  expect(source).toContain([
+    'func WidgetWithReplicas(value float64) WidgetOption {',
+    '\treturn func(props *WidgetProps) {',
+    '\t\tif props.Spec == nil {',
+    '\t\t\tprops.Spec = &WidgetSpec{}',
+    '\t\t}',
+    '\t\tprops.Spec.Replicas = &value',
+    '\t}',
+    '}',
+  ].join('\n'));
+  expect(source).toContain('func WidgetWithPorts(value *[]*WidgetSpecPorts) WidgetOption {');
+  expect(source).toContain('func WidgetWithPolicy(value WidgetSpecPolicy) WidgetOption {');
+
+  // conflicts with an option of the props
+  expect(source).toContain('func WidgetWithSpecMetadata(value *map[string]*string) WidgetOption {');
+
+  // types of unknown assemblies cannot be referenced
+  expect(source).not.toContain('WidgetWithExternal');
+});
+
+test('no options without constructs', () => {
+  expect(emitGoOptions({ name: 'empty', types: {} }, 'empty')).toBeUndefined();
+});
@@ -0,0 +1,108 @@
+import * as os from 'os';
+import * as path from 'path';
+import * as fs from 'fs-extra';
+import { mocked } from 'ts-jest/utils';
+import { Language } from '../../src/import/base';
+import { importBatch, readImportManifest } from '../../src/import/batch';
+import { importDispatch } from '../../src/import/dispatch';
+
+jest.mock('../../src/import/dispatch', () => {
+  const mod = jest.requireActual('../../src/import/dispatch');
+  return {
+    ...mod,
+    importDispatch: jest.fn(),
+  };
+});
+
+let workdir: string;
+
+beforeEach(() => {
+  workdir = fs.mkdtempSync(path.join(os.tmpdir(), 'cdk8s-import-batch-'));
+});
+
+afterEach(() => {
+  fs.removeSync(workdir);
+  jest.resetAllMocks();
+});
+
+function writeManifest(content: string) {
+  const file = path.join(workdir, 'imports.yaml');
+  fs.writeFileSync(file, content);
+  return file;
+}
+
+describe('readImportManifest', () => {
+
+  test('entries can be specs or objects', async () => {
+    const file = writeManifest([
+      'imports:',
+      '  - k8s',
+      '  - source: crds/',
+      '    name: acme',
+      '    language: python',
+      '    output: acme',
+      '    includeCrd: [acme.io/*]',
+    ].join('\n'));
+
+    expect(await readImportManifest(file)).toStrictEqual({
+      imports: [
+        { source: 'k8s' },
+        { source: 'crds/', name: 'acme', language: 'python', output: 'acme', includeCrd: ['acme.io/*'] },
+      ],
+    });
+  });
+
+  test.each([
+    ['imports: []', '"imports" must be a non-empty list of sources'],
+    ['imports:\n  - 42', 'imports[0] must be a source or an object with a "source"'],
+    ['imports:\n  - name: acme', 'imports[0].source must be a non-empty string'],
+    ['imports:\n  - source: k8s\n    lang: go', 'imports[0] has unknown keys "lang". Supported keys are source, name, language, output, includeCrd, excludeCrd'],
+    ['imports:\n  - k8s\n  - source: k8s\n    language: rust', 'imports[1].language must be one of typescript, python, dotnet, java, go (got "rust")'],
+    ['imports:\n  - source: k8s\n    excludeCrd: foo', 'imports[0].excludeCrd must be a list of strings'],
+  ])('rejects %j', async (content, reason) => {
+    const file = writeManifest(content);
+    await expect(readImportManifest(file)).rejects.toThrow(`Invalid import manifest ${file}: ${reason}`);
+  });
+
+});
+
+describe('importBatch', () => {
+
+  const options = { outdir: 'imports', targetLanguage: Language.TYPESCRIPT };
+
+  test('each entry overrides the options of the command', async () => {
+    mocked(importDispatch).mockResolvedValue([]);
+
+    await importBatch({
+      imports: [
+        { source: 'k8s' },
+        { source: 'crds/', name: 'acme', language: Language.PYTHON, output: 'acme', excludeCrd: ['acme.io/legacy'] },
+      ],
+    }, { includeCrd: ['*'], retries: 1 }, options);
+
+    expect(importDispatch).toHaveBeenCalledWith([{ source: 'k8s', moduleNamePrefix: undefined }], { includeCrd: ['*'], excludeCrd: undefined, retries: 1 }, options);
+    expect(importDispatch).toHaveBeenCalledWith(
+      [{ source: 'crds/', moduleNamePrefix: 'acme' }],
+      { includeCrd: ['*'], excludeCrd: ['acme.io/legacy'], retries: 1 },
+      { outdir: path.join('imports', 'acme'), targetLanguage: Language.PYTHON },
+    );
+  });
+
+  test('failed imports do not stop the others', async () => {
+    mocked(importDispatch).mockImplementation(async specs => {
+      if (specs[0].source === 'broken.yaml') {
+        throw new Error('not found');
+      }
+      return [path.join('imports', `${specs[0].source}.ts`)];
+    });
+
+    const results = await importBatch({ imports: [{ source: 'a' }, { source: 'broken.yaml' }, { source: 'b' }] }, { }, options, 2);
+
+    expect(results.map(r => [r.entry.source, r.emitted, r.error?.message])).toStrictEqual([
+      ['a', [path.join('imports', 'a.ts')], undefined],
+      ['broken.yaml', [], 'not found'],
+      ['b', [path.join('imports', 'b.ts')], undefined],
+    ]);
+  });
+
+});
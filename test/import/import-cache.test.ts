@@ -0,0 +1,104 @@
+import * as os from 'os';
+import * as path from 'path';
+import * as fs from 'fs-extra';
+import { Language } from '../../src/import/base';
+import { ImportCache } from '../../src/import/cache';
+
+jest.mock('../../src/util', () => {
+  const mod = jest.requireActual('../../src/util');
+  return {
+    ...mod,
+    download: jest.fn(),
+  };
+});
+
+let workdir: string;
+let cwd: string;
+
+beforeEach(() => {
+  cwd = process.cwd();
+  workdir = fs.realpathSync(fs.mkdtempSync(path.join(os.tmpdir(), 'cdk8s-import-cache-')));
+  process.chdir(workdir);
+});
+
+afterEach(() => {
+  process.chdir(cwd);
+  fs.removeSync(workdir);
+});
+
+test('keys depend on the content and the options', () => {
+  const cache = new ImportCache(path.join(workdir, 'cache'));
+  const key = cache.key('content', { targetLanguage: 'typescript' });
+
+  expect(cache.key('content', { targetLanguage: 'typescript' })).toEqual(key);
+  expect(cache.key('other content', { targetLanguage: 'typescript' })).not.toEqual(key);
+  expect(cache.key('content', { targetLanguage: 'python' })).not.toEqual(key);
+});
+
+test('restores the stored files and directories', async () => {
+  const cache = new ImportCache(path.join(workdir, 'cache'));
+  const key = cache.key('content', { targetLanguage: 'python' });
+
+  fs.outputFileSync(path.join(workdir, 'imports', 'k8s.ts'), 'typescript');
+  fs.outputFileSync(path.join(workdir, 'imports', 'io', 'k8s', '__init__.py'), 'python');
+  await cache.store(key, [path.join(workdir, 'imports', 'k8s.ts'), path.join(workdir, 'imports', 'io')]);
+
+  fs.removeSync(path.join(workdir, 'imports'));
+
+  expect(await cache.restore(key)).toEqual([path.join(workdir, 'imports', 'k8s.ts'), path.join(workdir, 'imports', 'io')]);
+  expect(fs.readFileSync(path.join(workdir, 'imports', 'k8s.ts'), 'utf-8')).toEqual('typescript');
+  expect(fs.readFileSync(path.join(workdir, 'imports', 'io', 'k8s', '__init__.py'), 'utf-8')).toEqual('python');
+});
+
+test('returns undefined if there is no cache entry', async () => {
+  const cache = new ImportCache(path.join(workdir, 'cache'));
+  expect(await cache.restore(cache.key('content', { }))).toBeUndefined();
+});
+
+describe('imports with remote references', () => {
+  const crd = {
+    apiVersion: 'apiextensions.k8s.io/v1',
+    kind: 'CustomResourceDefinition',
+    spec: {
+      group: 'foo.bar',
+      names: { kind: 'Widget' },
+      versions: [{
+        name: 'v1',
+        served: true,
+        storage: true,
+        schema: {
+          openAPIV3Schema: {
+            type: 'object',
+            properties: { spec: { type: 'object', properties: { name: { $ref: 'https://example.com/shared.yaml#/definitions/Name' } } } },
+          },
+        },
+      }],
+    },
+  };
+
+  async function importWidget(name: any, resolveRemoteRefs: boolean) {
+    // remote documents are fetched once per run, so each import gets fresh modules
+    jest.resetModules();
+    require('../../src/util').download.mockResolvedValue(JSON.stringify({ definitions: { Name: name } }));
+    const { importDispatch } = require('../../src/import/dispatch');
+
+    fs.writeFileSync(path.join(workdir, 'widget.yaml'), JSON.stringify(crd));
+    await importDispatch([{ source: 'widget.yaml' }], { resolveRemoteRefs }, {
+      targetLanguage: Language.TYPESCRIPT,
+      outdir: 'imports',
+      cache: new ImportCache(path.join(workdir, 'cache')),
+    });
+
+    return fs.readFileSync(path.join(workdir, 'imports', 'foo.bar.ts'), 'utf-8');
+  }
+
+  test('are not restored if the remote documents changed', async () => {
+    expect(await importWidget({ type: 'string' }, true)).toContain('readonly name?: string;');
+    expect(await importWidget({ type: 'integer' }, true)).toContain('readonly name?: number;');
+  });
+
+  test('are not restored without --resolve-remote-refs', async () => {
+    await importWidget({ type: 'string' }, true);
+    await expect(importWidget({ type: 'string' }, false)).rejects.toThrow(/--resolve-remote-refs/);
+  });
+});
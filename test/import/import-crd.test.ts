@@ -3,7 +3,9 @@ import * as path from 'path';
 import * as fs from 'fs-extra';
 import * as yaml from 'yaml';
 import { Language } from '../../src/import/base';
+import { extractConstraints, extractDefaults } from '../../src/import/codegen';
 import { ManifestObjectDefinition, ImportCustomResourceDefinition } from '../../src/import/crd';
+import { parseRenames } from '../../src/import/rename';
 import { testImportMatchSnapshot } from './util';
 
 const fixtures = path.join(__dirname, 'fixtures');
@@ -236,6 +238,39 @@ test('can import a "List" of CRDs (kubectl get crds -o json)', async () => {
 
 });
 
+test('skips non-CRD documents (e.g. rendered helm templates)', async () => {
+
+  const manifest = [
+    {
+      apiVersion: 'v1',
+      kind: 'ConfigMap',
+      metadata: {
+        name: 'config',
+      },
+      data: {
+        'not a valid key': 'value',
+      },
+    },
+    {
+      apiVersion: 'apiextensions.k8s.io/v1beta1',
+      kind: 'CustomResourceDefinition',
+      spec: {
+        group: 'testGroup',
+        version: 'v1',
+        names: {
+          kind: 'testNameKind',
+        },
+      },
+    },
+  ];
+
+  await withTempFixture(manifest, async (fixture) => {
+    const importer = await ImportCustomResourceDefinition.fromSpec({ source: fixture });
+    expect(importer.moduleNames).toEqual(['testGroup']);
+  });
+
+});
+
 describe('classPrefix can be used to add a prefix to all construct class names', () => {
   testImportMatchSnapshot('Foo', () => ImportCustomResourceDefinition.fromSpec({ source: path.join(fixtures, 'multi_object_crd.yaml') }), {
     classNamePrefix: 'Foo',
@@ -465,3 +500,832 @@ test('given a prefix, we can import two crds with the same group id', async () =
 
 
 });
+
+test('singleFile emits all groups into one file and qualifies colliding kinds', async () => {
+
+  const crd = (group: string, kind: string) => ({
+    apiVersion: 'apiextensions.k8s.io/v1beta1',
+    kind: 'CustomResourceDefinition',
+    spec: {
+      version: 'v1',
+      group,
+      names: {
+        kind,
+      },
+      validation: {
+        openAPIV3Schema: {
+          type: 'object',
+          properties: {
+            spec: {
+              type: 'object',
+              properties: {
+                replicas: { type: 'integer' },
+              },
+            },
+          },
+        },
+      },
+    },
+  });
+
+  const manifest = [
+    crd('foo.bar', 'CronTab'),
+    crd('baz.qux', 'CronTab'),
+    crd('baz.qux', 'Backup'),
+  ];
+
+  await withTempFixture(manifest, async (fixture: string, cwd: string) => {
+    const importer = await ImportCustomResourceDefinition.fromSpec({ source: fixture });
+    await importer.import({ targetLanguage: Language.TYPESCRIPT, outdir: cwd, singleFile: 'crds' });
+
+    expect(fs.existsSync(path.join(cwd, 'foo.bar.ts'))).toBeFalsy();
+    expect(fs.existsSync(path.join(cwd, 'baz.qux.ts'))).toBeFalsy();
+
+    const output = fs.readFileSync(path.join(cwd, 'crds.ts'), { encoding: 'utf8' });
+    expect(output.match(/\/\/ generated by cdk8s/g)?.length).toBe(1);
+    expect(output).toContain('export class FooBarCronTab extends ApiObject');
+    expect(output).toContain('export class BazQuxCronTab extends ApiObject');
+    expect(output).toContain('export class Backup extends ApiObject');
+    expect(output).toContain('export interface FooBarCronTabSpec');
+    expect(output).toContain('export interface BazQuxCronTabSpec');
+  });
+});
+
+test('resolves $refs across files when importing a directory', async () => {
+  const tempDir = fs.mkdtempSync(path.join(os.tmpdir(), 'cdk8s-import-test'));
+  try {
+    const crdsDir = path.join(tempDir, 'crds');
+    fs.mkdirpSync(crdsDir);
+
+    fs.writeFileSync(path.join(crdsDir, 'shared.yaml'), yaml.stringify({
+      definitions: {
+        Selector: {
+          type: 'object',
+          properties: {
+            matchLabels: { type: 'object', additionalProperties: { type: 'string' } },
+          },
+        },
+      },
+    }));
+
+    fs.writeFileSync(path.join(crdsDir, 'crd.yaml'), yaml.stringify({
+      apiVersion: 'apiextensions.k8s.io/v1',
+      kind: 'CustomResourceDefinition',
+      spec: {
+        group: 'testGroup',
+        names: { kind: 'testNameKind' },
+        versions: [{
+          name: 'v1',
+          schema: {
+            openAPIV3Schema: {
+              type: 'object',
+              properties: {
+                spec: {
+                  type: 'object',
+                  properties: {
+                    selector: { $ref: 'shared.yaml#/definitions/Selector' },
+                  },
+                },
+              },
+            },
+          },
+        }],
+      },
+    }));
+
+    const importer = await ImportCustomResourceDefinition.fromSpec({ source: crdsDir });
+    await importer.import({ targetLanguage: Language.TYPESCRIPT, outdir: tempDir });
+
+    const output = fs.readFileSync(path.join(tempDir, 'testGroup.ts'), { encoding: 'utf8' });
+    expect(output).toContain('export interface TestNameKindSpecSelector');
+    expect(output).toContain('readonly matchLabels?: { [key: string]: string };');
+  } finally {
+    fs.removeSync(tempDir);
+  }
+});
+
+test('imports the files that match a glob pattern', async () => {
+  const tempDir = fs.mkdtempSync(path.join(os.tmpdir(), 'cdk8s-import-test'));
+  try {
+    const crd = (kind: string, properties: any) => yaml.stringify({
+      apiVersion: 'apiextensions.k8s.io/v1',
+      kind: 'CustomResourceDefinition',
+      spec: {
+        group: 'testGroup',
+        names: { kind },
+        versions: [{ name: 'v1', schema: { openAPIV3Schema: { type: 'object', properties } } }],
+      },
+    });
+
+    fs.mkdirpSync(path.join(tempDir, 'crds', 'nested'));
+    fs.writeFileSync(path.join(tempDir, 'crds', 'foo.crd.yaml'), crd('Foo', { spec: { $ref: 'nested/shared.crd.yaml#/definitions/Spec' } }));
+    fs.writeFileSync(path.join(tempDir, 'crds', 'nested', 'bar.crd.yaml'), crd('Bar', { spec: { type: 'object' } }));
+    fs.writeFileSync(path.join(tempDir, 'crds', 'nested', 'shared.crd.yaml'), yaml.stringify({
+      definitions: { Spec: { type: 'object', properties: { replicas: { type: 'integer' } } } },
+    }));
+    fs.writeFileSync(path.join(tempDir, 'crds', 'ignored.yaml'), crd('Ignored', { }));
+
+    // the files matched by both alternatives are only imported once
+    const importer = await ImportCustomResourceDefinition.fromSpec({ source: `${tempDir}/crds/{**/*.crd.yaml,nested/*}` });
+    await importer.import({ targetLanguage: Language.TYPESCRIPT, outdir: tempDir });
+
+    const output = fs.readFileSync(path.join(tempDir, 'testGroup.ts'), { encoding: 'utf8' });
+    expect(output).toContain('export class Foo ');
+    expect(output).toContain('export class Bar ');
+    expect(output).toContain('readonly replicas?: number;');
+    expect(output).not.toContain('export class Ignored ');
+  } finally {
+    fs.removeSync(tempDir);
+  }
+});
+
+test('fails if a glob pattern does not match any file', async () => {
+  await expect(ImportCustomResourceDefinition.fromSpec({ source: path.join(fixtures, '**', '*.nothing') }))
+    .rejects.toThrow(`No files match ${path.join(fixtures, '**', '*.nothing')}`);
+});
+
+test('a go package name can only be used for a single module', async () => {
+  const crd = (group: string) => ({
+    apiVersion: 'apiextensions.k8s.io/v1beta1',
+    kind: 'CustomResourceDefinition',
+    spec: {
+      version: 'v1',
+      group,
+      names: { kind: 'testNameKind' },
+    },
+  });
+
+  await withTempFixture([crd('foo.bar'), crd('baz.qux')], async (fixture: string, cwd: string) => {
+    const importer = await ImportCustomResourceDefinition.fromSpec({ source: fixture });
+    await expect(() => importer.import({ targetLanguage: Language.GO, outdir: cwd, goModuleName: 'example.com/app/imports', goPackageName: 'crds' }))
+      .rejects.toThrow('A Go package name can only be specified when importing a single module, but found 2 (baz.qux, foo.bar). Use a single file to merge them.');
+  });
+});
+
+describe('include and exclude', () => {
+  const crd = (group: string, kind: string) => ({
+    apiVersion: 'apiextensions.k8s.io/v1beta1',
+    kind: 'CustomResourceDefinition',
+    spec: {
+      version: 'v1',
+      group,
+      names: { kind },
+    },
+  });
+
+  const manifest = [crd('s3.services.k8s.aws', 'Bucket'), crd('ec2.services.k8s.aws', 'Vpc'), crd('ec2.services.k8s.aws', 'Subnet')];
+
+  test('only imports included CRDs', async () => {
+    await withTempFixture(manifest, async (fixture: string, cwd: string) => {
+      const importer = await ImportCustomResourceDefinition.fromSpec({ source: fixture }, { include: ['s3.services.k8s.aws/*'] });
+      expect(importer.moduleNames).toEqual(['s3.services.k8s.aws']);
+      await importer.import({ targetLanguage: Language.TYPESCRIPT, outdir: cwd });
+      expect(fs.readFileSync(path.join(cwd, 's3.services.k8s.aws.ts'), 'utf-8')).toContain('export class Bucket ');
+    });
+  });
+
+  test('skips excluded CRDs', async () => {
+    await withTempFixture(manifest, async (fixture: string, cwd: string) => {
+      const importer = await ImportCustomResourceDefinition.fromSpec({ source: fixture }, { exclude: ['*/subnet'] });
+      await importer.import({ targetLanguage: Language.TYPESCRIPT, outdir: cwd });
+      const output = fs.readFileSync(path.join(cwd, 'ec2.services.k8s.aws.ts'), 'utf-8');
+      expect(output).toContain('export class Vpc ');
+      expect(output).not.toContain('export class Subnet ');
+    });
+  });
+
+  test('fails if an include pattern matches nothing', async () => {
+    await withTempFixture(manifest, async (fixture: string) => {
+      await expect(ImportCustomResourceDefinition.fromSpec({ source: fixture }, { include: ['foo.bar/*'] }))
+        .rejects.toThrow('No custom resource definitions match "foo.bar/*". Available: ec2.services.k8s.aws/Subnet, ec2.services.k8s.aws/Vpc, s3.services.k8s.aws/Bucket');
+    });
+  });
+
+  test('fails if an exclude pattern matches nothing', async () => {
+    await withTempFixture(manifest, async (fixture: string) => {
+      await expect(ImportCustomResourceDefinition.fromSpec({ source: fixture }, { exclude: ['*/bucket', '*/subnets'] }))
+        .rejects.toThrow('No custom resource definitions match "*/subnets". Available: ec2.services.k8s.aws/Subnet, ec2.services.k8s.aws/Vpc, s3.services.k8s.aws/Bucket');
+    });
+  });
+
+  test('fails if all CRDs are excluded', async () => {
+    await withTempFixture(manifest, async (fixture: string) => {
+      await expect(ImportCustomResourceDefinition.fromSpec({ source: fixture }, { exclude: ['*/*'] }))
+        .rejects.toThrow('All custom resource definitions were excluded');
+    });
+  });
+});
+
+test('deprecated and unserved versions are marked as deprecated', async () => {
+  const manifest = {
+    apiVersion: 'apiextensions.k8s.io/v1',
+    kind: 'CustomResourceDefinition',
+    spec: {
+      group: 'foo.bar',
+      names: { kind: 'Widget' },
+      versions: [
+        { name: 'v1', served: true, storage: true },
+        { name: 'v1beta1', served: true, storage: false, deprecated: true, deprecationWarning: 'foo.bar/v1beta1 Widget is deprecated;\n  use foo.bar/v1 Widget' },
+        { name: 'v1alpha1', served: false, storage: false },
+        { name: 'v1alpha2', served: true, storage: false, deprecated: true },
+      ],
+    },
+  };
+
+  await withTempFixture(manifest, async (fixture: string, cwd: string) => {
+    const importer = await ImportCustomResourceDefinition.fromSpec({ source: fixture });
+    await importer.import({ targetLanguage: Language.TYPESCRIPT, outdir: cwd });
+
+    const output = fs.readFileSync(path.join(cwd, 'foo.bar.ts'), 'utf-8');
+    expect(output).toContain([
+      ' * @schema WidgetV1Beta1',
+      ' * @deprecated foo.bar/v1beta1 Widget is deprecated; use foo.bar/v1 Widget Use `Widget` (foo.bar/v1) instead.',
+      ' */',
+      'export class WidgetV1Beta1 extends ApiObject {',
+    ].join('\n'));
+    expect(output).toContain(' * @deprecated foo.bar/v1alpha1 Widget is not served by the API server. Use `Widget` (foo.bar/v1) instead.');
+    expect(output).toContain(' * @deprecated foo.bar/v1alpha2 Widget is deprecated. Use `Widget` (foo.bar/v1) instead.');
+    expect(output).not.toContain('@deprecated foo.bar/v1 ');
+  });
+});
+
+test('free-form fields (x-kubernetes-preserve-unknown-fields) are generated as open maps', async () => {
+  const manifest = {
+    apiVersion: 'apiextensions.k8s.io/v1',
+    kind: 'CustomResourceDefinition',
+    spec: {
+      group: 'foo.bar',
+      names: { kind: 'Widget' },
+      versions: [{
+        name: 'v1',
+        served: true,
+        storage: true,
+        schema: {
+          openAPIV3Schema: {
+            type: 'object',
+            properties: {
+              spec: {
+                type: 'object',
+                properties: {
+                  config: { 'type': 'object', 'x-kubernetes-preserve-unknown-fields': true },
+                },
+              },
+            },
+          },
+        },
+      }],
+    },
+  };
+
+  await withTempFixture(manifest, async (fixture: string, cwd: string) => {
+    const importer = await ImportCustomResourceDefinition.fromSpec({ source: fixture });
+    await importer.import({ targetLanguage: Language.TYPESCRIPT, outdir: cwd });
+
+    const output = fs.readFileSync(path.join(cwd, 'foo.bar.ts'), 'utf-8');
+    expect(output).toContain('readonly config?: { [key: string]: any };');
+  });
+});
+
+test('int-or-string fields (x-kubernetes-int-or-string) are generated as unions', async () => {
+  const manifest = {
+    apiVersion: 'apiextensions.k8s.io/v1',
+    kind: 'CustomResourceDefinition',
+    spec: {
+      group: 'foo.bar',
+      names: { kind: 'Widget' },
+      versions: [{
+        name: 'v1',
+        served: true,
+        storage: true,
+        schema: {
+          openAPIV3Schema: {
+            type: 'object',
+            properties: {
+              spec: {
+                type: 'object',
+                properties: {
+                  port: { 'x-kubernetes-int-or-string': true },
+                  maxUnavailable: { 'type': 'string', 'x-kubernetes-int-or-string': true },
+                },
+              },
+            },
+          },
+        },
+      }],
+    },
+  };
+
+  await withTempFixture(manifest, async (fixture: string, cwd: string) => {
+    const importer = await ImportCustomResourceDefinition.fromSpec({ source: fixture });
+    await importer.import({ targetLanguage: Language.TYPESCRIPT, outdir: cwd });
+
+    const output = fs.readFileSync(path.join(cwd, 'foo.bar.ts'), 'utf-8');
+    expect(output).toContain('readonly port?: WidgetSpecPort;');
+    expect(output).toContain('readonly maxUnavailable?: WidgetSpecMaxUnavailable;');
+    expect(output).toContain('export type WidgetSpecPort = number | string;');
+    expect(output).toContain('export type WidgetSpecMaxUnavailable = number | string;');
+
+    // the value is serialized without coercion
+    expect(output).toContain("'port': obj.port,");
+  });
+});
+
+test('schema constraints are checked by the constructor with emitValidations', async () => {
+  const manifest = {
+    apiVersion: 'apiextensions.k8s.io/v1',
+    kind: 'CustomResourceDefinition',
+    spec: {
+      group: 'foo.bar',
+      names: { kind: 'Widget' },
+      versions: [{
+        name: 'v1',
+        served: true,
+        storage: true,
+        schema: {
+          openAPIV3Schema: {
+            type: 'object',
+            properties: {
+              spec: {
+                type: 'object',
+                required: ['replicas'],
+                properties: {
+                  replicas: { type: 'integer', minimum: 1, maximum: 10 },
+                  name: { type: 'string', pattern: '^[a-z]+$' },
+                  description: { type: 'string' },
+                },
+              },
+            },
+          },
+        },
+      }],
+    },
+  };
+
+  await withTempFixture(manifest, async (fixture: string, cwd: string) => {
+    const importer = await ImportCustomResourceDefinition.fromSpec({ source: fixture });
+    await importer.import({ targetLanguage: Language.TYPESCRIPT, outdir: cwd, emitValidations: true });
+
+    const output = fs.readFileSync(path.join(cwd, 'foo.bar.ts'), 'utf-8');
+    expect(output).toContain('function checkConstraints(value: any, constraints: any, path: string): string[] {');
+    expect(output).toContain('const constraints_Widget: any = {');
+    expect(output).toContain("const violations = checkConstraints(toJson_WidgetProps(props), constraints_Widget, 'Widget');");
+  });
+
+  await withTempFixture(manifest, async (fixture: string, cwd: string) => {
+    const importer = await ImportCustomResourceDefinition.fromSpec({ source: fixture });
+    await importer.import({ targetLanguage: Language.TYPESCRIPT, outdir: cwd });
+
+    const output = fs.readFileSync(path.join(cwd, 'foo.bar.ts'), 'utf-8');
+    expect(output).not.toContain('checkConstraints');
+  });
+});
+
+test('schema defaults are set when rendering with applyDefaults', async () => {
+  const manifest = {
+    apiVersion: 'apiextensions.k8s.io/v1',
+    kind: 'CustomResourceDefinition',
+    spec: {
+      group: 'foo.bar',
+      names: { kind: 'Widget' },
+      versions: [{
+        name: 'v1',
+        served: true,
+        storage: true,
+        schema: {
+          openAPIV3Schema: {
+            type: 'object',
+            properties: {
+              spec: {
+                type: 'object',
+                properties: {
+                  replicas: { type: 'integer', default: 1 },
+                  strategy: { type: 'object', properties: { type: { type: 'string', default: 'Rolling' } } },
+                },
+              },
+            },
+          },
+        },
+      }],
+    },
+  };
+
+  await withTempFixture(manifest, async (fixture: string, cwd: string) => {
+    const importer = await ImportCustomResourceDefinition.fromSpec({ source: fixture });
+    await importer.import({ targetLanguage: Language.TYPESCRIPT, outdir: cwd, applyDefaults: true });
+
+    const output = fs.readFileSync(path.join(cwd, 'foo.bar.ts'), 'utf-8');
+    expect(output).toContain('function applyDefaults(value: any, defaults: any): any {');
+    expect(output).toContain('const defaults_Widget: any = {');
+    expect(output).toContain('...applyDefaults(toJson_WidgetProps(resolved), defaults_Widget),');
+    expect(output).toContain('...applyDefaults(toJson_WidgetProps(props), defaults_Widget),');
+  });
+
+  await withTempFixture(manifest, async (fixture: string, cwd: string) => {
+    const importer = await ImportCustomResourceDefinition.fromSpec({ source: fixture });
+    await importer.import({ targetLanguage: Language.TYPESCRIPT, outdir: cwd });
+
+    const output = fs.readFileSync(path.join(cwd, 'foo.bar.ts'), 'utf-8');
+    expect(output).not.toContain('applyDefaults');
+  });
+});
+
+test('extractDefaults collects the defaults of nested schemas', () => {
+  expect(extractDefaults({
+    type: 'object',
+    properties: {
+      replicas: { type: 'integer', default: 1 },
+      strategy: { type: 'object', default: { type: 'Rolling' }, properties: { maxSurge: { type: 'integer', default: 25 } } },
+      ports: { type: 'array', items: { type: 'object', properties: { protocol: { type: 'string', default: 'TCP' } } } },
+      labels: { type: 'object', additionalProperties: { type: 'string', default: '' } },
+      metadata: { $ref: '#/definitions/ObjectMeta' },
+      description: { type: 'string' },
+    },
+  })).toStrictEqual({
+    properties: {
+      replicas: { default: 1 },
+      strategy: { default: { type: 'Rolling' }, properties: { maxSurge: { default: 25 } } },
+      ports: { items: { properties: { protocol: { default: 'TCP' } } } },
+      labels: { additionalProperties: { default: '' } },
+    },
+  });
+
+  expect(extractDefaults({ type: 'string' })).toBeUndefined();
+});
+
+test('extractConstraints prunes schemas without constraints', () => {
+  expect(extractConstraints({
+    type: 'object',
+    required: [],
+    properties: {
+      replicas: { type: 'integer', minimum: 1 },
+      labels: { type: 'object', additionalProperties: { type: 'string', maxLength: 63 } },
+      ports: { type: 'array', items: { type: 'object', required: ['port'], properties: { port: { type: 'integer' } } } },
+      metadata: { $ref: '#/definitions/ObjectMeta' },
+      description: { type: 'string' },
+    },
+  })).toStrictEqual({
+    properties: {
+      replicas: { minimum: 1 },
+      labels: { additionalProperties: { maxLength: 63 } },
+      ports: { items: { required: ['port'] } },
+    },
+  });
+
+  expect(extractConstraints({ type: 'string' })).toBeUndefined();
+});
+
+test('objects with a schema of their values are typed maps', async () => {
+  const manifest = {
+    apiVersion: 'apiextensions.k8s.io/v1',
+    kind: 'CustomResourceDefinition',
+    spec: {
+      group: 'foo.bar',
+      names: { kind: 'Quota' },
+      versions: [{
+        name: 'v1',
+        served: true,
+        storage: true,
+        schema: {
+          openAPIV3Schema: {
+            type: 'object',
+            properties: {
+              spec: {
+                type: 'object',
+                properties: {
+                  hard: { properties: {}, additionalProperties: { type: 'string' } },
+                  ports: { type: 'object', additionalProperties: { properties: { port: { type: 'integer' } } } },
+                },
+              },
+            },
+          },
+        },
+      }],
+    },
+  };
+
+  await withTempFixture(manifest, async (fixture: string, cwd: string) => {
+    const importer = await ImportCustomResourceDefinition.fromSpec({ source: fixture });
+    await importer.import({ targetLanguage: Language.TYPESCRIPT, outdir: cwd });
+
+    const output = fs.readFileSync(path.join(cwd, 'foo.bar.ts'), 'utf-8');
+    expect(output).toContain('readonly hard?: { [key: string]: string };');
+    expect(output).toContain('readonly ports?: { [key: string]: QuotaSpecPorts };');
+    expect(output).toContain('export interface QuotaSpecPorts {');
+  });
+});
+
+test('properties can be renamed', async () => {
+  const manifest = {
+    apiVersion: 'apiextensions.k8s.io/v1',
+    kind: 'CustomResourceDefinition',
+    spec: {
+      group: 'foo.bar',
+      names: { kind: 'Widget' },
+      versions: [{
+        name: 'v1',
+        served: true,
+        storage: true,
+        schema: {
+          openAPIV3Schema: {
+            type: 'object',
+            properties: {
+              spec: {
+                type: 'object',
+                properties: {
+                  private_key: { type: 'string' },
+                  size: { type: 'integer' },
+                },
+              },
+            },
+          },
+        },
+      }],
+    },
+  };
+
+  await withTempFixture(manifest, async (fixture: string, cwd: string) => {
+    const importer = await ImportCustomResourceDefinition.fromSpec({ source: fixture });
+    await importer.import({
+      targetLanguage: Language.TYPESCRIPT,
+      outdir: cwd,
+      renames: parseRenames(['WidgetSpec#private_key=key', 'size=replicas']),
+    });
+
+    const output = fs.readFileSync(path.join(cwd, 'foo.bar.ts'), 'utf-8');
+    expect(output).toContain('readonly key?: string;');
+    expect(output).toContain('readonly replicas?: number;');
+    expect(output).toContain('\'key\': obj.key,');
+    expect(output).toContain('\'replicas\': obj.replicas,');
+    expect(output).toContain('const names_Widget: any = {');
+    expect(output).toContain('"name": "private_key"');
+    expect(output).toContain('"name": "size"');
+    expect(output).toContain('Widget.restoreNames(toJson_WidgetProps(resolved), names_Widget)');
+    expect(output).not.toContain('privateKey');
+  });
+});
+
+test('properties are renamed in the files of all API groups', async () => {
+  const crd = (group: string) => ({
+    apiVersion: 'apiextensions.k8s.io/v1',
+    kind: 'CustomResourceDefinition',
+    spec: {
+      group,
+      names: { kind: 'Widget' },
+      versions: [{
+        name: 'v1',
+        served: true,
+        storage: true,
+        schema: { openAPIV3Schema: { type: 'object', properties: { spec: { type: 'object', properties: { private_key: { type: 'string' } } } } } },
+      }],
+    },
+  });
+
+  await withTempFixture([crd('foo.bar'), crd('baz.qux')], async (fixture: string, cwd: string) => {
+    const importer = await ImportCustomResourceDefinition.fromSpec({ source: fixture });
+    await importer.import({ targetLanguage: Language.TYPESCRIPT, outdir: cwd, renames: parseRenames(['private_key=key']) });
+
+    for (const file of ['baz.qux.ts', 'foo.bar.ts']) {
+      const output = fs.readFileSync(path.join(cwd, file), 'utf-8');
+      expect(output).toContain('readonly key?: string;');
+      expect(output).toContain('"name": "private_key"');
+      expect(output).toContain('Widget.restoreNames(toJson_WidgetProps(props), names_Widget)');
+    }
+  });
+});
+
+test('enums can be generated as union types', async () => {
+  const manifest = {
+    apiVersion: 'apiextensions.k8s.io/v1',
+    kind: 'CustomResourceDefinition',
+    spec: {
+      group: 'foo.bar',
+      names: { kind: 'Widget' },
+      versions: [{
+        name: 'v1',
+        served: true,
+        storage: true,
+        schema: {
+          openAPIV3Schema: {
+            type: 'object',
+            properties: {
+              spec: {
+                type: 'object',
+                properties: {
+                  policy: { type: 'string', enum: ['Always', 'Never'] },
+                },
+              },
+            },
+          },
+        },
+      }],
+    },
+  };
+
+  await withTempFixture(manifest, async (fixture: string, cwd: string) => {
+    const importer = await ImportCustomResourceDefinition.fromSpec({ source: fixture });
+    await importer.import({ targetLanguage: Language.TYPESCRIPT, outdir: cwd, enumsAsUnions: true });
+
+    const output = fs.readFileSync(path.join(cwd, 'foo.bar.ts'), 'utf-8');
+    expect(output).toContain('export type WidgetSpecPolicy =\n  | \'Always\'\n  | \'Never\';');
+    expect(output).toContain('readonly policy?: WidgetSpecPolicy;');
+    expect(output).not.toContain('export enum');
+  });
+});
+
+test('numeric enums keep their values as union types', async () => {
+  const manifest = {
+    apiVersion: 'apiextensions.k8s.io/v1',
+    kind: 'CustomResourceDefinition',
+    spec: {
+      group: 'foo.bar',
+      names: { kind: 'Widget' },
+      versions: [{
+        name: 'v1',
+        served: true,
+        storage: true,
+        schema: { openAPIV3Schema: { type: 'object', properties: { spec: { type: 'object', properties: { replicas: { type: 'integer', enum: [1, 3] } } } } } },
+      }],
+    },
+  };
+
+  await withTempFixture(manifest, async (fixture: string, cwd: string) => {
+    const importer = await ImportCustomResourceDefinition.fromSpec({ source: fixture });
+    await importer.import({ targetLanguage: Language.TYPESCRIPT, outdir: cwd, enumsAsUnions: true });
+
+    const output = fs.readFileSync(path.join(cwd, 'foo.bar.ts'), 'utf-8');
+    expect(output).toContain('readonly replicas?: ');
+    // every union type has members
+    expect(output).not.toMatch(/export type \w+ =\n(?! {2}\| )/);
+  });
+});
+
+test('enums are generated as union types in the files of all API groups', async () => {
+  const crd = (group: string) => ({
+    apiVersion: 'apiextensions.k8s.io/v1',
+    kind: 'CustomResourceDefinition',
+    spec: {
+      group,
+      names: { kind: 'Widget' },
+      versions: [{
+        name: 'v1',
+        served: true,
+        storage: true,
+        schema: { openAPIV3Schema: { type: 'object', properties: { spec: { type: 'object', properties: { policy: { type: 'string', enum: ['Always', 'Never'] } } } } } },
+      }],
+    },
+  });
+
+  await withTempFixture([crd('foo.bar'), crd('baz.qux')], async (fixture: string, cwd: string) => {
+    const importer = await ImportCustomResourceDefinition.fromSpec({ source: fixture });
+    await importer.import({ targetLanguage: Language.TYPESCRIPT, outdir: cwd, enumsAsUnions: true });
+
+    for (const file of ['baz.qux.ts', 'foo.bar.ts']) {
+      const output = fs.readFileSync(path.join(cwd, file), 'utf-8');
+      expect(output).toContain('export type WidgetSpecPolicy =\n  | \'Always\'\n  | \'Never\';');
+      expect(output).not.toContain('export enum');
+    }
+  });
+});
+
+test('dry runs do not write any files', async () => {
+  const manifest = {
+    apiVersion: 'apiextensions.k8s.io/v1',
+    kind: 'CustomResourceDefinition',
+    spec: {
+      group: 'foo.bar',
+      names: { kind: 'Widget' },
+      versions: [{
+        name: 'v1',
+        served: true,
+        storage: true,
+        schema: { openAPIV3Schema: { type: 'object', properties: { spec: { type: 'object', properties: { size: { type: 'integer' } } } } } },
+      }],
+    },
+  };
+
+  await withTempFixture(manifest, async (fixture: string, cwd: string) => {
+    const outdir = path.join(cwd, 'imports');
+    const log = jest.spyOn(console, 'log').mockImplementation(() => undefined);
+    try {
+      const importer = await ImportCustomResourceDefinition.fromSpec({ source: fixture });
+      const emitted = await importer.import({ targetLanguage: Language.TYPESCRIPT, outdir, dryRun: true });
+
+      expect(emitted).toEqual([path.join(outdir, 'foo.bar.ts')]);
+      expect(fs.existsSync(outdir)).toBeFalsy();
+
+      const output = log.mock.calls.map(args => args.join(' ')).join('\n');
+      expect(output).toContain('Dry run, no files were written.');
+      expect(output).toContain(`would write ${path.relative(process.cwd(), path.join(outdir, 'foo.bar.ts'))}`);
+      expect(output).toContain('foo.bar/Widget (foo.bar/v1) => Widget');
+    } finally {
+      log.mockRestore();
+    }
+  });
+});
+
+test('properties are emitted in the order they are declared', async () => {
+  const manifest = {
+    apiVersion: 'apiextensions.k8s.io/v1',
+    kind: 'CustomResourceDefinition',
+    spec: {
+      group: 'foo.bar',
+      names: { kind: 'Widget' },
+      versions: [{
+        name: 'v1',
+        served: true,
+        storage: true,
+        schema: {
+          openAPIV3Schema: {
+            type: 'object',
+            properties: {
+              spec: {
+                type: 'object',
+                properties: {
+                  replicas: { type: 'integer' },
+                  image: { type: 'string' },
+                  args: { type: 'array', items: { type: 'string' } },
+                },
+              },
+            },
+          },
+        },
+      }],
+    },
+  };
+
+  await withTempFixture(manifest, async (fixture: string, cwd: string) => {
+    const importer = await ImportCustomResourceDefinition.fromSpec({ source: fixture });
+    await importer.import({ targetLanguage: Language.TYPESCRIPT, outdir: cwd });
+
+    const output = fs.readFileSync(path.join(cwd, 'foo.bar.ts'), 'utf-8');
+    const order = (pattern: RegExp) => Array.from(output.match(pattern) ?? []);
+
+    expect(order(/readonly (replicas|image|args|metadata|spec)\?/g)).toEqual([
+      'readonly metadata?', 'readonly spec?', 'readonly replicas?', 'readonly image?', 'readonly args?',
+    ]);
+    expect(order(/'(replicas|image|args)':/g)).toEqual(['\'replicas\':', '\'image\':', '\'args\':']);
+  });
+});
+
+describe('unify versions', () => {
+  const version = (name: string, spec: any) => ({
+    name,
+    served: true,
+    storage: name === 'v1',
+    schema: { openAPIV3Schema: { type: 'object', properties: { spec } } },
+  });
+
+  const manifest = (versions: any[]) => ({
+    apiVersion: 'apiextensions.k8s.io/v1',
+    kind: 'CustomResourceDefinition',
+    spec: { group: 'foo.bar', names: { kind: 'Widget' }, versions },
+  });
+
+  test('generates a single construct with a version selector', async () => {
+    const crd = manifest([
+      version('v1', { type: 'object', properties: { replicas: { type: 'integer' } } }),
+      version('v1beta1', { type: 'object', properties: { size: { type: 'string' } } }),
+    ]);
+
+    await withTempFixture(crd, async (fixture: string, cwd: string) => {
+      const importer = await ImportCustomResourceDefinition.fromSpec({ source: fixture });
+      await importer.import({ targetLanguage: Language.TYPESCRIPT, outdir: cwd, unifyVersions: true });
+
+      const output = fs.readFileSync(path.join(cwd, 'foo.bar.ts'), 'utf-8');
+      expect(output).toContain([
+        'export type WidgetProps =',
+        '  | ({ readonly version: \'v1\' } & WidgetV1Props)',
+        '  | ({ readonly version: \'v1beta1\' } & WidgetV1Beta1Props);',
+      ].join('\n'));
+      expect(output).toContain('export class Widget extends ApiObject {');
+      expect(output).toContain('apiVersion: `foo.bar/${version}`,');
+      expect(output).toContain('case \'v1beta1\': return toJson_WidgetV1Beta1Props(props);');
+      expect(output).toContain('export interface WidgetV1Spec {');
+      expect(output).toContain('export interface WidgetV1Beta1Spec {');
+      expect(output).not.toContain('export class WidgetV1Beta1 ');
+    });
+  });
+
+  test('versions with different top-level fields are generated separately', async () => {
+    const crd = manifest([
+      version('v1', { type: 'object' }),
+      { ...version('v1beta1', { type: 'object' }), schema: { openAPIV3Schema: { type: 'object', properties: { config: { type: 'object' } } } } },
+    ]);
+
+    await withTempFixture(crd, async (fixture: string, cwd: string) => {
+      const error = jest.spyOn(console, 'error').mockImplementation(() => undefined);
+      try {
+        const importer = await ImportCustomResourceDefinition.fromSpec({ source: fixture });
+        await importer.import({ targetLanguage: Language.TYPESCRIPT, outdir: cwd, unifyVersions: true });
+
+        expect(error).toHaveBeenCalledWith('warning: Cannot unify the versions of foo.bar/widget (the versions have different top-level fields: v1 (spec), v1beta1 (config)), generating a construct per version');
+      } finally {
+        error.mockRestore();
+      }
+
+      const output = fs.readFileSync(path.join(cwd, 'foo.bar.ts'), 'utf-8');
+      expect(output).toContain('export class Widget extends ApiObject {');
+      expect(output).toContain('export class WidgetV1Beta1 extends ApiObject {');
+    });
+  });
+});
@@ -0,0 +1,75 @@
+import { enumsToUnions } from '../../src/import/enums';
+
+test('enums are replaced with union types', () => {
+  const code = [
+    '/**',
+    ' * @schema SelectorOperator',
+    ' */',
+    'export enum SelectorOperator {',
+    '  /** In */',
+    '  IN = \'In\',',
+    '  /** != */',
+    '  VALUE_NOT_EQUAL = \'!=\',',
+    '  /** it\'s */',
+    '  ITS = \'it\\\'s\',',
+    '}',
+    '',
+    'export interface Selector {',
+    '  readonly operator?: SelectorOperator;',
+    '}',
+  ].join('\n');
+
+  expect(enumsToUnions(code)).toEqual([
+    '/**',
+    ' * @schema SelectorOperator',
+    ' */',
+    'export type SelectorOperator =',
+    '  | \'In\'',
+    '  | \'!=\'',
+    '  | \'it\\\'s\';',
+    '',
+    'export interface Selector {',
+    '  readonly operator?: SelectorOperator;',
+    '}',
+  ].join('\n'));
+});
+
+test('numeric and mixed enums are replaced with union types', () => {
+  const code = [
+    'export enum WidgetSpecReplicas {',
+    '  /** 1 */',
+    '  VALUE_1 = 1,',
+    '  /** 2.5 */',
+    '  VALUE_2_5 = 2.5,',
+    '}',
+    '',
+    'export enum WidgetSpecMode {',
+    '  /** auto */',
+    '  AUTO = "auto",',
+    '  /** 0 */',
+    '  VALUE_0 = 0,',
+    '}',
+  ].join('\n');
+
+  expect(enumsToUnions(code)).toEqual([
+    'export type WidgetSpecReplicas =',
+    '  | 1',
+    '  | 2.5;',
+    '',
+    'export type WidgetSpecMode =',
+    '  | "auto"',
+    '  | 0;',
+  ].join('\n'));
+});
+
+test('enums with members that are not literals are kept', () => {
+  const code = [
+    'export enum Flags {',
+    '  /** A */',
+    '  A = 1 << 0,',
+    '  B = \'b\',',
+    '}',
+  ].join('\n');
+
+  expect(enumsToUnions(code)).toEqual(code);
+});
@@ -0,0 +1,94 @@
+import * as os from 'os';
+import * as path from 'path';
+import * as fs from 'fs-extra';
+import { mocked } from 'ts-jest/utils';
+import { cloneGitRepository, matchGitRepository } from '../../src/import/git';
+import { shell } from '../../src/util';
+
+jest.mock('../../src/util', () => {
+  const mod = jest.requireActual('../../src/util');
+  return {
+    ...mod,
+    shell: jest.fn(),
+  };
+});
+
+const gitRepositoryTests = [
+  {
+    import: 'git+https://github.com/org/repo.git//config/crd?ref=v1.2.0',
+    expected: { url: 'https://github.com/org/repo.git', subdir: 'config/crd', ref: 'v1.2.0' },
+  },
+  {
+    import: 'git+https://github.com/org/repo.git',
+    expected: { url: 'https://github.com/org/repo.git', subdir: undefined, ref: undefined },
+  },
+  {
+    import: 'git+ssh://git@github.com/org/repo.git?ref=main',
+    expected: { url: 'ssh://git@github.com/org/repo.git', subdir: undefined, ref: 'main' },
+  },
+  {
+    import: 'git+file:///tmp/repo//crds/',
+    expected: { url: 'file:///tmp/repo', subdir: 'crds', ref: undefined },
+  },
+];
+
+describe('git repository reference', () => {
+  for (const t of gitRepositoryTests) {
+    test(t.import, () => {
+      expect(matchGitRepository(t.import)).toStrictEqual(t.expected);
+    });
+  }
+});
+
+test('ignores other sources', () => {
+  expect(matchGitRepository('https://github.com/org/repo.git')).toBeUndefined();
+  expect(matchGitRepository('github:org/repo@1.0.0')).toBeUndefined();
+});
+
+test('fails for an invalid repository', () => {
+  expect(() => matchGitRepository('git+github.com/org/repo')).toThrow('Expected Git repository "git+github.com/org/repo" to match format "git+https://<host>/<repository>[//<subdir>][?ref=<ref>]".');
+  expect(() => matchGitRepository('git+https://github.com/org/repo.git//../etc')).toThrow('must be within the repository');
+});
+
+describe('clone', () => {
+  let workdir: string;
+
+  beforeEach(() => {
+    workdir = fs.mkdtempSync(path.join(os.tmpdir(), 'cdk8s-import-git-'));
+    fs.mkdirpSync(path.join(workdir, 'config', 'crd'));
+  });
+
+  afterEach(() => {
+    fs.removeSync(workdir);
+  });
+
+  test('branches and tags are cloned', async () => {
+    const dir = await cloneGitRepository({ url: 'https://github.com/org/repo.git', subdir: 'config/crd', ref: 'v1.2.0' }, workdir);
+
+    expect(dir).toEqual(path.join(workdir, 'config', 'crd'));
+    expect(shell).toHaveBeenCalledWith('git', ['clone', '--quiet', '--depth', '1', '--branch', 'v1.2.0', 'https://github.com/org/repo.git', workdir]);
+  });
+
+  test('commits are fetched', async () => {
+    const sha = 'a'.repeat(40);
+    await cloneGitRepository({ url: 'https://github.com/org/repo.git', ref: sha }, workdir);
+
+    expect(mocked(shell).mock.calls).toEqual([
+      ['git', ['init', '--quiet'], { cwd: workdir }],
+      ['git', ['remote', 'add', 'origin', 'https://github.com/org/repo.git'], { cwd: workdir }],
+      ['git', ['fetch', '--quiet', '--depth', '1', 'origin', sha], { cwd: workdir }],
+      ['git', ['checkout', '--quiet', 'FETCH_HEAD'], { cwd: workdir }],
+    ]);
+  });
+
+  test('fails if the subdirectory does not exist', async () => {
+    await expect(cloneGitRepository({ url: 'https://github.com/org/repo.git', subdir: 'crds' }, workdir))
+      .rejects.toThrow('Directory "crds" not found in Git repository "https://github.com/org/repo.git"');
+  });
+
+  test('fails if the repository cannot be cloned', async () => {
+    mocked(shell).mockRejectedValueOnce(new Error('exit code 128'));
+    await expect(cloneGitRepository({ url: 'https://github.com/org/repo.git', ref: 'main' }, workdir))
+      .rejects.toThrow('Unable to clone Git repository "https://github.com/org/repo.git" at "main"');
+  });
+});
@@ -0,0 +1,139 @@
+import * as os from 'os';
+import * as path from 'path';
+import * as fs from 'fs-extra';
+import { Language } from '../../src/import/base';
+import { importDispatch } from '../../src/import/dispatch';
+import { matchHelmChart, renderHelmChart } from '../../src/import/helm';
+import { shell } from '../../src/util';
+
+jest.mock('../../src/util', () => ({
+  ...jest.requireActual('../../src/util'),
+  shell: jest.fn(),
+}));
+
+const mockShell = shell as jest.MockedFunction<typeof shell>;
+
+const crd = (group: string, kind: string) => [
+  'apiVersion: apiextensions.k8s.io/v1',
+  'kind: CustomResourceDefinition',
+  'metadata:',
+  `  name: ${kind.toLowerCase()}s.${group}`,
+  'spec:',
+  `  group: ${group}`,
+  '  names:',
+  `    kind: ${kind}`,
+  '  versions:',
+  '    - name: v1',
+  '      served: true',
+  '      storage: true',
+  '      schema:',
+  '        openAPIV3Schema:',
+  '          type: object',
+  '          properties:',
+  '            spec:',
+  '              type: object',
+  '              properties:',
+  '                size:',
+  '                  type: string',
+].join('\n');
+
+// the output of "helm template --include-crds" for a chart with a CRD in its
+// "crds/" directory and a templated CRD
+const rendered = [
+  '---',
+  '# Source: mychart/crds/widget.yaml',
+  crd('widgets.example.com', 'Widget'),
+  '---',
+  '# Source: mychart/templates/service.yaml',
+  'apiVersion: v1',
+  'kind: Service',
+  'metadata:',
+  '  name: cdk8s-mychart',
+  '---',
+  '# Source: mychart/templates/gadget-crd.yaml',
+  crd('gadgets.example.com', 'Gadget'),
+  '---',
+  '# Source: mychart/templates/deployment.yaml',
+  'apiVersion: apps/v1',
+  'kind: Deployment',
+  'metadata:',
+  '  name: cdk8s-mychart',
+  '',
+].join('\n');
+
+beforeEach(() => {
+  mockShell.mockReset();
+});
+
+const helmChartTests = [
+  { import: 'helm:https://charts.example.com/mychart@1.2.3', expected: { repo: 'https://charts.example.com', chart: 'mychart', version: '1.2.3' } },
+  { import: 'helm:https://charts.example.com/stable/mychart', expected: { repo: 'https://charts.example.com/stable', chart: 'mychart', version: undefined } },
+  { import: 'helm:./local-chart.tgz', expected: { chart: './local-chart.tgz', version: undefined } },
+  { import: 'helm:./local-chart@0.1.0-rc.1', expected: { chart: './local-chart', version: '0.1.0-rc.1' } },
+  { import: 'helm:https://charts.example.com/mychart@v1.2.3', expected: { repo: 'https://charts.example.com', chart: 'mychart', version: 'v1.2.3' } },
+  { import: 'helm:https://charts.example.com/mychart@~1.2', expected: { repo: 'https://charts.example.com', chart: 'mychart', version: '~1.2' } },
+  { import: 'helm:oci://registry.example.com/charts/mychart@2.0.0', expected: { chart: 'oci://registry.example.com/charts/mychart', version: '2.0.0' } },
+  { import: 'https://charts.example.com/mychart', expected: undefined, reason: 'missing helm scheme' },
+  { import: 'github:crossplane/crossplane@0.14.0', expected: undefined, reason: 'crds.dev import' },
+];
+
+describe('helm chart reference', () => {
+  for ( const t of helmChartTests ) {
+    test(t.import, () => {
+      expect(matchHelmChart(t.import)).toStrictEqual(t.expected);
+    });
+  }
+});
+
+test('fails if remote chart has no name', () => {
+  expect(() => matchHelmChart('helm:https://charts.example.com')).toThrow('Expected helm chart "https://charts.example.com" to match format "helm:<repo-url>/<chart>[@<version>]".');
+});
+
+describe('renderHelmChart', () => {
+  test('renders remote charts with their repo and version', async () => {
+    mockShell.mockResolvedValue(rendered);
+
+    expect(await renderHelmChart({ repo: 'https://charts.example.com', chart: 'mychart', version: '1.2.3' })).toEqual(rendered);
+    expect(mockShell).toHaveBeenCalledWith('helm', ['template', 'cdk8s', 'mychart', '--include-crds', '--repo', 'https://charts.example.com', '--version', '1.2.3']);
+  });
+
+  test('renders local charts', async () => {
+    mockShell.mockResolvedValue(rendered);
+
+    await renderHelmChart({ chart: './mychart' });
+    expect(mockShell).toHaveBeenCalledWith('helm', ['template', 'cdk8s', './mychart', '--include-crds']);
+  });
+
+  test('fails if helm fails', async () => {
+    mockShell.mockRejectedValue(new Error('command "helm" failed'));
+
+    await expect(renderHelmChart({ chart: './mychart' })).rejects.toThrow('Unable to render helm chart "./mychart" (make sure "helm" is installed): Error: command "helm" failed');
+  });
+});
+
+describe('helm imports', () => {
+  let workdir: string;
+
+  beforeEach(() => {
+    workdir = fs.mkdtempSync(path.join(os.tmpdir(), 'cdk8s-import-helm-'));
+  });
+
+  afterEach(() => {
+    fs.removeSync(workdir);
+  });
+
+  test('imports the CRDs of the rendered chart and skips other resources', async () => {
+    mockShell.mockResolvedValue(rendered);
+
+    const emitted = await importDispatch([{ source: 'helm:https://charts.example.com/mychart@1.2.3' }], { }, {
+      targetLanguage: Language.TYPESCRIPT,
+      outdir: workdir,
+    });
+
+    expect(mockShell).toHaveBeenCalledWith('helm', ['template', 'cdk8s', 'mychart', '--include-crds', '--repo', 'https://charts.example.com', '--version', '1.2.3']);
+    expect(emitted.map(file => path.basename(file)).sort()).toStrictEqual(['gadgets.example.com.ts', 'widgets.example.com.ts']);
+    expect(fs.readdirSync(workdir).sort()).toStrictEqual(['gadgets.example.com.ts', 'widgets.example.com.ts']);
+    expect(fs.readFileSync(path.join(workdir, 'widgets.example.com.ts'), 'utf-8')).toContain('export class Widget extends ApiObject');
+    expect(fs.readFileSync(path.join(workdir, 'gadgets.example.com.ts'), 'utf-8')).toContain('export class Gadget extends ApiObject');
+  });
+});
@@ -0,0 +1,192 @@
+import * as os from 'os';
+import * as path from 'path';
+import * as fs from 'fs-extra';
+import { mocked } from 'ts-jest/utils';
+import { Language } from '../../src/import/base';
+import { ImportKubernetesApi } from '../../src/import/k8s';
+import { getServerVersion, kubectl } from '../../src/kubectl';
+
+jest.mock('../../src/kubectl', () => {
+  const mod = jest.requireActual('../../src/kubectl');
+  return {
+    ...mod,
+    kubectl: jest.fn(),
+    getServerVersion: jest.fn(),
+  };
+});
+
+const definitions = {
+  'io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta': {
+    type: 'object',
+    properties: { name: { type: 'string' } },
+  },
+  'io.k8s.api.example.v1alpha1.Widget': {
+    'type': 'object',
+    'properties': {
+      metadata: { $ref: '#/definitions/io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta' },
+      size: { type: 'integer' },
+    },
+    'x-kubernetes-group-version-kind': [{ group: 'example.k8s.io', kind: 'Widget', version: 'v1alpha1' }],
+  },
+};
+
+let workdir: string;
+
+beforeEach(() => {
+  workdir = fs.mkdtempSync(path.join(os.tmpdir(), 'cdk8s-import-cluster-'));
+  jest.spyOn(console, 'error').mockImplementation(() => undefined);
+});
+
+afterEach(() => {
+  fs.removeSync(workdir);
+  jest.restoreAllMocks();
+});
+
+test('the version of the cluster is used', async () => {
+  mocked(getServerVersion).mockResolvedValue('1.24.3');
+
+  const options = await ImportKubernetesApi.match({ source: 'k8s' }, { fromCluster: true, kubeContext: 'staging' });
+
+  expect(options).toStrictEqual({ apiVersion: '1.24.3', exclude: undefined, fromCluster: true, kubeContext: 'staging' });
+  expect(getServerVersion).toHaveBeenCalledWith({ context: 'staging' });
+});
+
+test('a k8s version cannot be used with --from-cluster', async () => {
+  await expect(ImportKubernetesApi.match({ source: 'k8s@1.22.0' }, { fromCluster: true }))
+    .rejects.toThrow('A k8s version ("k8s@1.22.0") cannot be used in combination with --from-cluster');
+});
+
+test('types are generated from the openapi v2 spec of the cluster', async () => {
+  mocked(kubectl).mockImplementation(async () => {
+    // paths contain keys that must not be sanitized
+    return JSON.stringify({ swagger: '2.0', paths: { '/api/v1/namespaces/{namespace}/widgets': {} }, definitions });
+  });
+
+  const importer = new ImportKubernetesApi({ apiVersion: '1.24.3', fromCluster: true, kubeContext: 'staging' });
+  await importer.import({ targetLanguage: Language.TYPESCRIPT, outdir: workdir });
+
+  const output = fs.readFileSync(path.join(workdir, 'k8s.ts'), 'utf-8');
+  expect(output).toContain('export class KubeWidgetV1Alpha1 extends ApiObject');
+  expect(output).toContain('apiVersion: \'example.k8s.io/v1alpha1\'');
+  expect(kubectl).toHaveBeenCalledWith(['get', '--raw', '/openapi/v2'], { context: 'staging' });
+});
+
+test('falls back to the openapi v3 specs of the cluster', async () => {
+  const v3 = (schemas: Record<string, any>) => JSON.stringify({ openapi: '3.0.0', components: { schemas } });
+  const toV3 = (schema: any) => JSON.parse(JSON.stringify(schema).replace(/#\/definitions\//g, '#/components/schemas/'));
+
+  mocked(kubectl).mockImplementation(async args => {
+    switch (args[2]) {
+      case '/openapi/v2': throw new Error('not found');
+      case '/openapi/v3': return JSON.stringify({
+        paths: {
+          'apis/meta/v1': { serverRelativeURL: '/openapi/v3/apis/meta/v1?hash=1' },
+          'apis/example.k8s.io/v1alpha1': { serverRelativeURL: '/openapi/v3/apis/example.k8s.io/v1alpha1?hash=2' },
+        },
+      });
+      case '/openapi/v3/apis/meta/v1?hash=1': return v3({ 'io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta': definitions['io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta'] });
+      case '/openapi/v3/apis/example.k8s.io/v1alpha1?hash=2': return v3({ 'io.k8s.api.example.v1alpha1.Widget': toV3(definitions['io.k8s.api.example.v1alpha1.Widget']) });
+      default: throw new Error(`unexpected ${args}`);
+    }
+  });
+
+  const importer = new ImportKubernetesApi({ apiVersion: '1.27.0', fromCluster: true });
+  await importer.import({ targetLanguage: Language.TYPESCRIPT, outdir: workdir });
+
+  const output = fs.readFileSync(path.join(workdir, 'k8s.ts'), 'utf-8');
+  expect(output).toContain('export class KubeWidgetV1Alpha1 extends ApiObject');
+  expect(output).toContain('readonly metadata?: ObjectMeta;');
+});
+
+describe('aggregated APIs', () => {
+
+  const metrics = {
+    'io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta': definitions['io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta'],
+    'io.k8s.metrics.pkg.apis.metrics.v1beta1.NodeMetrics': {
+      'type': 'object',
+      'properties': {
+        metadata: { $ref: '#/components/schemas/io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta' },
+        window: { type: 'string' },
+      },
+      'x-kubernetes-group-version-kind': [{ group: 'metrics.k8s.io', kind: 'NodeMetrics', version: 'v1beta1' }],
+    },
+  };
+
+  test('requires --from-cluster', async () => {
+    await expect(ImportKubernetesApi.match({ source: 'aggregated:metrics.k8s.io/v1beta1' }, { }))
+      .rejects.toThrow('The aggregated API "metrics.k8s.io/v1beta1" can only be imported with --from-cluster');
+  });
+
+  test('the group version must be valid', async () => {
+    await expect(ImportKubernetesApi.match({ source: 'aggregated:metrics.k8s.io' }, { fromCluster: true }))
+      .rejects.toThrow('Expected aggregated API "metrics.k8s.io" to match format "<group>/<version>".');
+  });
+
+  test('types are generated from the openapi v3 spec of the group version', async () => {
+    mocked(getServerVersion).mockResolvedValue('1.27.0');
+    mocked(kubectl).mockImplementation(async args => {
+      switch (args[2]) {
+        case '/apis/metrics.k8s.io/v1beta1': return JSON.stringify({ kind: 'APIResourceList' });
+        case '/openapi/v3': return JSON.stringify({
+          paths: {
+            'apis/example.k8s.io/v1alpha1': { serverRelativeURL: '/openapi/v3/apis/example.k8s.io/v1alpha1?hash=1' },
+            'apis/metrics.k8s.io/v1beta1': { serverRelativeURL: '/openapi/v3/apis/metrics.k8s.io/v1beta1?hash=2' },
+          },
+        });
+        case '/openapi/v3/apis/metrics.k8s.io/v1beta1?hash=2': return JSON.stringify({ openapi: '3.0.0', components: { schemas: metrics } });
+        default: throw new Error(`unexpected ${args}`);
+      }
+    });
+
+    const options = await ImportKubernetesApi.match({ source: 'aggregated:metrics.k8s.io/v1beta1' }, { fromCluster: true });
+    const importer = new ImportKubernetesApi(options!);
+    expect(importer.moduleNames).toStrictEqual(['metrics.k8s.io']);
+
+    await importer.import({ targetLanguage: Language.TYPESCRIPT, outdir: workdir });
+
+    const output = fs.readFileSync(path.join(workdir, 'metrics.k8s.io.ts'), 'utf-8');
+    expect(output).toContain('export class KubeNodeMetricsV1Beta1 extends ApiObject');
+    expect(output).toContain('apiVersion: \'metrics.k8s.io/v1beta1\'');
+  });
+
+  test('only api objects of the group version are generated from the openapi v2 spec', async () => {
+    mocked(kubectl).mockImplementation(async args => {
+      switch (args[2]) {
+        case '/apis/metrics.k8s.io/v1beta1': return JSON.stringify({ kind: 'APIResourceList' });
+        case '/openapi/v3': throw new Error('not found');
+        case '/openapi/v2': return JSON.stringify({
+          definitions: {
+            ...definitions,
+            ...JSON.parse(JSON.stringify(metrics).replace(/#\/components\/schemas\//g, '#/definitions/')),
+          },
+        });
+        default: throw new Error(`unexpected ${args}`);
+      }
+    });
+
+    const importer = new ImportKubernetesApi({ apiVersion: '1.24.3', fromCluster: true, aggregatedApi: 'metrics.k8s.io/v1beta1' });
+    await importer.import({ targetLanguage: Language.TYPESCRIPT, outdir: workdir });
+
+    const output = fs.readFileSync(path.join(workdir, 'metrics.k8s.io.ts'), 'utf-8');
+    expect(output).toContain('export class KubeNodeMetricsV1Beta1 extends ApiObject');
+    expect(output).not.toContain('KubeWidgetV1Alpha1');
+  });
+
+  test('fails with the status of the api service if the server is unreachable', async () => {
+    mocked(kubectl).mockImplementation(async args => {
+      if (args[0] === 'get' && args[1] === 'apiservice') {
+        return JSON.stringify({
+          status: {
+            conditions: [{ type: 'Available', status: 'False', reason: 'FailedDiscoveryCheck', message: 'no response from https://10.0.0.1:443' }],
+          },
+        });
+      }
+      throw new Error('the server is currently unable to handle the request');
+    });
+
+    const importer = new ImportKubernetesApi({ apiVersion: '1.24.3', fromCluster: true, aggregatedApi: 'metrics.k8s.io/v1beta1' });
+    await expect(importer.loadSchema()).rejects.toThrow('The aggregated API "metrics.k8s.io/v1beta1" is unreachable (APIService "v1beta1.metrics.k8s.io" is not available: FailedDiscoveryCheck, no response from https://10.0.0.1:443)');
+    expect(kubectl).toHaveBeenCalledWith(['get', 'apiservice', 'v1beta1.metrics.k8s.io', '-o', 'json'], { context: undefined });
+  });
+
+});
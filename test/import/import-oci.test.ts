@@ -0,0 +1,120 @@
+import * as os from 'os';
+import * as path from 'path';
+import * as fs from 'fs-extra';
+import { Language } from '../../src/import/base';
+import { importDispatch } from '../../src/import/dispatch';
+import { formatOciArtifact, matchOciArtifact, pullOciArtifact } from '../../src/import/oci';
+
+const ociArtifactTests = [
+  { import: 'oci://registry.example.com/crds/myapp:v1', expected: { registry: 'registry.example.com', repository: 'crds/myapp', tag: 'v1', digest: undefined }, reference: 'registry.example.com/crds/myapp:v1' },
+  { import: 'oci://localhost:5000/myapp', expected: { registry: 'localhost:5000', repository: 'myapp', tag: undefined, digest: undefined }, reference: 'localhost:5000/myapp:latest' },
+  {
+    import: `oci://ghcr.io/acme/crds@sha256:${'a'.repeat(64)}`,
+    expected: { registry: 'ghcr.io', repository: 'acme/crds', tag: undefined, digest: `sha256:${'a'.repeat(64)}` },
+    reference: `ghcr.io/acme/crds@sha256:${'a'.repeat(64)}`,
+  },
+];
+
+describe('oci artifact reference', () => {
+  for ( const t of ociArtifactTests ) {
+    test(t.import, () => {
+      const ref = matchOciArtifact(t.import);
+      expect(ref).toStrictEqual(t.expected);
+      expect(formatOciArtifact(ref!)).toEqual(t.reference);
+    });
+  }
+});
+
+test('ignores other sources', () => {
+  expect(matchOciArtifact('helm:oci://registry.example.com/charts/mychart@2.0.0')).toBeUndefined();
+  expect(matchOciArtifact('https://registry.example.com/crds/myapp')).toBeUndefined();
+});
+
+test('fails if the artifact has no repository', () => {
+  expect(() => matchOciArtifact('oci://registry.example.com')).toThrow('Expected OCI artifact "oci://registry.example.com" to match format "oci://<registry>/<repository>[:<tag>|@<digest>]".');
+});
+
+const crd = (group: string, kind: string) => [
+  'apiVersion: apiextensions.k8s.io/v1',
+  'kind: CustomResourceDefinition',
+  'metadata:',
+  `  name: ${kind.toLowerCase()}s.${group}`,
+  'spec:',
+  `  group: ${group}`,
+  '  names:',
+  `    kind: ${kind}`,
+  '  versions:',
+  '    - name: v1',
+  '      served: true',
+  '      storage: true',
+  '      schema:',
+  '        openAPIV3Schema:',
+  '          type: object',
+  '          properties:',
+  '            spec:',
+  '              type: object',
+  '              properties:',
+  '                size:',
+  '                  type: string',
+].join('\n');
+
+describe('pull', () => {
+  let workdir: string;
+  let outdir: string;
+  const originalPath = process.env.PATH;
+
+  // a stub of "oras pull REFERENCE --output DIR" that writes an archive layer
+  // and a plain layer, and records its arguments
+  beforeEach(() => {
+    workdir = fs.mkdtempSync(path.join(os.tmpdir(), 'cdk8s-import-oci-'));
+    outdir = path.join(workdir, 'out');
+    fs.mkdirpSync(outdir);
+
+    const layer = path.join(workdir, 'layer');
+    fs.mkdirpSync(path.join(layer, 'crds'));
+    fs.writeFileSync(path.join(layer, 'crds', 'widget.yaml'), crd('widgets.example.com', 'Widget'));
+
+    const bin = path.join(workdir, 'bin');
+    fs.mkdirpSync(bin);
+    fs.writeFileSync(path.join(bin, 'oras'), [
+      '#!/bin/sh',
+      `echo "$@" > "${path.join(workdir, 'oras.args')}"`,
+      `tar -czf "$4/crds.tgz" -C "${layer}" .`,
+      `cat > "$4/gadget.yaml" <<EOF\n${crd('gadgets.example.com', 'Gadget')}\nEOF`,
+    ].join('\n'), { mode: 0o755 });
+
+    process.env.PATH = `${bin}${path.delimiter}${originalPath}`;
+  });
+
+  afterEach(() => {
+    process.env.PATH = originalPath;
+    fs.removeSync(workdir);
+  });
+
+  test('archive layers are extracted and removed', async () => {
+    await pullOciArtifact({ registry: 'registry.example.com', repository: 'crds/myapp', tag: 'v1' }, outdir);
+
+    expect(fs.readFileSync(path.join(workdir, 'oras.args'), 'utf-8').trim()).toEqual(`pull registry.example.com/crds/myapp:v1 --output ${outdir}`);
+    expect(fs.readdirSync(outdir).sort()).toStrictEqual(['crds.tgz.d', 'gadget.yaml']);
+    expect(fs.readFileSync(path.join(outdir, 'crds.tgz.d', 'crds', 'widget.yaml'), 'utf-8')).toContain('kind: Widget');
+  });
+
+  test('imports the CRDs of all layers', async () => {
+    const imports = path.join(workdir, 'imports');
+    const emitted = await importDispatch([{ source: 'oci://registry.example.com/crds/myapp:v1' }], { }, {
+      targetLanguage: Language.TYPESCRIPT,
+      outdir: imports,
+    });
+
+    expect(emitted.map(file => path.basename(file)).sort()).toStrictEqual(['gadgets.example.com.ts', 'widgets.example.com.ts']);
+    expect(fs.readFileSync(path.join(imports, 'widgets.example.com.ts'), 'utf-8')).toContain('export class Widget extends ApiObject');
+    expect(fs.readFileSync(path.join(imports, 'gadgets.example.com.ts'), 'utf-8')).toContain('export class Gadget extends ApiObject');
+  });
+
+  test('fails if oras fails', async () => {
+    fs.writeFileSync(path.join(workdir, 'bin', 'oras'), '#!/bin/sh\necho "unauthorized" >&2\nexit 1\n', { mode: 0o755 });
+
+    await expect(pullOciArtifact({ registry: 'registry.example.com', repository: 'crds/myapp' }, outdir))
+      .rejects.toThrow('Unable to pull OCI artifact "registry.example.com/crds/myapp:latest" (make sure "oras" is installed and you are logged in to the registry)');
+  });
+});
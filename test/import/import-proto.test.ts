@@ -0,0 +1,168 @@
+import * as os from 'os';
+import * as path from 'path';
+import * as fs from 'fs-extra';
+import { Language } from '../../src/import/base';
+import { ImportProto, jsonName, loadProtoFiles, matchProtoFile, parseProto } from '../../src/import/proto';
+
+const widget = `
+syntax = "proto3";
+
+package widgets.example.com.v1alpha1;
+
+import "google/protobuf/timestamp.proto";
+import "common.proto";
+
+// A widget.
+message Widget {
+  common.ObjectMeta metadata = 1;
+
+  // The desired state of the widget.
+  WidgetSpec spec = 2;
+}
+
+message WidgetSpec {
+  // The number of replicas.
+  int32 max_replicas = 1;
+  repeated string tags = 2;
+  map<string, Port> ports = 3 [json_name = "namedPorts"];
+  google.protobuf.Timestamp expires_at = 4;
+  Size size = 5;
+
+  oneof source {
+    string url = 6;
+    bytes data = 7;
+  }
+
+  enum Size {
+    SIZE_UNSPECIFIED = 0;
+    SMALL = 1;
+  }
+
+  message Port {
+    int64 number = 1;
+  }
+}
+
+service WidgetService {
+  rpc Get(Widget) returns (Widget) {}
+}
+`;
+
+const common = `
+syntax = "proto3";
+package common;
+message ObjectMeta { string name = 1; }
+`;
+
+let workdir: string;
+
+beforeEach(() => {
+  workdir = fs.mkdtempSync(path.join(os.tmpdir(), 'cdk8s-import-proto-'));
+  jest.spyOn(console, 'error').mockImplementation(() => undefined);
+});
+
+afterEach(() => {
+  fs.removeSync(workdir);
+  jest.restoreAllMocks();
+});
+
+test('matchProtoFile', () => {
+  expect(matchProtoFile('proto:./api/widget.proto')).toBe('./api/widget.proto');
+  expect(matchProtoFile('widget.proto')).toBeUndefined();
+  expect(() => matchProtoFile('proto:')).toThrow('Invalid protobuf import "proto:". Expected "proto:<file>.proto"');
+});
+
+describe('parseProto', () => {
+
+  test('flattens nested declarations', () => {
+    const file = parseProto(widget, 'widget.proto');
+
+    expect(file.package).toBe('widgets.example.com.v1alpha1');
+    expect(file.imports).toStrictEqual(['google/protobuf/timestamp.proto', 'common.proto']);
+    expect(file.messages.map(m => [m.fullName, m.topLevel])).toStrictEqual([
+      ['widgets.example.com.v1alpha1.Widget', true],
+      ['widgets.example.com.v1alpha1.WidgetSpec', true],
+      ['widgets.example.com.v1alpha1.WidgetSpec.Port', false],
+    ]);
+    expect(file.enums).toStrictEqual([{ fullName: 'widgets.example.com.v1alpha1.WidgetSpec.Size', values: ['SIZE_UNSPECIFIED', 'SMALL'], comment: undefined }]);
+  });
+
+  test('fields', () => {
+    const spec = parseProto(widget, 'widget.proto').messages[1];
+
+    expect(spec.fields.slice(0, 3)).toStrictEqual([
+      { name: 'max_replicas', type: 'int32', label: undefined, keyType: undefined, jsonName: undefined, comment: 'The number of replicas.' },
+      { name: 'tags', type: 'string', label: 'repeated', keyType: undefined, jsonName: undefined, comment: undefined },
+      { name: 'ports', type: 'Port', label: undefined, keyType: 'string', jsonName: 'namedPorts', comment: undefined },
+    ]);
+    expect(spec.fields.map(jsonName)).toStrictEqual(['maxReplicas', 'tags', 'namedPorts', 'expiresAt', 'size', 'url', 'data']);
+  });
+
+  test('reports the line of syntax errors', () => {
+    expect(() => parseProto('syntax = "proto3";\n\nmessage Widget {\n  string name 1;\n}', 'widget.proto'))
+      .toThrow('widget.proto:4: expected "=" but found "1"');
+  });
+
+});
+
+test('generates constructs for messages with a spec', async () => {
+  fs.writeFileSync(path.join(workdir, 'widget.proto'), widget);
+  fs.writeFileSync(path.join(workdir, 'common.proto'), common);
+
+  const files = await loadProtoFiles(path.join(workdir, 'widget.proto'));
+  expect(files.map(f => path.basename(f.file))).toStrictEqual(['widget.proto', 'common.proto']);
+
+  const importer = new ImportProto(files);
+  expect(importer.moduleNames).toStrictEqual(['widgets.example.com']);
+
+  await importer.import({ targetLanguage: Language.TYPESCRIPT, outdir: workdir });
+  const output = fs.readFileSync(path.join(workdir, 'widgets.example.com.ts'), 'utf-8');
+
+  expect(output).toContain('export class Widget extends ApiObject');
+  expect(output).toContain('apiVersion: \'widgets.example.com/v1alpha1\'');
+  expect(output).toContain('readonly maxReplicas?: number;');
+  expect(output).toContain('readonly tags?: string[];');
+  expect(output).toContain('readonly namedPorts?: { [key: string]: WidgetSpecPort };');
+  expect(output).toContain('readonly expiresAt?: string;');
+  expect(output).toContain('readonly size?: WidgetSpecSize;');
+  expect(output).toContain('readonly url?: string;');
+  expect(output).not.toContain('export class WidgetSpec extends ApiObject');
+});
+
+test('comments cannot terminate the generated doc comments', async () => {
+  fs.writeFileSync(path.join(workdir, 'widget.proto'), [
+    'package widgets.example.com.v1;',
+    'message Widget {',
+    '  // The spec. */ export const injected = true; /*',
+    '  WidgetSpec spec = 1;',
+    '}',
+    'message WidgetSpec { string name = 1; }',
+  ].join('\n'));
+
+  const importer = new ImportProto(await loadProtoFiles(path.join(workdir, 'widget.proto')));
+  await importer.import({ targetLanguage: Language.TYPESCRIPT, outdir: workdir });
+  const output = fs.readFileSync(path.join(workdir, 'widgets.example.com.ts'), 'utf-8');
+
+  expect(output).toContain('The spec. _/ export const injected = true; /*');
+  expect(output).not.toContain('*/ export const injected');
+});
+
+test('unresolved types are represented as any', async () => {
+  fs.writeFileSync(path.join(workdir, 'widget.proto'), widget);
+
+  const importer = new ImportProto(await loadProtoFiles(path.join(workdir, 'widget.proto')));
+  await importer.import({ targetLanguage: Language.TYPESCRIPT, outdir: workdir });
+
+  expect(console.error).toHaveBeenCalledWith(expect.stringContaining('warning: unable to resolve import "common.proto"'));
+  expect(console.error).toHaveBeenCalledWith('warning: unknown type "common.ObjectMeta" of field "metadata" in message "widgets.example.com.v1alpha1.Widget" is represented as "any"');
+});
+
+test('the package must end with a version', () => {
+  expect(() => new ImportProto([{ file: path.join(process.cwd(), 'widget.proto'), content: 'package widgets;\nmessage Widget { string spec = 1; }' }]))
+    .toThrow('The package of widget.proto must be "<group>.<version>" (e.g. "widgets.example.com.v1"), but is "widgets"');
+});
+
+test('fails if there are no messages with a spec', () => {
+  expect(() => new ImportProto([{ file: path.join(process.cwd(), 'widget.proto'), content: 'package widgets.v1;\nmessage Widget { string name = 1; }' }]))
+    .toThrow('No custom resources found in widget.proto. Top-level messages with a "spec" field are imported as custom resources (kind = message name)');
+});
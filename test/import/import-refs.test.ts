@@ -0,0 +1,146 @@
+import { mocked } from 'ts-jest/utils';
+import { fetchRemoteReferences, ReferenceResolver, resolveLocation } from '../../src/import/refs';
+import { download } from '../../src/util';
+
+jest.mock('../../src/util', () => {
+  const mod = jest.requireActual('../../src/util');
+  return {
+    ...mod,
+    download: jest.fn(),
+  };
+});
+
+test('resolveLocation', () => {
+  expect(resolveLocation('/crds/foo.yaml', 'shared.yaml')).toBe('/crds/shared.yaml');
+  expect(resolveLocation('/crds/foo.yaml', '../common/shared.yaml')).toBe('/common/shared.yaml');
+  expect(resolveLocation('https://example.com/crds/foo.yaml', 'shared.yaml')).toBe('https://example.com/crds/shared.yaml');
+  expect(resolveLocation('/crds/foo.yaml', 'https://example.com/shared.yaml')).toBe('https://example.com/shared.yaml');
+});
+
+test('resolves local references', () => {
+  const doc = {
+    definitions: { Name: { type: 'string' } },
+    properties: { name: { $ref: '#/definitions/Name' } },
+  };
+
+  const resolver = new ReferenceResolver([{ location: '/crds/foo.yaml', documents: [doc] }]);
+  expect(resolver.resolve(doc, '/crds/foo.yaml').properties).toEqual({ name: { type: 'string' } });
+});
+
+test('resolves references across files and keeps sibling keys', () => {
+  const crd = {
+    properties: {
+      selector: { $ref: 'shared.yaml#/definitions/Selector', description: 'the selector' },
+    },
+  };
+
+  const shared = {
+    definitions: {
+      Labels: { type: 'object', additionalProperties: { type: 'string' } },
+      Selector: { type: 'object', properties: { matchLabels: { $ref: '#/definitions/Labels' } } },
+    },
+  };
+
+  const resolver = new ReferenceResolver([
+    { location: '/crds/foo.yaml', documents: [crd] },
+    { location: '/crds/shared.yaml', documents: [shared] },
+  ]);
+
+  expect(resolver.resolve(crd, '/crds/foo.yaml')).toEqual({
+    properties: {
+      selector: {
+        type: 'object',
+        description: 'the selector',
+        properties: {
+          matchLabels: { type: 'object', additionalProperties: { type: 'string' } },
+        },
+      },
+    },
+  });
+});
+
+test('fails on unknown files and definitions', () => {
+  const doc = { properties: { a: { $ref: 'missing.yaml#/definitions/A' }, b: { $ref: '#/definitions/B' } } };
+  const resolver = new ReferenceResolver([{ location: '/crds/foo.yaml', documents: [doc] }]);
+
+  expect(() => resolver.resolve(doc.properties.a, '/crds/foo.yaml', doc)).toThrow('Cannot resolve reference "missing.yaml#/definitions/A" in /crds/foo.yaml: /crds/missing.yaml is not one of the imported files');
+  expect(() => resolver.resolve(doc.properties.b, '/crds/foo.yaml', doc)).toThrow('Cannot resolve reference "#/definitions/B" in /crds/foo.yaml');
+});
+
+test('detects circular references between files', () => {
+  const a = { definitions: { A: { properties: { b: { $ref: 'b.yaml#/definitions/B' } } } } };
+  const b = { definitions: { B: { properties: { a: { $ref: 'a.yaml#/definitions/A' } } } } };
+
+  const resolver = new ReferenceResolver([
+    { location: '/crds/a.yaml', documents: [a] },
+    { location: '/crds/b.yaml', documents: [b] },
+  ]);
+
+  expect(() => resolver.resolve({ $ref: 'a.yaml#/definitions/A' }, '/crds/main.yaml'))
+    .toThrow('Circular $ref detected: /crds/a.yaml#/definitions/A -> /crds/b.yaml#/definitions/B -> /crds/a.yaml#/definitions/A');
+});
+
+describe('fetchRemoteReferences', () => {
+  const remote: Record<string, string> = {
+    'https://schemas.example.com/v1/common.yaml': JSON.stringify({
+      definitions: {
+        Selector: { $ref: 'labels.yaml#/definitions/Labels' },
+      },
+    }),
+    'https://schemas.example.com/v1/labels.yaml': JSON.stringify({
+      definitions: {
+        Labels: { type: 'object', additionalProperties: { type: 'string' } },
+      },
+    }),
+  };
+
+  beforeEach(() => {
+    mocked(download).mockImplementation(async url => {
+      if (!(url in remote)) {
+        throw new Error(`Not Found: ${url}`);
+      }
+      return remote[url];
+    });
+  });
+
+  test('fetches remote documents transitively and only once', async () => {
+    const file = {
+      location: '/crds/foo.yaml',
+      content: JSON.stringify({
+        properties: {
+          a: { $ref: 'https://schemas.example.com/v1/common.yaml#/definitions/Selector' },
+          b: { $ref: 'https://schemas.example.com/v1/common.yaml#/definitions/Selector' },
+        },
+      }),
+    };
+
+    const fetched = await fetchRemoteReferences([file]);
+    expect(fetched.map(f => f.location)).toEqual([
+      'https://schemas.example.com/v1/common.yaml',
+      'https://schemas.example.com/v1/labels.yaml',
+    ]);
+    expect(download).toHaveBeenCalledTimes(2);
+
+    const resolver = new ReferenceResolver([file, ...fetched].map(f => ({ location: f.location, documents: [JSON.parse(f.content)] })));
+    expect(resolver.resolve(JSON.parse(file.content), file.location).properties.a).toStrictEqual({
+      type: 'object',
+      additionalProperties: { type: 'string' },
+    });
+  });
+
+  test('only follows https urls', async () => {
+    const file = { location: '/crds/foo.yaml', content: JSON.stringify({ $ref: 'http://schemas.example.com/common.yaml#/definitions/A' }) };
+    await expect(fetchRemoteReferences([file])).rejects.toThrow('Cannot resolve reference "http://schemas.example.com/common.yaml#/definitions/A" in /crds/foo.yaml: only https URLs can be fetched');
+  });
+
+  test('fails if a document cannot be fetched', async () => {
+    const file = { location: '/crds/foo.yaml', content: JSON.stringify({ $ref: 'https://schemas.example.com/missing.yaml' }) };
+    await expect(fetchRemoteReferences([file])).rejects.toThrow('Unable to fetch reference "https://schemas.example.com/missing.yaml" in /crds/foo.yaml: Error: Not Found: https://schemas.example.com/missing.yaml');
+  });
+
+  test('ignores local references', async () => {
+    const file = { location: '/crds/foo.yaml', content: JSON.stringify({ a: { $ref: '#/definitions/A' }, b: { $ref: 'shared.yaml#/definitions/B' } }) };
+    expect(await fetchRemoteReferences([file])).toEqual([]);
+    expect(download).not.toHaveBeenCalled();
+  });
+});
@@ -0,0 +1,71 @@
+import { parseRenames, renameSchemaProperties } from '../../src/import/rename';
+
+const schema = {
+  type: 'object',
+  required: ['spec'],
+  properties: {
+    spec: {
+      type: 'object',
+      required: ['private_key'],
+      properties: {
+        private_key: { type: 'string' },
+        key: { type: 'string' },
+        tls: { type: 'array', items: { type: 'object', properties: { private_key: { type: 'string' } } } },
+      },
+    },
+  },
+};
+
+test('parses rename rules', () => {
+  expect(parseRenames(['WidgetSpec#private_key=secret', 'private_key=secret'])).toStrictEqual([
+    { type: 'WidgetSpec', property: 'private_key', name: 'secret' },
+    { type: undefined, property: 'private_key', name: 'secret' },
+  ]);
+});
+
+test('rejects invalid rename rules', () => {
+  expect(() => parseRenames(['private_key'])).toThrow('Invalid rename "private_key". Expected format "[TYPE#]PROPERTY=NAME"');
+  expect(() => parseRenames(['#private_key=secret'])).toThrow('Expected format "[TYPE#]PROPERTY=NAME"');
+  expect(() => parseRenames(['private_key=Secret'])).toThrow('The name must be camelCase');
+});
+
+test('renames the properties of nested schemas and returns their original names', () => {
+  const { schema: renamed, names } = renameSchemaProperties(schema, 'Widget', parseRenames(['WidgetSpec#private_key=secret', 'WidgetSpecTls#private_key=tlsKey']));
+
+  expect(renamed.properties!.spec).toStrictEqual({
+    type: 'object',
+    required: ['secret'],
+    properties: {
+      secret: { type: 'string' },
+      key: { type: 'string' },
+      tls: { type: 'array', items: { type: 'object', properties: { tlsKey: { type: 'string' } } } },
+    },
+  });
+  expect(names).toStrictEqual({
+    properties: {
+      spec: {
+        properties: {
+          secret: { name: 'private_key' },
+          tls: { items: { properties: { tlsKey: { name: 'private_key' } } } },
+        },
+      },
+    },
+  });
+
+  // the input is not modified
+  expect(Object.keys(schema.properties.spec.properties)).toEqual(['private_key', 'key', 'tls']);
+});
+
+test('the props can be referred to by their type name', () => {
+  const { names } = renameSchemaProperties(schema, 'Widget', parseRenames(['WidgetProps#spec=config']), ['WidgetProps']);
+  expect(names).toStrictEqual({ properties: { config: { name: 'spec' } } });
+});
+
+test('renames in other types are ignored', () => {
+  expect(renameSchemaProperties(schema, 'Widget', parseRenames(['OtherSpec#private_key=secret']))).toStrictEqual({ schema, names: undefined });
+});
+
+test('fails if the new name is already used', () => {
+  expect(() => renameSchemaProperties(schema, 'Widget', parseRenames(['WidgetSpec#private_key=key'])))
+    .toThrow('Cannot rename "WidgetSpec#private_key" to "key": a member with this name already exists');
+});
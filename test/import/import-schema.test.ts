@@ -0,0 +1,97 @@
+import { intOrString, mapSubSchemas, preserveUnknownFields, typedAdditionalProperties } from '../../src/import/schema';
+
+describe('preserveUnknownFields', () => {
+  test('free-form objects become open maps', () => {
+    expect(preserveUnknownFields({ 'description': 'config', 'x-kubernetes-preserve-unknown-fields': true })).toStrictEqual({
+      description: 'config',
+      type: 'object',
+      additionalProperties: {},
+    });
+  });
+
+  test('objects with properties are left as-is', () => {
+    const schema = { 'type': 'object', 'properties': { foo: { type: 'string' } }, 'x-kubernetes-preserve-unknown-fields': true };
+    expect(preserveUnknownFields(schema)).toBe(schema);
+  });
+
+  test('non-objects are left as-is', () => {
+    const schema = { 'type': 'array', 'x-kubernetes-preserve-unknown-fields': true };
+    expect(preserveUnknownFields(schema)).toBe(schema);
+  });
+});
+
+describe('intOrString', () => {
+  test('int-or-string values become a union of integer and string', () => {
+    expect(intOrString({ 'type': 'string', 'format': 'int-or-string', 'x-kubernetes-int-or-string': true })).toStrictEqual({
+      'anyOf': [{ type: 'integer' }, { type: 'string' }],
+      'x-kubernetes-int-or-string': true,
+    });
+  });
+
+  test('existing unions are left as-is', () => {
+    const schema = { 'anyOf': [{ type: 'string' }, { type: 'integer' }], 'x-kubernetes-int-or-string': true };
+    expect(intOrString(schema)).toBe(schema);
+  });
+
+  test('other schemas are left as-is', () => {
+    const schema = { type: 'string' };
+    expect(intOrString(schema)).toBe(schema);
+  });
+});
+
+describe('typedAdditionalProperties', () => {
+  test('objects with a schema of their values become typed maps', () => {
+    expect(typedAdditionalProperties({ description: 'limits', properties: {}, additionalProperties: { type: 'string' } })).toStrictEqual({
+      description: 'limits',
+      type: 'object',
+      additionalProperties: { type: 'string' },
+    });
+  });
+
+  test('values with properties are objects', () => {
+    expect(typedAdditionalProperties({ type: 'object', additionalProperties: { properties: { port: { type: 'integer' } } } })).toStrictEqual({
+      type: 'object',
+      additionalProperties: { type: 'object', properties: { port: { type: 'integer' } } },
+    });
+  });
+
+  test('objects with properties and untyped maps are left as-is', () => {
+    const struct = { type: 'object', properties: { foo: { type: 'string' } }, additionalProperties: { type: 'string' } };
+    expect(typedAdditionalProperties(struct)).toBe(struct);
+
+    const open = { type: 'object', additionalProperties: {} };
+    expect(typedAdditionalProperties(open)).toBe(open);
+
+    const closed = { type: 'object', additionalProperties: false };
+    expect(typedAdditionalProperties(closed)).toBe(closed);
+  });
+});
+
+test('mapSubSchemas transforms nested schemas but not the root', () => {
+  const free = { 'x-kubernetes-preserve-unknown-fields': true };
+  const schema = {
+    ...free,
+    type: 'object',
+    properties: {
+      a: free,
+      b: { type: 'array', items: free },
+      c: { type: 'object', additionalProperties: free },
+      d: { anyOf: [free, { type: 'string' }] },
+    },
+  };
+
+  const open = { type: 'object', additionalProperties: {} };
+  expect(mapSubSchemas(schema, preserveUnknownFields)).toStrictEqual({
+    ...free,
+    type: 'object',
+    properties: {
+      a: open,
+      b: { type: 'array', items: open },
+      c: { type: 'object', additionalProperties: open },
+      d: { anyOf: [open, { type: 'string' }] },
+    },
+  });
+
+  // the input is not modified
+  expect(schema.properties.a).toStrictEqual(free);
+});
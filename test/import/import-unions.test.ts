@@ -0,0 +1,83 @@
+import { numberOrStringUnions } from '../../src/import/unions';
+
+const union = (name: string) => [
+  `export class ${name} {`,
+  `  public static fromNumber(value: number): ${name} {`,
+  `    return new ${name}(value);`,
+  '  }',
+  `  public static fromString(value: string): ${name} {`,
+  `    return new ${name}(value);`,
+  '  }',
+  '  private constructor(public readonly value: any) {',
+  '  }',
+  '}',
+];
+
+test('number or string union classes are replaced with union types', () => {
+  const code = [
+    'export interface WidgetSpec {',
+    '  readonly port?: WidgetSpecPort;',
+    '  readonly limits?: { [key: string]: WidgetSpecLimits };',
+    '  readonly other?: WidgetSpecOther;',
+    '  readonly value?: WidgetSpecPort;',
+    '}',
+    '',
+    'export function toJson_WidgetSpec(obj: WidgetSpec | undefined): Record<string, any> | undefined {',
+    '  if (obj === undefined) { return undefined; }',
+    '  const result = {',
+    '    \'port\': obj.port?.value,',
+    '    \'limits\': ((obj.limits) === undefined) ? undefined : (Object.entries(obj.limits).reduce((r, i) => (i[1] === undefined) ? r : ({ ...r, [i[0]]: i[1]?.value }), {})),',
+    '    \'other\': obj.other?.value,',
+    '    \'value\': obj.value?.value,',
+    '  };',
+    '}',
+    '',
+    ...union('WidgetSpecPort'),
+    '',
+    ...union('WidgetSpecLimits'),
+    '',
+    'export class WidgetSpecOther {',
+    '  public static fromBoolean(value: boolean): WidgetSpecOther {',
+    '    return new WidgetSpecOther(value);',
+    '  }',
+    '  private constructor(public readonly value: any) {',
+    '  }',
+    '}',
+  ].join('\n');
+
+  expect(numberOrStringUnions(code)).toEqual([
+    'export interface WidgetSpec {',
+    '  readonly port?: WidgetSpecPort;',
+    '  readonly limits?: { [key: string]: WidgetSpecLimits };',
+    '  readonly other?: WidgetSpecOther;',
+    '  readonly value?: WidgetSpecPort;',
+    '}',
+    '',
+    'export function toJson_WidgetSpec(obj: WidgetSpec | undefined): Record<string, any> | undefined {',
+    '  if (obj === undefined) { return undefined; }',
+    '  const result = {',
+    '    \'port\': obj.port,',
+    '    \'limits\': ((obj.limits) === undefined) ? undefined : (Object.entries(obj.limits).reduce((r, i) => (i[1] === undefined) ? r : ({ ...r, [i[0]]: i[1] }), {})),',
+    '    \'other\': obj.other?.value,',
+    '    \'value\': obj.value,',
+    '  };',
+    '}',
+    '',
+    'export type WidgetSpecPort = number | string;',
+    '',
+    'export type WidgetSpecLimits = number | string;',
+    '',
+    'export class WidgetSpecOther {',
+    '  public static fromBoolean(value: boolean): WidgetSpecOther {',
+    '    return new WidgetSpecOther(value);',
+    '  }',
+    '  private constructor(public readonly value: any) {',
+    '  }',
+    '}',
+  ].join('\n'));
+});
+
+test('code without union classes is left as-is', () => {
+  const code = 'export interface WidgetSpec {\n  readonly port?: number;\n}';
+  expect(numberOrStringUnions(code)).toBe(code);
+});
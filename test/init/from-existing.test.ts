@@ -0,0 +1,187 @@
+import * as os from 'os';
+import * as path from 'path';
+import * as fs from 'fs-extra';
+import { Language } from '../../src/import/base';
+import { generateChartCode, readExistingManifests } from '../../src/init/from-existing';
+
+const schema = {
+  definitions: {
+    'io.k8s.api.core.v1.Service': {
+      'properties': {
+        apiVersion: { type: 'string' },
+        kind: { type: 'string' },
+        metadata: { $ref: '#/definitions/io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta' },
+        spec: { $ref: '#/definitions/io.k8s.api.core.v1.ServiceSpec' },
+      },
+      'x-kubernetes-group-version-kind': [{ group: '', kind: 'Service', version: 'v1' }],
+    },
+    'io.k8s.api.core.v1.ServiceSpec': {
+      properties: {
+        ports: { type: 'array', items: { $ref: '#/definitions/io.k8s.api.core.v1.ServicePort' } },
+      },
+    },
+    'io.k8s.api.core.v1.ServicePort': {
+      properties: {
+        port: { type: 'integer' },
+        targetPort: { $ref: '#/definitions/io.k8s.apimachinery.pkg.util.intstr.IntOrString' },
+      },
+    },
+    'io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta': {
+      properties: {
+        name: { type: 'string' },
+        labels: { type: 'object', additionalProperties: { type: 'string' } },
+      },
+    },
+    'io.k8s.apimachinery.pkg.util.intstr.IntOrString': { type: 'string', format: 'int-or-string' },
+  },
+};
+
+const resources = [
+  {
+    apiVersion: 'v1',
+    kind: 'Service',
+    metadata: { name: 'web', labels: { 'app.kubernetes.io/name': 'web' } },
+    spec: { ports: [{ port: 80, targetPort: 'http' }] },
+    status: { loadBalancer: {} },
+  },
+  {
+    apiVersion: 'foo.bar/v1',
+    kind: 'Widget',
+    metadata: { name: 'w' },
+    spec: { size: 3 },
+  },
+];
+
+describe('generateChartCode', () => {
+
+  test('typescript', () => {
+    const code = generateChartCode(Language.TYPESCRIPT, resources, schema);
+
+    expect(code.imports).toBe([
+      '',
+      'import { ApiObject } from \'cdk8s\';',
+      'import { IntOrString, KubeService } from \'./imports/k8s\';',
+    ].join('\n'));
+
+    expect(code.resources).toBe([
+      '    new KubeService(this, \'web\', {',
+      '      metadata: {',
+      '        name: \'web\',',
+      '        labels: {',
+      '          \'app.kubernetes.io/name\': \'web\',',
+      '        },',
+      '      },',
+      '      spec: {',
+      '        ports: [',
+      '          {',
+      '            port: 80,',
+      '            targetPort: IntOrString.fromString(\'http\'),',
+      '          },',
+      '        ],',
+      '      },',
+      '    });',
+      '',
+      '    new ApiObject(this, \'w\', {',
+      '      apiVersion: \'foo.bar/v1\',',
+      '      kind: \'Widget\',',
+      '      metadata: {',
+      '        name: \'w\',',
+      '      },',
+      '      spec: {',
+      '        size: 3,',
+      '      },',
+      '    });',
+      '',
+    ].join('\n'));
+  });
+
+  test('python', () => {
+    const code = generateChartCode(Language.PYTHON, resources, schema);
+
+    expect(code.imports).toBe([
+      '',
+      'from cdk8s import ApiObject, JsonPatch',
+      'from imports import k8s',
+    ].join('\n'));
+
+    expect(code.resources).toBe([
+      '        k8s.KubeService(',
+      '            self,',
+      '            "web",',
+      '            metadata={',
+      '                "name": "web",',
+      '                "labels": {',
+      '                    "app.kubernetes.io/name": "web",',
+      '                },',
+      '            },',
+      '            spec={',
+      '                "ports": [',
+      '                    {',
+      '                        "port": 80,',
+      '                        "target_port": k8s.IntOrString.from_string("http"),',
+      '                    },',
+      '                ],',
+      '            },',
+      '        )',
+      '',
+      '        w = ApiObject(',
+      '            self,',
+      '            "w",',
+      '            api_version="foo.bar/v1",',
+      '            kind="Widget",',
+      '            metadata={',
+      '                "name": "w",',
+      '            },',
+      '        )',
+      '        w.add_json_patch(JsonPatch.add("/spec", {',
+      '            "size": 3,',
+      '        }))',
+      '',
+    ].join('\n'));
+  });
+
+  test('all resources are api objects without a schema', () => {
+    const code = generateChartCode(Language.TYPESCRIPT, resources);
+    expect(code.imports).toBe('\nimport { ApiObject } from \'cdk8s\';');
+    expect(code.resources).not.toContain('KubeService');
+  });
+
+  test('construct ids are unique', () => {
+    const code = generateChartCode(Language.TYPESCRIPT, [
+      { apiVersion: 'v1', kind: 'Service', metadata: { name: 'web' } },
+      { apiVersion: 'apps/v1', kind: 'Deployment', metadata: { name: 'web' } },
+      { apiVersion: 'v1', kind: 'ConfigMap' },
+    ]);
+
+    expect(code.resources).toContain('new ApiObject(this, \'web\', {');
+    expect(code.resources).toContain('new ApiObject(this, \'web-deployment\', {');
+    expect(code.resources).toContain('new ApiObject(this, \'configmap\', {');
+  });
+
+  test('"$" is escaped in strings', () => {
+    const code = generateChartCode(Language.TYPESCRIPT, [
+      { apiVersion: 'v1', kind: 'ConfigMap', metadata: { name: 'config' }, data: { script: 'echo $1' } },
+    ]);
+
+    expect(code.resources).toContain('script: \'echo \\u00241\',');
+  });
+
+  test('fails for other languages', () => {
+    expect(() => generateChartCode(Language.GO, resources)).toThrow('--from-existing is not supported for go');
+  });
+
+});
+
+test('readExistingManifests reads all documents of a directory', async () => {
+  const dir = fs.mkdtempSync(path.join(os.tmpdir(), 'cdk8s-from-existing-test'));
+  try {
+    fs.writeFileSync(path.join(dir, 'a.yaml'), 'apiVersion: v1\nkind: Service\n---\napiVersion: v1\nkind: ConfigMap\n');
+    fs.mkdirpSync(path.join(dir, 'sub'));
+    fs.writeJsonSync(path.join(dir, 'sub', 'b.json'), { apiVersion: 'v1', kind: 'List', items: [{ apiVersion: 'v1', kind: 'Secret' }] });
+    fs.writeFileSync(path.join(dir, 'README.md'), '# not a manifest');
+
+    expect((await readExistingManifests(dir)).map(r => r.kind)).toStrictEqual(['Service', 'ConfigMap', 'Secret']);
+  } finally {
+    fs.removeSync(dir);
+  }
+});
@@ -0,0 +1,75 @@
+import * as os from 'os';
+import * as path from 'path';
+import * as fs from 'fs-extra';
+import { fetchTemplate } from '../../src/init/template';
+import { mkdtemp, shell } from '../../src/util';
+
+let tempDir: string;
+
+beforeEach(() => {
+  tempDir = fs.mkdtempSync(path.join(os.tmpdir(), 'cdk8s-template-test'));
+});
+
+afterEach(() => {
+  fs.removeSync(tempDir);
+});
+
+// creates a template archive with the files (relative path => content)
+async function archive(name: string, files: Record<string, string>) {
+  const dir = path.join(tempDir, 'src');
+  for (const [file, content] of Object.entries(files)) {
+    await fs.outputFile(path.join(dir, file), content);
+  }
+
+  const file = path.join(tempDir, name);
+  await shell('tar', ['-czf', file, '-C', dir, '.']);
+  await fs.remove(dir);
+  return file;
+}
+
+test('fetches the template of an archive', async () => {
+  const file = await archive('template.tgz', {
+    'cdk8s.yaml': 'language: typescript\napp: npx ts-node main.ts\n',
+    'main.ts': '// {{ $base }}\n',
+  });
+
+  await mkdtemp(async workdir => {
+    const template = await fetchTemplate(file, workdir);
+    expect(template.language).toBe('typescript');
+    expect(fs.readFileSync(path.join(template.dir, 'main.ts'), 'utf-8')).toBe('// {{ $base }}\n');
+  });
+});
+
+test('an archive with a single directory contains the template in that directory', async () => {
+  const file = await archive('template.tar.gz', {
+    'templates-1.0.0/cdk8s.yaml': 'language: python\n',
+  });
+
+  await mkdtemp(async workdir => {
+    const template = await fetchTemplate(file, workdir);
+    expect(template.language).toBe('python');
+    expect(path.basename(template.dir)).toBe('templates-1.0.0');
+  });
+});
+
+test('templates must contain a cdk8s.yaml', async () => {
+  const file = await archive('template.tgz', { 'main.ts': '' });
+
+  await mkdtemp(async workdir => {
+    await expect(fetchTemplate(file, workdir)).rejects.toThrow(`Invalid template ${file}: missing cdk8s.yaml`);
+  });
+});
+
+test('the language of the template must be supported', async () => {
+  const file = await archive('template.tgz', { 'cdk8s.yaml': 'language: cobol\n' });
+
+  await mkdtemp(async workdir => {
+    await expect(fetchTemplate(file, workdir)).rejects.toThrow(`Invalid template ${file}: "language" of cdk8s.yaml must be one of typescript, python, dotnet, java, go (got "cobol")`);
+  });
+});
+
+test('templates must be archives or git repositories', async () => {
+  await mkdtemp(async workdir => {
+    await expect(fetchTemplate('https://example.com/template.zip', workdir)).rejects.toThrow('Unsupported template https://example.com/template.zip');
+  });
+});
@@ -32,16 +32,31 @@ test('python-app', () => {
   init('python-app');
 });
 
+test('typescript-monorepo', () => {
+  init('typescript-monorepo');
+});
+
+test('python-monorepo', () => {
+  init('python-monorepo');
+});
+
+test('go-monorepo', () => {
+  init('go-monorepo');
+});
+
 function init(template: string) {
 
   const workdir = mkdtempSync(join(tmpdir(), 'cdk8s-init-test-'));
-  execSync(`${clidir}/node_modules/.bin/cdk8s init ${template}`, {
+  const stdout = execSync(`${clidir}/node_modules/.bin/cdk8s init ${template}`, {
     cwd: workdir,
     env: {
       ...process.env,
       CDK8S_TARBALL: cli,
     },
-    stdio: ['inherit', 'inherit', 'inherit'],
+    stdio: ['inherit', 'pipe', 'inherit'],
   });
 
+  // progress (including the output of the hooks) is written to STDERR
+  expect(stdout.toString()).toBe('');
+
 }
@@ -0,0 +1,62 @@
+import { getLogLevel, logger, LogLevel, LogSink, setLogLevel, setLogSink } from '../src/logger';
+
+let messages: Array<[LogLevel, string]>;
+let previousSink: LogSink;
+const previousLevel = getLogLevel();
+
+beforeEach(() => {
+  messages = [];
+  previousSink = setLogSink((level, message) => messages.push([level, message]));
+});
+
+afterEach(() => {
+  setLogSink(previousSink);
+  setLogLevel(previousLevel);
+});
+
+function logAll() {
+  logger.error('failed');
+  logger.warn('careful');
+  logger.info('progress');
+  logger.debug('details');
+}
+
+test('info is the default level', () => {
+  expect(getLogLevel()).toBe(LogLevel.INFO);
+
+  logAll();
+
+  expect(messages).toStrictEqual([
+    [LogLevel.ERROR, 'failed'],
+    [LogLevel.WARN, 'warning: careful'],
+    [LogLevel.INFO, 'progress'],
+  ]);
+});
+
+test.each([
+  [LogLevel.ERROR, ['failed']],
+  [LogLevel.WARN, ['failed', 'warning: careful']],
+  [LogLevel.DEBUG, ['failed', 'warning: careful', 'progress', 'details']],
+])('level %s', (level, expected) => {
+  setLogLevel(level);
+  logAll();
+  expect(messages.map(([, message]) => message)).toStrictEqual(expected);
+});
+
+test('invalid levels are rejected', () => {
+  expect(() => setLogLevel('verbose' as LogLevel)).toThrow('Invalid log level "verbose". Supported levels are error, warn, info, debug');
+});
+
+test('messages are written to stderr by default', () => {
+  setLogSink(previousSink);
+  const error = jest.spyOn(console, 'error').mockImplementation(() => undefined);
+  const log = jest.spyOn(console, 'log').mockImplementation(() => undefined);
+  try {
+    logger.info('progress');
+    expect(error).toHaveBeenCalledWith('progress');
+    expect(log).not.toHaveBeenCalled();
+  } finally {
+    error.mockRestore();
+    log.mockRestore();
+  }
+});
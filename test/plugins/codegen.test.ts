@@ -0,0 +1,113 @@
+import * as os from 'os';
+import * as path from 'path';
+import * as fs from 'fs-extra';
+import { Language } from '../../src/import/base';
+import { ImportCustomResourceDefinition } from '../../src/import/crd';
+import { loadCodegenHooks, runCodegenHooks } from '../../src/plugins/codegen';
+
+const LICENSE_HOOK = `
+exports.transform = (file, contents) => '// Copyright Acme\\n' + contents;
+`;
+
+const PRAGMA_HOOK = `
+module.exports = (file, contents) => file.endsWith('.ts') ? '/* eslint-disable */\\n' + contents : contents;
+`;
+
+let workdir: string;
+
+beforeEach(() => {
+  workdir = fs.mkdtempSync(path.join(os.tmpdir(), 'cdk8s-codegen-test'));
+  fs.writeFileSync(path.join(workdir, 'license.js'), LICENSE_HOOK);
+  fs.writeFileSync(path.join(workdir, 'pragma.js'), PRAGMA_HOOK);
+});
+
+afterEach(() => {
+  fs.removeSync(workdir);
+});
+
+test('runs hooks in order against all emitted files', async () => {
+  const hooks = loadCodegenHooks([path.join(workdir, 'license.js'), path.join(workdir, 'pragma.js')]);
+
+  fs.outputFileSync(path.join(workdir, 'imports', 'k8s.ts'), 'export class Foo {}\n');
+  fs.outputFileSync(path.join(workdir, 'imports', 'pkg', '__init__.py'), 'class Foo: pass\n');
+  fs.outputFileSync(path.join(workdir, 'imports', 'pkg', '_jsii', 'pkg@0.0.0.jsii.tgz'), 'binary');
+
+  await runCodegenHooks(hooks, [path.join(workdir, 'imports', 'k8s.ts'), path.join(workdir, 'imports', 'pkg')]);
+
+  expect(fs.readFileSync(path.join(workdir, 'imports', 'k8s.ts'), 'utf-8')).toEqual('/* eslint-disable */\n// Copyright Acme\nexport class Foo {}\n');
+  expect(fs.readFileSync(path.join(workdir, 'imports', 'pkg', '__init__.py'), 'utf-8')).toEqual('// Copyright Acme\nclass Foo: pass\n');
+  expect(fs.readFileSync(path.join(workdir, 'imports', 'pkg', '_jsii', 'pkg@0.0.0.jsii.tgz'), 'utf-8')).toEqual('binary');
+});
+
+test('hooks receive the path relative to the project directory', async () => {
+  const files = new Array<string>();
+  fs.outputFileSync(path.join(workdir, 'imports', 'k8s.ts'), '');
+
+  const cwd = process.cwd();
+  process.chdir(workdir);
+  try {
+    await runCodegenHooks([(file, contents) => { files.push(file); return contents; }], [path.join(workdir, 'imports')]);
+  } finally {
+    process.chdir(cwd);
+  }
+
+  expect(files).toEqual([path.join('imports', 'k8s.ts')]);
+});
+
+test('fails if the module does not export a transform function', () => {
+  fs.writeFileSync(path.join(workdir, 'invalid.js'), 'exports.foo = 1;');
+  expect(() => loadCodegenHooks([path.join(workdir, 'invalid.js')])).toThrow(`Codegen hook "${path.join(workdir, 'invalid.js')}" must export a "transform" function`);
+});
+
+test('fails if a hook does not return a string', async () => {
+  fs.outputFileSync(path.join(workdir, 'imports', 'k8s.ts'), '');
+  await expect(runCodegenHooks([() => undefined as any], [path.join(workdir, 'imports')]))
+    .rejects.toThrow(`Codegen hook returned undefined instead of a string for ${path.relative(process.cwd(), path.join(workdir, 'imports', 'k8s.ts'))}`);
+});
+
+test('import applies hooks to the generated files', async () => {
+  const crd = {
+    apiVersion: 'apiextensions.k8s.io/v1beta1',
+    kind: 'CustomResourceDefinition',
+    spec: { version: 'v1', group: 'foo.bar', names: { kind: 'Widget' } },
+  };
+  fs.writeFileSync(path.join(workdir, 'crd.json'), JSON.stringify(crd));
+
+  const importer = await ImportCustomResourceDefinition.fromSpec({ source: path.join(workdir, 'crd.json') });
+  await importer.import({
+    targetLanguage: Language.TYPESCRIPT,
+    outdir: path.join(workdir, 'imports'),
+    codegenHooks: loadCodegenHooks([path.join(workdir, 'license.js')]),
+  });
+
+  expect(fs.readFileSync(path.join(workdir, 'imports', 'foo.bar.ts'), 'utf-8')).toMatch(/^\/\/ Copyright Acme\n\/\/ generated by cdk8s\n/);
+});
+
+test('import applies hooks to the files of all modules', async () => {
+  const crd = (group: string) => ({
+    apiVersion: 'apiextensions.k8s.io/v1beta1',
+    kind: 'CustomResourceDefinition',
+    spec: { version: 'v1', group, names: { kind: 'Widget' } },
+  });
+  fs.writeFileSync(path.join(workdir, 'crds.json'), JSON.stringify({ apiVersion: 'v1', kind: 'List', items: [crd('foo.bar'), crd('baz.qux')] }));
+
+  const importer = await ImportCustomResourceDefinition.fromSpec({ source: path.join(workdir, 'crds.json') });
+  await importer.import({
+    targetLanguage: Language.TYPESCRIPT,
+    outdir: path.join(workdir, 'imports'),
+    codegenHooks: loadCodegenHooks([path.join(workdir, 'license.js')]),
+  });
+
+  for (const file of ['baz.qux.ts', 'foo.bar.ts']) {
+    expect(fs.readFileSync(path.join(workdir, 'imports', file), 'utf-8')).toMatch(/^\/\/ Copyright Acme\n\/\/ generated by cdk8s\n/);
+  }
+});
+
+test('files in nested paths are transformed once', async () => {
+  fs.outputFileSync(path.join(workdir, 'imports', 'pkg', 'sub', '__init__.py'), 'class Foo: pass\n');
+  const hooks = loadCodegenHooks([path.join(workdir, 'license.js')]);
+
+  await runCodegenHooks(hooks, [path.join(workdir, 'imports', 'pkg'), path.join(workdir, 'imports', 'pkg', 'sub')]);
+
+  expect(fs.readFileSync(path.join(workdir, 'imports', 'pkg', 'sub', '__init__.py'), 'utf-8')).toEqual('// Copyright Acme\nclass Foo: pass\n');
+});
@@ -0,0 +1,78 @@
+import * as os from 'os';
+import * as path from 'path';
+import * as fs from 'fs-extra';
+import { loadSynthPlugins, runSynthPlugins } from '../../src/plugins/transform';
+
+const LABELS_PLUGIN = `
+exports.transform = resources => {
+  for (const resource of resources) {
+    resource.metadata.labels = { ...resource.metadata.labels, team: 'platform' };
+  }
+};
+`;
+
+const REGISTRY_PLUGIN = `
+module.exports = resources => resources
+  .filter(r => r.kind !== 'Secret')
+  .map(r => ({ ...r, image: r.image && r.image.replace('docker.io/', 'registry.acme.com/') }));
+`;
+
+const configMap = (name: string) => ({ apiVersion: 'v1', kind: 'ConfigMap', metadata: { name } });
+
+let dir: string;
+
+beforeEach(() => {
+  dir = fs.mkdtempSync(path.join(os.tmpdir(), 'cdk8s-synth-plugins-'));
+});
+
+afterEach(() => {
+  fs.removeSync(dir);
+});
+
+test('loads the plugins in the order of their file names', async () => {
+  fs.writeFileSync(path.join(dir, '20-registry.js'), REGISTRY_PLUGIN);
+  fs.writeFileSync(path.join(dir, '10-labels.js'), LABELS_PLUGIN);
+  fs.writeFileSync(path.join(dir, 'helpers.js'), 'exports.registry = "registry.acme.com";');
+  fs.writeFileSync(path.join(dir, 'README.md'), '# plugins');
+
+  const plugins = await loadSynthPlugins(dir);
+  expect(plugins.map(p => p.name)).toEqual(['10-labels.js', '20-registry.js']);
+});
+
+test('fails if the plugin directory does not exist', async () => {
+  await expect(loadSynthPlugins(path.join(dir, 'missing'))).rejects.toThrow(`Plugin directory ${path.join(dir, 'missing')} does not exist`);
+});
+
+test('plugins modify and remove resources in order', async () => {
+  fs.writeFileSync(path.join(dir, '10-labels.js'), LABELS_PLUGIN);
+  fs.writeFileSync(path.join(dir, '20-registry.js'), REGISTRY_PLUGIN);
+
+  const manifests = await runSynthPlugins(await loadSynthPlugins(dir), [
+    { file: 'web.k8s.yaml', resources: [{ ...configMap('web'), image: 'docker.io/nginx' }, { apiVersion: 'v1', kind: 'Secret', metadata: { name: 'token' } }] },
+    { file: 'db.k8s.yaml', resources: [configMap('db')] },
+  ]);
+
+  expect(manifests).toEqual([
+    { file: 'web.k8s.yaml', resources: [{ ...configMap('web'), metadata: { name: 'web', labels: { team: 'platform' } }, image: 'registry.acme.com/nginx' }] },
+    { file: 'db.k8s.yaml', resources: [{ ...configMap('db'), metadata: { name: 'db', labels: { team: 'platform' } }, image: undefined }] },
+  ]);
+});
+
+test('new resources are added to the manifest of the resource before them', async () => {
+  const manifests = await runSynthPlugins([
+    { name: 'add.js', transform: resources => [configMap('first'), ...resources.flatMap(r => [r, configMap(`${r.metadata.name}-config`)])] },
+  ], [
+    { file: 'web.k8s.yaml', resources: [configMap('web')] },
+    { file: 'db.k8s.yaml', resources: [configMap('db')] },
+  ]);
+
+  expect(manifests).toEqual([
+    { file: 'web.k8s.yaml', resources: [configMap('first'), configMap('web'), configMap('web-config')] },
+    { file: 'db.k8s.yaml', resources: [configMap('db'), configMap('db-config')] },
+  ]);
+});
+
+test('fails if a plugin does not return resources', async () => {
+  await expect(runSynthPlugins([{ name: 'invalid.js', transform: () => 'foo' as any }], [{ file: 'web.k8s.yaml', resources: [] }]))
+    .rejects.toThrow('Synth plugin "invalid.js" returned string instead of an array of resources');
+});
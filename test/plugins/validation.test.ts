@@ -0,0 +1,80 @@
+import * as os from 'os';
+import * as path from 'path';
+import * as fs from 'fs-extra';
+import { findViolations, validateManifests, ValidationSeverity } from '../../src/plugins/validation';
+
+const PLUGIN = `
+class NoLatestTag {
+  constructor(props) {
+    this.severity = props.severity;
+  }
+
+  validate(context) {
+    for (const manifest of context.manifests) {
+      context.report({
+        ruleName: 'no-latest-tag',
+        message: 'images must not use the "latest" tag',
+        severity: this.severity,
+        resources: [{ manifestPath: manifest }],
+      });
+    }
+  }
+}
+
+module.exports = { NoLatestTag };
+`;
+
+let workdir: string;
+let plugin: string;
+
+beforeEach(() => {
+  workdir = fs.mkdtempSync(path.join(os.tmpdir(), 'cdk8s-validation-test'));
+  plugin = path.join(workdir, 'plugin.js');
+  fs.writeFileSync(plugin, PLUGIN);
+});
+
+afterEach(() => {
+  fs.removeSync(workdir);
+});
+
+test('aggregates the reports of all plugins', async () => {
+  const reports = await validateManifests([
+    { package: plugin, class: 'NoLatestTag', properties: { severity: 'low' } },
+    { package: plugin, class: 'NoLatestTag', properties: { severity: 'high' } },
+  ], ['dist/chart.k8s.yaml']);
+
+  expect(reports).toEqual([
+    {
+      plugin: `${plugin}#NoLatestTag`,
+      violations: [{
+        ruleName: 'no-latest-tag',
+        message: 'images must not use the "latest" tag',
+        severity: 'low',
+        resources: [{ manifestPath: 'dist/chart.k8s.yaml' }],
+      }],
+    },
+    {
+      plugin: `${plugin}#NoLatestTag`,
+      violations: [{
+        ruleName: 'no-latest-tag',
+        message: 'images must not use the "latest" tag',
+        severity: 'high',
+        resources: [{ manifestPath: 'dist/chart.k8s.yaml' }],
+      }],
+    },
+  ]);
+
+  expect(findViolations(reports, ValidationSeverity.LOW).length).toBe(2);
+  expect(findViolations(reports, ValidationSeverity.HIGH).length).toBe(1);
+  expect(findViolations(reports, ValidationSeverity.CRITICAL).length).toBe(0);
+});
+
+test('fails if the plugin class does not exist', async () => {
+  await expect(() => validateManifests([{ package: plugin, class: 'Missing' }], []))
+    .rejects.toThrow(`Unable to find class "Missing" in validation plugin "${plugin}"`);
+});
+
+test('fails if a plugin reports an invalid severity', async () => {
+  await expect(() => validateManifests([{ package: plugin, class: 'NoLatestTag', properties: { severity: 'severe' } }], ['chart.k8s.yaml']))
+    .rejects.toThrow(`Validation plugin "${plugin}#NoLatestTag" reported an invalid severity "severe"`);
+});
@@ -0,0 +1,75 @@
+import * as os from 'os';
+import * as path from 'path';
+import * as fs from 'fs-extra';
+import { prepareServerSideApply, writeApplyScript } from '../../src/synth/apply';
+import { Manifest } from '../../src/synth/manifests';
+
+test('prepareServerSideApply removes the last-applied-configuration annotation', () => {
+  const manifests: Manifest[] = [{
+    file: 'chart.k8s.yaml',
+    resources: [
+      {
+        apiVersion: 'v1',
+        kind: 'ConfigMap',
+        metadata: { name: 'only', annotations: { 'kubectl.kubernetes.io/last-applied-configuration': '{}' } },
+      },
+      {
+        apiVersion: 'v1',
+        kind: 'ConfigMap',
+        metadata: { name: 'other', annotations: { 'kubectl.kubernetes.io/last-applied-configuration': '{}', 'foo': 'bar' } },
+      },
+      {
+        apiVersion: 'v1',
+        kind: 'ConfigMap',
+        metadata: { name: 'none' },
+      },
+    ],
+  }];
+
+  prepareServerSideApply(manifests);
+
+  expect(manifests[0].resources.map(r => r.metadata)).toEqual([
+    { name: 'only' },
+    { name: 'other', annotations: { foo: 'bar' } },
+    { name: 'none' },
+  ]);
+});
+
+test('writeApplyScript applies manifests in order', async () => {
+  const outdir = fs.mkdtempSync(path.join(os.tmpdir(), 'cdk8s-apply-test'));
+  try {
+    await writeApplyScript(outdir, [
+      { file: '0000-first.k8s.yaml', resources: [] },
+      { file: '0001-second.k8s.yaml', resources: [] },
+    ], 'my-manager');
+
+    expect(fs.readFileSync(path.join(outdir, 'apply.sh'), 'utf-8')).toEqual([
+      '#!/bin/sh',
+      '# generated by cdk8s',
+      'set -e',
+      'cd "$(dirname "$0")"',
+      'kubectl apply --server-side --field-manager=my-manager -f "0000-first.k8s.yaml"',
+      'kubectl apply --server-side --field-manager=my-manager -f "0001-second.k8s.yaml"',
+      '',
+    ].join('\n'));
+  } finally {
+    fs.removeSync(outdir);
+  }
+});
+
+test('writeApplyScript decompresses compressed manifests', async () => {
+  const outdir = fs.mkdtempSync(path.join(os.tmpdir(), 'cdk8s-apply-test'));
+  try {
+    await writeApplyScript(outdir, [{ file: '0000-first.k8s.yaml.gz', resources: [] }], 'my-manager');
+
+    expect(fs.readFileSync(path.join(outdir, 'apply.sh'), 'utf-8')).toContain(
+      'gunzip -c "0000-first.k8s.yaml.gz" | kubectl apply --server-side --field-manager=my-manager -f -\n');
+  } finally {
+    fs.removeSync(outdir);
+  }
+});
+
+test('writeApplyScript fails for an invalid field manager', async () => {
+  await expect(() => writeApplyScript(os.tmpdir(), [], 'my manager; rm -rf /'))
+    .rejects.toThrow('Invalid field manager "my manager; rm -rf /"');
+});
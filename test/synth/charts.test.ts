@@ -0,0 +1,38 @@
+import * as os from 'os';
+import * as path from 'path';
+import * as fs from 'fs-extra';
+import { chartId, filterChart } from '../../src/synth/charts';
+
+let outdir: string;
+
+beforeEach(() => {
+  outdir = fs.mkdtempSync(path.join(os.tmpdir(), 'cdk8s-charts-'));
+});
+
+afterEach(() => {
+  fs.removeSync(outdir);
+});
+
+test('chart ids are derived from the output files', () => {
+  expect(chartId('my-chart.k8s.yaml')).toEqual('my-chart');
+  expect(chartId('0001-my-chart.k8s.yaml')).toEqual('my-chart');
+  expect(chartId('0001-my-chart')).toEqual('my-chart');
+  expect(chartId('0001-my-chart.k8s.jsonl.gz')).toEqual('my-chart');
+});
+
+test('only the output of the chart is kept', async () => {
+  fs.outputFileSync(path.join(outdir, '0000-first.k8s.yaml'), '');
+  fs.outputFileSync(path.join(outdir, '0001-second.k8s.yaml'), '');
+  fs.outputFileSync(path.join(outdir, '0002-third', 'Deployment.third.k8s.yaml'), '');
+
+  expect(await filterChart(outdir, 'third')).toEqual([`${outdir}/0002-third/Deployment.third.k8s.yaml`]);
+  expect(fs.readdirSync(outdir)).toEqual(['0002-third']);
+});
+
+test('fails if no chart matches', async () => {
+  fs.outputFileSync(path.join(outdir, '0000-first.k8s.yaml'), '');
+  fs.outputFileSync(path.join(outdir, '0001-second.k8s.yaml'), '');
+
+  await expect(filterChart(outdir, 'third')).rejects.toThrow('No chart with id "third" found. Available charts: "first", "second"');
+  expect(fs.readdirSync(outdir)).toEqual(['0000-first.k8s.yaml', '0001-second.k8s.yaml']);
+});
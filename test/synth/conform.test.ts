@@ -0,0 +1,149 @@
+import * as os from 'os';
+import * as path from 'path';
+import * as fs from 'fs-extra';
+import * as yaml from 'yaml';
+import { conformManifests, importedCrds, kubernetesVersion, schemaFile } from '../../src/synth/conform';
+import { download, DownloadError } from '../../src/util';
+
+jest.mock('../../src/util', () => ({
+  ...jest.requireActual('../../src/util'),
+  download: jest.fn(),
+}));
+
+const mockDownload = download as jest.MockedFunction<typeof download>;
+
+const deploymentSchema = {
+  $schema: 'http://json-schema.org/schema#',
+  type: 'object',
+  required: ['apiVersion', 'kind', 'metadata'],
+  properties: {
+    apiVersion: { type: 'string' },
+    kind: { type: 'string' },
+    metadata: { type: 'object' },
+    spec: {
+      type: 'object',
+      additionalProperties: false,
+      properties: {
+        replicas: { type: 'integer', format: 'int32' },
+      },
+    },
+  },
+};
+
+const crd = {
+  apiVersion: 'apiextensions.k8s.io/v1',
+  kind: 'CustomResourceDefinition',
+  metadata: { name: 'widgets.example.com' },
+  spec: {
+    group: 'example.com',
+    names: { kind: 'Widget' },
+    versions: [{
+      name: 'v1',
+      schema: {
+        openAPIV3Schema: {
+          type: 'object',
+          properties: { spec: { type: 'object', properties: { size: { type: 'string', 'x-kubernetes-int-or-string': true } } } },
+        },
+      },
+    }],
+  },
+};
+
+const deployment = (spec: any) => ({ apiVersion: 'apps/v1', kind: 'Deployment', metadata: { name: 'web' }, spec });
+
+let cacheDir: string;
+
+beforeEach(() => {
+  cacheDir = fs.mkdtempSync(path.join(os.tmpdir(), 'conform-test'));
+  fs.outputJsonSync(path.join(cacheDir, 'v1.22.0-standalone-strict', 'deployment-apps-v1.json'), deploymentSchema);
+
+  // only the cached schemas exist
+  mockDownload.mockReset();
+  mockDownload.mockImplementation(async (url: string) => {
+    if (url.startsWith('https://')) {
+      throw new DownloadError(`Not Found: ${url}`, false, 404);
+    }
+    return fs.readFile(url, 'utf-8');
+  });
+});
+
+afterEach(() => {
+  fs.removeSync(cacheDir);
+});
+
+test('the kubernetes version is the version of the k8s import', () => {
+  expect(kubernetesVersion(['crd.yaml', 'k8s@1.25.0'])).toBe('1.25.0');
+  expect(kubernetesVersion(['kube:=k8s@1.24.1'])).toBe('1.24.1');
+  expect(kubernetesVersion(['k8s'])).toBe('1.22.0');
+  expect(kubernetesVersion()).toBe('1.22.0');
+});
+
+test('schema files are named like kubeconform', () => {
+  expect(schemaFile('apps/v1', 'Deployment')).toBe('deployment-apps-v1.json');
+  expect(schemaFile('networking.k8s.io/v1', 'Ingress')).toBe('ingress-networking-v1.json');
+  expect(schemaFile('v1', 'ConfigMap')).toBe('configmap-v1.json');
+});
+
+test('reports the resources that do not match their schema', async () => {
+  const report = await conformManifests([
+    { file: 'app.k8s.yaml', resources: [deployment({ replicas: 2 }), { ...deployment({ replicas: 'two', extra: true }), metadata: { name: 'api' } }] },
+  ], { kubernetesVersion: '1.22.0', cacheDir });
+
+  expect(report.valid).toBe(1);
+  expect(report.skipped).toEqual([]);
+  expect(report.violations).toEqual([{
+    file: 'app.k8s.yaml',
+    resource: 'apps/v1/Deployment//api',
+    errors: ['/spec must NOT have additional properties', '/spec/replicas must be integer'],
+  }]);
+});
+
+test('built-in resources without a schema are invalid', async () => {
+  const report = await conformManifests([
+    { file: 'app.k8s.yaml', resources: [{ apiVersion: 'v1', kind: 'Unknown', metadata: { name: 'foo' } }] },
+  ], { kubernetesVersion: '1.22.0', cacheDir });
+
+  expect(report.violations).toEqual([{
+    file: 'app.k8s.yaml',
+    resource: 'v1/Unknown//foo',
+    errors: ['there is no schema of v1/Unknown in Kubernetes 1.22.0'],
+  }]);
+});
+
+test('fails if a schema cannot be downloaded', async () => {
+  mockDownload.mockRejectedValue(new Error('getaddrinfo ENOTFOUND raw.githubusercontent.com'));
+
+  await expect(conformManifests([
+    { file: 'app.k8s.yaml', resources: [{ apiVersion: 'v1', kind: 'ConfigMap', metadata: { name: 'foo' } }] },
+  ], { kubernetesVersion: '1.22.0', cacheDir })).rejects.toThrow('Cannot download the schema of v1/ConfigMap from https://raw.githubusercontent.com/yannh/kubernetes-json-schema/master/v1.22.0-standalone-strict/configmap-v1.json: getaddrinfo ENOTFOUND raw.githubusercontent.com');
+});
+
+test('custom resources are validated against the schema of their CRD', async () => {
+  const widget = (size: any) => ({ apiVersion: 'example.com/v1', kind: 'Widget', metadata: { name: `size-${size}` }, spec: { size } });
+
+  const report = await conformManifests([
+    { file: 'app.k8s.yaml', resources: [widget('large'), widget(false), { apiVersion: 'other.com/v1', kind: 'Gadget', metadata: { name: 'g' } }] },
+  ], { kubernetesVersion: '1.22.0', cacheDir, crds: [crd] });
+
+  expect(report.valid).toBe(1);
+  expect(report.violations.map(v => v.resource)).toEqual(['example.com/v1/Widget//size-false']);
+  expect(report.skipped).toEqual(['other.com/v1/Gadget//g']);
+});
+
+test('downloaded schemas are cached', async () => {
+  fs.removeSync(cacheDir);
+  mockDownload.mockResolvedValue(JSON.stringify(deploymentSchema));
+
+  await conformManifests([{ file: 'app.k8s.yaml', resources: [deployment({ replicas: 1 })] }], { kubernetesVersion: '1.22.0', cacheDir });
+
+  expect(mockDownload).toHaveBeenCalledWith('https://raw.githubusercontent.com/yannh/kubernetes-json-schema/master/v1.22.0-standalone-strict/deployment-apps-v1.json');
+  expect(fs.readJsonSync(path.join(cacheDir, 'v1.22.0-standalone-strict', 'deployment-apps-v1.json'))).toEqual(deploymentSchema);
+});
+
+test('CRDs are imported from local files', async () => {
+  const file = path.join(cacheDir, 'widget.crd.yaml');
+  fs.writeFileSync(file, yaml.stringify(crd));
+
+  const crds = await importedCrds(['k8s', `widgets:=${file}`, 'https://example.com/crd.yaml']);
+  expect(crds.map(c => c.spec.names.kind)).toEqual(['Widget']);
+});
@@ -0,0 +1,38 @@
+import { mocked } from 'ts-jest/utils';
+import { listContexts } from '../../src/kubectl';
+import { resolveContext } from '../../src/synth/contexts';
+
+jest.mock('../../src/kubectl', () => {
+  const mod = jest.requireActual('../../src/kubectl');
+  return {
+    ...mod,
+    listContexts: jest.fn(),
+  };
+});
+
+const contexts = {
+  staging: 'arn:aws:eks:us-east-1:111111111111:cluster/staging',
+  prod: 'prod-admin',
+};
+
+test('resolves the kubeconfig context', async () => {
+  mocked(listContexts).mockResolvedValue(['prod-admin', 'arn:aws:eks:us-east-1:111111111111:cluster/staging']);
+
+  expect(await resolveContext('staging', contexts)).toStrictEqual({
+    CDK8S_CONTEXT: 'staging',
+    CDK8S_KUBE_CONTEXT: 'arn:aws:eks:us-east-1:111111111111:cluster/staging',
+  });
+});
+
+test('fails if the context is not defined', async () => {
+  await expect(resolveContext('dev', contexts))
+    .rejects.toThrow('Context "dev" is not defined in the "contexts" section of cdk8s.yaml. Available contexts: "prod", "staging"');
+  expect(listContexts).not.toHaveBeenCalled();
+});
+
+test('fails if the kubeconfig context does not exist', async () => {
+  mocked(listContexts).mockResolvedValue(['staging-admin']);
+
+  await expect(resolveContext('prod', contexts))
+    .rejects.toThrow('Context "prod" refers to kubeconfig context "prod-admin", which does not exist in the kubeconfig');
+});
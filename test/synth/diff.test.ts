@@ -0,0 +1,69 @@
+import { diffResource, findDeletions, resourceKey } from '../../src/synth/diff';
+
+test('resourceKey', () => {
+  expect(resourceKey({ apiVersion: 'apps/v1', kind: 'Deployment', metadata: { name: 'web', namespace: 'prod' } })).toBe('apps/v1/Deployment/prod/web');
+  expect(resourceKey({ apiVersion: 'v1', kind: 'Namespace', metadata: { name: 'prod' } })).toBe('v1/Namespace//prod');
+});
+
+test('diffResource ignores fields populated by the cluster', () => {
+  const desired = {
+    apiVersion: 'apps/v1',
+    kind: 'Deployment',
+    metadata: { name: 'web' },
+    spec: { replicas: 2, template: { spec: { containers: [{ name: 'web', image: 'nginx:1.23' }] } } },
+  };
+
+  const live = {
+    apiVersion: 'apps/v1',
+    kind: 'Deployment',
+    metadata: { name: 'web', namespace: 'default', uid: '1234', resourceVersion: '1' },
+    spec: { replicas: 2, template: { spec: { containers: [{ name: 'web', image: 'nginx:1.23', imagePullPolicy: 'IfNotPresent' }] } } },
+    status: { readyReplicas: 2 },
+  };
+
+  expect(diffResource(desired, live)).toEqual([]);
+});
+
+test('diffResource reports changed values', () => {
+  const desired = {
+    spec: { replicas: 3, template: { spec: { containers: [{ name: 'web', image: 'nginx:1.24' }] } }, paused: true },
+  };
+
+  const live = {
+    spec: { replicas: 2, template: { spec: { containers: [{ name: 'web', image: 'nginx:1.23' }] } } },
+  };
+
+  expect(diffResource(desired, live)).toEqual([
+    { path: 'spec.replicas', desired: 3, live: 2 },
+    { path: 'spec.template.spec.containers[0].image', desired: 'nginx:1.24', live: 'nginx:1.23' },
+    { path: 'spec.paused', desired: true, live: undefined },
+  ]);
+});
+
+test('diffResource compares arrays of different length as a whole', () => {
+  expect(diffResource({ args: ['a', 'b'] }, { args: ['a'] })).toEqual([
+    { path: 'args', desired: ['a', 'b'], live: ['a'] },
+  ]);
+});
+
+test('diffResource compares the stringData of a secret as data', () => {
+  const desired = { apiVersion: 'v1', kind: 'Secret', metadata: { name: 'creds' }, stringData: { user: 'admin', password: 'secret' } };
+  const live = { apiVersion: 'v1', kind: 'Secret', metadata: { name: 'creds' }, data: { user: 'YWRtaW4=', password: 'b2xk' } };
+
+  expect(diffResource(desired, live)).toEqual([
+    { path: 'data.password', desired: 'c2VjcmV0', live: 'b2xk' },
+  ]);
+});
+
+test('findDeletions matches resources without a namespace in the given namespace only', () => {
+  const configMap = (name: string, namespace?: string) => ({ apiVersion: 'v1', kind: 'ConfigMap', metadata: { name, namespace } });
+  const role = { apiVersion: 'rbac.authorization.k8s.io/v1', kind: 'ClusterRole', metadata: { name: 'reader' } };
+
+  const deletions = findDeletions(
+    [configMap('config'), configMap('settings', 'prod'), role],
+    [configMap('config', 'dev'), configMap('config', 'staging'), configMap('settings', 'prod'), configMap('settings', 'dev'), role],
+    'dev',
+  );
+
+  expect(deletions.map(resourceKey)).toEqual(['v1/ConfigMap/staging/config', 'v1/ConfigMap/dev/settings']);
+});
@@ -0,0 +1,24 @@
+import * as os from 'os';
+import * as path from 'path';
+import * as fs from 'fs-extra';
+import * as yaml from 'yaml';
+import { writeKustomization } from '../../src/synth/kustomize';
+
+test('writeKustomization references all manifests in order', async () => {
+  const outdir = fs.mkdtempSync(path.join(os.tmpdir(), 'cdk8s-kustomize-test'));
+  try {
+    await writeKustomization(outdir, [
+      { file: '0000-first.k8s.yaml', resources: [] },
+      { file: '0001-second.k8s.yaml', resources: [] },
+      { file: path.join('sub', '0002-third.k8s.yaml'), resources: [] },
+    ]);
+
+    expect(yaml.parse(fs.readFileSync(path.join(outdir, 'kustomization.yaml'), 'utf-8'))).toStrictEqual({
+      apiVersion: 'kustomize.config.k8s.io/v1beta1',
+      kind: 'Kustomization',
+      resources: ['0000-first.k8s.yaml', '0001-second.k8s.yaml', 'sub/0002-third.k8s.yaml'],
+    });
+  } finally {
+    fs.removeSync(outdir);
+  }
+});
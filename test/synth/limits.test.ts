@@ -0,0 +1,39 @@
+import * as os from 'os';
+import * as path from 'path';
+import * as fs from 'fs-extra';
+import { checkOutputSize, checkResourceLimits } from '../../src/synth/limits';
+
+const resources = (count: number) => Array.from({ length: count }, (_, i) => ({ apiVersion: 'v1', kind: 'ConfigMap', metadata: { name: `cm-${i}` } }));
+
+const manifests = [
+  { file: '0000-web.k8s.yaml', resources: resources(3) },
+  { file: '0001-db.k8s.yaml', resources: resources(2) },
+];
+
+test('fails if the charts synthesize more resources than the maximum', () => {
+  expect(() => checkResourceLimits(manifests, { maxResources: 4 }))
+    .toThrow('Synthesis produced more than 4 resources (--max-resources). Exceeded in 0001-db.k8s.yaml');
+  expect(() => checkResourceLimits(manifests, { maxResources: 5 })).not.toThrow();
+});
+
+test('warns about charts with more resources than the threshold', () => {
+  expect(checkResourceLimits(manifests, { chartResourcesWarning: 2 })).toEqual([
+    'Chart "web" synthesized 3 resources (more than 2)',
+  ]);
+  expect(checkResourceLimits(manifests, { chartResourcesWarning: 0 })).toEqual([]);
+  expect(checkResourceLimits(manifests, {})).toEqual([]);
+});
+
+test('fails if the manifests are larger than the maximum', async () => {
+  const outdir = fs.mkdtempSync(path.join(os.tmpdir(), 'cdk8s-limits-'));
+  try {
+    fs.writeFileSync(path.join(outdir, '0000-web.k8s.yaml'), 'a'.repeat(10));
+    fs.writeFileSync(path.join(outdir, '0001-db.k8s.yaml'), 'b'.repeat(10));
+
+    await expect(checkOutputSize(outdir, manifests, 15))
+      .rejects.toThrow('Synthesis produced more than 15 bytes of manifests (--max-output-bytes). Exceeded in 0001-db.k8s.yaml');
+    await expect(checkOutputSize(outdir, manifests, 20)).resolves.toBeUndefined();
+  } finally {
+    fs.removeSync(outdir);
+  }
+});
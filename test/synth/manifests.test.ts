@@ -0,0 +1,104 @@
+import * as os from 'os';
+import * as path from 'path';
+import * as zlib from 'zlib';
+import * as fs from 'fs-extra';
+import { addContentHash, CONTENT_HASH_ANNOTATION, isCompressed, manifestFile, OutputFormat, readManifests, serializeResources, sortKeys, writeManifests } from '../../src/synth/manifests';
+
+const resources = [
+  { apiVersion: 'v1', kind: 'ConfigMap', metadata: { name: 'first' }, data: { foo: 'bar' } },
+  { apiVersion: 'v1', kind: 'ConfigMap', metadata: { name: 'second' } },
+];
+
+let outdir: string;
+
+beforeEach(() => {
+  outdir = fs.mkdtempSync(path.join(os.tmpdir(), 'cdk8s-manifests-'));
+});
+
+afterEach(() => {
+  fs.removeSync(outdir);
+});
+
+test('serializes resources as json', () => {
+  expect(JSON.parse(serializeResources(resources, OutputFormat.JSON))).toStrictEqual(resources);
+});
+
+test('serializes resources as newline-delimited json', () => {
+  expect(serializeResources(resources, OutputFormat.JSON_STREAM)).toEqual([
+    '{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"first"},"data":{"foo":"bar"}}',
+    '{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"second"}}',
+    '',
+  ].join('\n'));
+});
+
+test('sorts the keys of resources', () => {
+  const resource = {
+    spec: { template: { spec: { containers: [{ name: 'b', image: 'b:1' }, { image: 'a:1', name: 'a' }] } }, replicas: 1 },
+    metadata: { name: 'web', labels: { tier: 'frontend', app: 'web' } },
+    kind: 'Deployment',
+    apiVersion: 'apps/v1',
+  };
+
+  const sorted = sortKeys(resource);
+
+  expect(sorted).toStrictEqual(resource);
+  expect(serializeResources([sorted], OutputFormat.JSON_STREAM)).toEqual([
+    '{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"labels":{"app":"web","tier":"frontend"},"name":"web"},',
+    '"spec":{"replicas":1,"template":{"spec":{"containers":[{"image":"b:1","name":"b"},{"image":"a:1","name":"a"}]}}}}\n',
+  ].join(''));
+});
+
+test('the content hash does not depend on the order of keys', () => {
+  const hash = (resource: any) => addContentHash(resource).metadata.annotations[CONTENT_HASH_ANNOTATION];
+  const configMap = { apiVersion: 'v1', kind: 'ConfigMap', metadata: { name: 'config', annotations: { team: 'web' } }, data: { a: '1', b: '2' } };
+
+  const annotated = addContentHash(configMap);
+  expect(annotated.metadata.annotations).toStrictEqual({ team: 'web', [CONTENT_HASH_ANNOTATION]: expect.stringMatching(/^[0-9a-f]{64}$/) });
+  expect(configMap.metadata.annotations).toStrictEqual({ team: 'web' });
+
+  expect(hash({ data: { b: '2', a: '1' }, metadata: { annotations: { team: 'web' }, name: 'config' }, kind: 'ConfigMap', apiVersion: 'v1' })).toEqual(hash(configMap));
+  expect(hash(annotated)).toEqual(hash(configMap));
+  expect(hash({ ...configMap, data: { a: '1', b: '3' } })).not.toEqual(hash(configMap));
+});
+
+test('manifest file names follow the format', () => {
+  expect(manifestFile('0000-chart.k8s.yaml', OutputFormat.YAML)).toEqual('0000-chart.k8s.yaml');
+  expect(manifestFile('0000-chart.k8s.yaml', OutputFormat.JSON)).toEqual('0000-chart.k8s.json');
+  expect(manifestFile('sub/chart.k8s.yaml', OutputFormat.JSON_STREAM)).toEqual('sub/chart.k8s.jsonl');
+  expect(manifestFile('0000-chart.k8s.yaml', OutputFormat.YAML, true)).toEqual('0000-chart.k8s.yaml.gz');
+  expect(manifestFile('0000-chart.k8s.yaml', OutputFormat.JSON, true)).toEqual('0000-chart.k8s.json.gz');
+});
+
+test.each([OutputFormat.JSON, OutputFormat.JSON_STREAM])('converts synthesized yaml manifests to %s', async (format) => {
+  await writeManifests(outdir, [{ file: 'chart.k8s.yaml', resources }]);
+
+  const written = await writeManifests(outdir, await readManifests(outdir), format);
+
+  expect(written.map(m => m.file)).toEqual([manifestFile('chart.k8s.yaml', format)]);
+  expect(fs.readdirSync(outdir)).toEqual([manifestFile('chart.k8s.yaml', format)]);
+  expect(await readManifests(outdir, format)).toStrictEqual([{ file: manifestFile('chart.k8s.yaml', format), resources }]);
+});
+
+test('compresses manifests with gzip', async () => {
+  await writeManifests(outdir, [{ file: 'chart.k8s.yaml', resources }]);
+  const yaml = fs.readFileSync(path.join(outdir, 'chart.k8s.yaml'), 'utf-8');
+
+  const written = await writeManifests(outdir, await readManifests(outdir), OutputFormat.YAML, true);
+
+  expect(written.map(m => m.file)).toEqual(['chart.k8s.yaml.gz']);
+  expect(isCompressed(written[0].file)).toBeTruthy();
+  expect(fs.readdirSync(outdir)).toEqual(['chart.k8s.yaml.gz']);
+
+  // the multi-document structure is preserved
+  expect(zlib.gunzipSync(fs.readFileSync(path.join(outdir, 'chart.k8s.yaml.gz'))).toString('utf-8')).toEqual(yaml);
+});
+
+test('reads compressed manifests transparently', async () => {
+  await writeManifests(outdir, [{ file: '0000-first.k8s.yaml', resources: [resources[0]] }], OutputFormat.YAML, true);
+  await writeManifests(outdir, [{ file: '0001-second.k8s.yaml', resources: [resources[1]] }]);
+
+  expect(await readManifests(outdir)).toStrictEqual([
+    { file: '0000-first.k8s.yaml.gz', resources: [resources[0]] },
+    { file: '0001-second.k8s.yaml', resources: [resources[1]] },
+  ]);
+});
@@ -0,0 +1,76 @@
+import * as os from 'os';
+import * as path from 'path';
+import * as fs from 'fs-extra';
+import { readManifests, writeManifests } from '../../src/synth/manifests';
+import { clusterScopedKinds, effectiveNamespace, writeByNamespace } from '../../src/synth/namespaces';
+
+const namespace = { apiVersion: 'v1', kind: 'Namespace', metadata: { name: 'prod' } };
+const role = { apiVersion: 'rbac.authorization.k8s.io/v1', kind: 'ClusterRole', metadata: { name: 'reader' } };
+const service = { apiVersion: 'v1', kind: 'Service', metadata: { name: 'web', namespace: 'prod' } };
+const configMap = { apiVersion: 'v1', kind: 'ConfigMap', metadata: { name: 'config' } };
+const crd = {
+  apiVersion: 'apiextensions.k8s.io/v1',
+  kind: 'CustomResourceDefinition',
+  metadata: { name: 'widgets.example.com' },
+  spec: { group: 'example.com', scope: 'Cluster', names: { kind: 'Widget' } },
+};
+
+describe('effectiveNamespace', () => {
+
+  const clusterScoped = clusterScopedKinds([crd]);
+
+  test('cluster-scoped resources have no namespace', () => {
+    expect(effectiveNamespace(namespace, clusterScoped)).toBeUndefined();
+    expect(effectiveNamespace(role, clusterScoped)).toBeUndefined();
+    expect(effectiveNamespace({ apiVersion: 'example.com/v1', kind: 'Widget' }, clusterScoped)).toBeUndefined();
+  });
+
+  test('namespaced resources fall back to the default namespace', () => {
+    expect(effectiveNamespace(service, clusterScoped)).toBe('prod');
+    expect(effectiveNamespace(configMap, clusterScoped)).toBe('default');
+    expect(effectiveNamespace(configMap, clusterScoped, 'staging')).toBe('staging');
+  });
+
+  test('custom resources of namespaced CRDs are namespaced', () => {
+    expect(effectiveNamespace({ apiVersion: 'other.com/v1', kind: 'Widget' }, clusterScoped)).toBe('default');
+  });
+
+});
+
+describe('writeByNamespace', () => {
+
+  let outdir: string;
+
+  beforeEach(() => {
+    outdir = fs.mkdtempSync(path.join(os.tmpdir(), 'cdk8s-namespaces-test'));
+  });
+
+  afterEach(() => {
+    fs.removeSync(outdir);
+  });
+
+  test('writes a file per namespace', async () => {
+    await writeManifests(outdir, [
+      { file: '0000-infra.k8s.yaml', resources: [namespace, role] },
+      { file: '0001-app.k8s.yaml', resources: [service, configMap, { ...configMap, metadata: { name: 'other', namespace: 'prod' } }] },
+    ]);
+
+    const written = await writeByNamespace(outdir, await readManifests(outdir), 'apps');
+
+    expect(written).toStrictEqual([
+      { file: 'cluster.k8s.yaml', resources: [namespace, role] },
+      { file: 'namespace-prod.k8s.yaml', resources: [service, { ...configMap, metadata: { name: 'other', namespace: 'prod' } }] },
+      { file: 'namespace-apps.k8s.yaml', resources: [configMap] },
+    ]);
+    expect(fs.readdirSync(outdir).sort()).toStrictEqual(['cluster.k8s.yaml', 'namespace-apps.k8s.yaml', 'namespace-prod.k8s.yaml']);
+  });
+
+  test('there is no cluster file without cluster-scoped resources', async () => {
+    await writeManifests(outdir, [{ file: 'app.k8s.yaml', resources: [service] }]);
+
+    const written = await writeByNamespace(outdir, await readManifests(outdir));
+
+    expect(written.map(m => m.file)).toStrictEqual(['namespace-prod.k8s.yaml']);
+  });
+
+});
@@ -0,0 +1,111 @@
+import * as os from 'os';
+import * as path from 'path';
+import * as fs from 'fs-extra';
+import { readManifests, writeManifests } from '../../src/synth/manifests';
+import { renderOutputPath, validateOutputPath, writeOutputPath } from '../../src/synth/output-path';
+
+const service = { apiVersion: 'v1', kind: 'Service', metadata: { name: 'web', namespace: 'prod' } };
+const deployment = { apiVersion: 'apps/v1', kind: 'Deployment', metadata: { name: 'web', namespace: 'prod' } };
+const namespace = { apiVersion: 'v1', kind: 'Namespace', metadata: { name: 'prod' } };
+
+describe('validateOutputPath', () => {
+
+  test('accepts templates with known variables', () => {
+    expect(() => validateOutputPath('{chartId}/{namespace}/{kind}-{name}.yaml')).not.toThrow();
+    expect(() => validateOutputPath('all')).not.toThrow();
+  });
+
+  test.each([
+    ['', 'the template is empty'],
+    ['/abs/{name}.yaml', 'the path must be relative to the output directory'],
+    ['../{name}.yaml', 'the path must not leave the output directory'],
+    ['{chartId/{name}.yaml', 'unbalanced "{" or "}"'],
+    ['{chart}/{name}.yaml', 'unknown variable "{chart}". Available variables: {chartId}, {kind}, {name}, {namespace}'],
+    ['{chartId}/', 'the template must end with a file name'],
+  ])('rejects "%s"', (template, reason) => {
+    expect(() => validateOutputPath(template)).toThrow(`Invalid outputPath "${template}": ${reason}`);
+  });
+
+});
+
+describe('renderOutputPath', () => {
+
+  test('renders the variables of a resource', () => {
+    expect(renderOutputPath('{chartId}/{namespace}/{kind}-{name}.yaml', 'my-chart', service)).toBe('my-chart/prod/Service-web.k8s.yaml');
+  });
+
+  test('empty segments are dropped', () => {
+    expect(renderOutputPath('{chartId}/{namespace}/{kind}-{name}', 'my-chart', namespace)).toBe('my-chart/Namespace-prod.k8s.yaml');
+  });
+
+  test('fails if the file name is empty', () => {
+    expect(() => renderOutputPath('{chartId}/{name}', 'my-chart', { kind: 'ConfigMap' }))
+      .toThrow('outputPath "{chartId}/{name}" renders an empty file name for ConfigMap "" of chart "my-chart"');
+  });
+
+});
+
+describe('writeOutputPath', () => {
+
+  let outdir: string;
+
+  beforeEach(() => {
+    outdir = fs.mkdtempSync(path.join(os.tmpdir(), 'cdk8s-output-path-test'));
+  });
+
+  afterEach(() => {
+    fs.removeSync(outdir);
+  });
+
+  test('moves resources into the rendered files', async () => {
+    await writeManifests(outdir, [
+      { file: '0000-infra.k8s.yaml', resources: [namespace] },
+      { file: '0001-app.k8s.yaml', resources: [service, deployment] },
+    ]);
+
+    const written = await writeOutputPath(outdir, await readManifests(outdir), '{chartId}/{kind}-{name}.yaml');
+
+    expect(written.map(m => m.file)).toStrictEqual([
+      'infra/Namespace-prod.k8s.yaml',
+      'app/Service-web.k8s.yaml',
+      'app/Deployment-web.k8s.yaml',
+    ]);
+    expect((await readManifests(outdir)).map(m => m.file).sort()).toStrictEqual([
+      'app/Deployment-web.k8s.yaml',
+      'app/Service-web.k8s.yaml',
+      'infra/Namespace-prod.k8s.yaml',
+    ]);
+  });
+
+  test('resources of a chart that render to the same path share a file', async () => {
+    await writeManifests(outdir, [{ file: '0000-app.k8s.yaml', resources: [service, deployment] }]);
+
+    const written = await writeOutputPath(outdir, await readManifests(outdir), '{chartId}/{namespace}.yaml');
+
+    expect(written).toStrictEqual([{ file: 'app/prod.k8s.yaml', resources: [service, deployment] }]);
+    expect(fs.readdirSync(outdir)).toStrictEqual(['app']);
+  });
+
+  test('fails before writing if resources of a chart collide and the template includes {name}', async () => {
+    const configMap = (ns: string) => ({ apiVersion: 'v1', kind: 'ConfigMap', metadata: { name: 'app', namespace: ns } });
+    await writeManifests(outdir, [{ file: '0000-app.k8s.yaml', resources: [configMap('a'), configMap('b')] }]);
+
+    await expect(writeOutputPath(outdir, await readManifests(outdir), '{chartId}/{kind}-{name}.yaml'))
+      .rejects.toThrow('outputPath "{chartId}/{kind}-{name}.yaml" renders "app/ConfigMap-app.k8s.yaml" for ConfigMap "a/app" and ConfigMap "b/app" of chart "app"');
+
+    expect(fs.readdirSync(outdir)).toStrictEqual(['0000-app.k8s.yaml']);
+  });
+
+  test('fails before writing if charts collide', async () => {
+    await writeManifests(outdir, [
+      { file: '0000-a.k8s.yaml', resources: [service] },
+      { file: '0001-b.k8s.yaml', resources: [service] },
+    ]);
+
+    await expect(writeOutputPath(outdir, await readManifests(outdir), '{kind}-{name}.yaml'))
+      .rejects.toThrow('outputPath "{kind}-{name}.yaml" renders "Service-web.k8s.yaml" for resources of charts "a" and "b"');
+
+    expect(fs.readdirSync(outdir).sort()).toStrictEqual(['0000-a.k8s.yaml', '0001-b.k8s.yaml']);
+  });
+
+});
@@ -0,0 +1,156 @@
+import { Manifest } from '../../src/synth/manifests';
+import { applyJsonPatch, applyPatches, strategicMerge } from '../../src/synth/patches';
+
+function deployment(name: string, namespace?: string) {
+  return {
+    apiVersion: 'apps/v1',
+    kind: 'Deployment',
+    metadata: { name, namespace },
+    spec: {
+      replicas: 1,
+      template: {
+        spec: {
+          containers: [
+            { name: 'app', image: 'app:1', env: [{ name: 'A', value: '1' }] },
+            { name: 'sidecar', image: 'sidecar:1' },
+          ],
+        },
+      },
+    },
+  };
+}
+
+function manifests(): Manifest[] {
+  return [{
+    file: 'chart.k8s.yaml',
+    resources: [
+      deployment('web', 'prod'),
+      deployment('worker', 'prod'),
+      { apiVersion: 'v1', kind: 'Service', metadata: { name: 'web' }, spec: { ports: [{ port: 80 }] } },
+    ],
+  }];
+}
+
+describe('applyPatches', () => {
+
+  test('applies patches to resources matching the target', () => {
+    const m = manifests();
+    const count = applyPatches(m, [{
+      target: { kind: 'Deployment', name: 'w*' },
+      jsonPatch: [{ op: 'replace', path: '/spec/replicas', value: 3 }],
+    }]);
+
+    expect(count).toBe(2);
+    expect(m[0].resources.map(r => r.spec.replicas)).toStrictEqual([3, 3, undefined]);
+  });
+
+  test('all fields of the target must match', () => {
+    const m = manifests();
+    applyPatches(m, [{
+      target: { apiVersion: 'apps/*', name: 'web', namespace: 'prod' },
+      jsonPatch: [{ op: 'add', path: '/metadata/labels', value: { tier: 'frontend' } }],
+    }]);
+
+    expect(m[0].resources.map(r => r.metadata.labels)).toStrictEqual([{ tier: 'frontend' }, undefined, undefined]);
+  });
+
+  test('patches apply in declared order', () => {
+    const m = manifests();
+    applyPatches(m, [
+      { target: { kind: 'Service' }, jsonPatch: [{ op: 'add', path: '/metadata/labels', value: { a: '1' } }] },
+      { target: { kind: 'Service' }, jsonPatch: [{ op: 'add', path: '/metadata/labels/b', value: '2' }] },
+    ]);
+
+    expect(m[0].resources[2].metadata.labels).toStrictEqual({ a: '1', b: '2' });
+  });
+
+  test('fails if a path does not exist', () => {
+    expect(() => applyPatches(manifests(), [{
+      target: { kind: 'Service' },
+      jsonPatch: [{ op: 'replace', path: '/spec/replicas', value: 3 }],
+    }])).toThrow('Patch 0 failed for v1/Service//web in chart.k8s.yaml: path "/spec/replicas" does not exist');
+  });
+
+  test('optional patches skip resources without the path', () => {
+    const m = manifests();
+    applyPatches(m, [{
+      target: { name: 'web' },
+      jsonPatch: [{ op: 'replace', path: '/spec/replicas', value: 3 }],
+      optional: true,
+    }]);
+
+    expect(m[0].resources[0].spec.replicas).toBe(3);
+    expect(m[0].resources[2]).toStrictEqual(manifests()[0].resources[2]);
+  });
+
+  test('applies strategic merge patches', () => {
+    const m = manifests();
+    applyPatches(m, [{
+      target: { kind: 'Deployment', name: 'web' },
+      strategicMerge: {
+        spec: {
+          template: {
+            spec: {
+              containers: [
+                { name: 'app', image: 'app:2', env: [{ name: 'B', value: '2' }] },
+                { name: 'proxy', image: 'proxy:1' },
+              ],
+            },
+          },
+        },
+      },
+    }]);
+
+    expect(m[0].resources[0].spec.template.spec.containers).toStrictEqual([
+      { name: 'app', image: 'app:2', env: [{ name: 'A', value: '1' }, { name: 'B', value: '2' }] },
+      { name: 'sidecar', image: 'sidecar:1' },
+      { name: 'proxy', image: 'proxy:1' },
+    ]);
+    expect(m[0].resources[1]).toStrictEqual(manifests()[0].resources[1]);
+  });
+
+  test('fails if a patch has no operations', () => {
+    expect(() => applyPatches(manifests(), [{ target: { kind: 'Service' } }]))
+      .toThrow('Patch 0 must specify "jsonPatch" or "strategicMerge"');
+  });
+
+});
+
+describe('applyJsonPatch', () => {
+
+  const doc = { a: { b: 1 }, list: ['x', 'y'], 'c/d': 2 };
+
+  test('does not modify the original document', () => {
+    applyJsonPatch(doc, [{ op: 'remove', path: '/a' }]);
+    expect(doc.a).toStrictEqual({ b: 1 });
+  });
+
+  test('add inserts into arrays', () => {
+    expect(applyJsonPatch(doc, [
+      { op: 'add', path: '/list/1', value: 'z' },
+      { op: 'add', path: '/list/-', value: 'end' },
+    ]).list).toStrictEqual(['x', 'z', 'y', 'end']);
+  });
+
+  test('replace, move and copy', () => {
+    expect(applyJsonPatch(doc, [
+      { op: 'replace', path: '/list/0', value: 'w' },
+      { op: 'move', from: '/a/b', path: '/b' },
+      { op: 'copy', from: '/c~1d', path: '/e' },
+    ])).toStrictEqual({ a: {}, list: ['w', 'y'], 'c/d': 2, 'b': 1, 'e': 2 });
+  });
+
+  test('test fails if the value differs', () => {
+    expect(() => applyJsonPatch(doc, [{ op: 'test', path: '/a/b', value: 2 }])).toThrow('test failed, "/a/b" is not 2');
+  });
+
+  test('add fails if the parent does not exist', () => {
+    expect(() => applyJsonPatch(doc, [{ op: 'add', path: '/x/y', value: 1 }])).toThrow('path "/x" does not exist');
+  });
+
+});
+
+test('strategicMerge removes fields set to null and replaces other lists', () => {
+  expect(strategicMerge({ a: 1, b: 2, ports: [{ port: 80 }] }, { b: null, ports: [{ port: 443 }] }))
+    .toStrictEqual({ a: 1, ports: [{ port: 443 }] });
+});
@@ -0,0 +1,47 @@
+import * as os from 'os';
+import * as path from 'path';
+import * as fs from 'fs-extra';
+import { ApplyMode } from '../../src/synth/apply';
+import { readManifests } from '../../src/synth/manifests';
+import { synthManifests } from '../../src/synth/pipeline';
+
+function writeApp(workdir: string) {
+  const app = path.join(workdir, 'app.js');
+  fs.writeFileSync(app, [
+    'const fs = require("fs");',
+    'const path = require("path");',
+    'const outdir = process.env.CDK8S_OUTDIR;',
+    'fs.mkdirSync(outdir, { recursive: true });',
+    'fs.writeFileSync(path.join(outdir, "0000-web.k8s.yaml"), `kind: ConfigMap\\napiVersion: v1\\nmetadata:\\n  name: ${process.env.CDK8S_CONTEXT}\\ndata:\\n  a: "1"\\n`);',
+    'fs.writeFileSync(path.join(outdir, "0001-worker.k8s.yaml"), "kind: ConfigMap\\napiVersion: v1\\nmetadata:\\n  name: worker\\n  annotations:\\n    kubectl.kubernetes.io/last-applied-configuration: \'{}\'\\n");',
+  ].join('\n'));
+  return `node ${app}`;
+}
+
+test('synthManifests applies the chart filter, patches and plugins in order', async () => {
+  const workdir = fs.mkdtempSync(path.join(os.tmpdir(), 'cdk8s-pipeline-'));
+  const outdir = path.join(workdir, 'dist');
+
+  const manifests = await synthManifests(writeApp(workdir), outdir, {
+    env: { CDK8S_CONTEXT: 'staging' },
+    chart: 'web',
+    patches: [{ target: { kind: 'ConfigMap' }, jsonPatch: [{ op: 'replace', path: '/data/a', value: '2' }] }],
+    plugins: [{ name: 'label.js', transform: resources => resources.map(r => ({ ...r, metadata: { ...r.metadata, labels: { a: r.data.a } } })) }],
+  });
+
+  const expected = [{ apiVersion: 'v1', kind: 'ConfigMap', metadata: { name: 'staging', labels: { a: '2' } }, data: { a: '2' } }];
+  expect(manifests.map(m => m.resources)).toEqual([expected]);
+
+  // the manifests are written back
+  expect((await readManifests(outdir)).map(m => m.resources)).toEqual([expected]);
+});
+
+test('synthManifests prepares the manifests for server-side apply', async () => {
+  const workdir = fs.mkdtempSync(path.join(os.tmpdir(), 'cdk8s-pipeline-'));
+  const outdir = path.join(workdir, 'dist');
+
+  await synthManifests(writeApp(workdir), outdir, { applyMode: ApplyMode.SERVER_SIDE });
+
+  const [, worker] = await readManifests(outdir);
+  expect(worker.resources).toEqual([{ apiVersion: 'v1', kind: 'ConfigMap', metadata: { name: 'worker' } }]);
+});
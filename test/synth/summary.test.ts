@@ -0,0 +1,126 @@
+import * as os from 'os';
+import * as path from 'path';
+import * as fs from 'fs-extra';
+import { writeByNamespace } from '../../src/synth/namespaces';
+import { writeOutputPath } from '../../src/synth/output-path';
+import { resourceCharts, summarizeManifests, writeSummary } from '../../src/synth/summary';
+
+const service = { apiVersion: 'v1', kind: 'Service', metadata: { name: 'web', namespace: 'prod' } };
+const deployment = { apiVersion: 'apps/v1', kind: 'Deployment', metadata: { name: 'web', namespace: 'prod' } };
+const namespace = { apiVersion: 'v1', kind: 'Namespace', metadata: { name: 'prod' } };
+
+test('lists the resources of each chart', () => {
+  const summary = summarizeManifests([
+    { file: '0000-infra.k8s.yaml', resources: [namespace] },
+    { file: 'app/Service-web.k8s.json', resources: [service] },
+    { file: 'app/Deployment-web.k8s.json', resources: [deployment] },
+  ]);
+
+  expect(summary).toStrictEqual({
+    charts: [
+      {
+        chartId: 'infra',
+        resources: [{ apiVersion: 'v1', kind: 'Namespace', namespace: undefined, name: 'prod', file: '0000-infra.k8s.yaml' }],
+      },
+      {
+        chartId: 'app',
+        resources: [
+          { apiVersion: 'v1', kind: 'Service', namespace: 'prod', name: 'web', file: 'app/Service-web.k8s.json' },
+          { apiVersion: 'apps/v1', kind: 'Deployment', namespace: 'prod', name: 'web', file: 'app/Deployment-web.k8s.json' },
+        ],
+      },
+    ],
+    counts: {
+      charts: 2,
+      files: 3,
+      resources: 3,
+      kinds: { 'v1/Namespace': 1, 'v1/Service': 1, 'apps/v1/Deployment': 1 },
+    },
+    warnings: [],
+  });
+});
+
+describe('charts of moved resources', () => {
+  const configMap = { apiVersion: 'v1', kind: 'ConfigMap', metadata: { name: 'config', namespace: 'dev' } };
+  const synthesized = [
+    { file: '0000-infra.k8s.yaml', resources: [namespace, configMap] },
+    { file: '0001-app.k8s.yaml', resources: [service, deployment] },
+  ];
+
+  let outdir: string;
+
+  beforeEach(() => {
+    outdir = fs.mkdtempSync(path.join(os.tmpdir(), 'cdk8s-summary-test'));
+  });
+
+  afterEach(() => {
+    fs.removeSync(outdir);
+  });
+
+  const resourcesOf = (summary: ReturnType<typeof summarizeManifests>) => summary.charts.map(c => ({
+    chartId: c.chartId,
+    resources: c.resources.map(r => `${r.kind}/${r.name} (${r.file})`),
+  }));
+
+  test('are recorded before splitting by namespace', async () => {
+    const charts = resourceCharts(synthesized);
+    const manifests = await writeByNamespace(outdir, synthesized);
+
+    expect(resourcesOf(summarizeManifests(manifests, { charts }))).toStrictEqual([
+      { chartId: 'infra', resources: ['Namespace/prod (cluster.k8s.yaml)', 'ConfigMap/config (namespace-dev.k8s.yaml)'] },
+      { chartId: 'app', resources: ['Service/web (namespace-prod.k8s.yaml)', 'Deployment/web (namespace-prod.k8s.yaml)'] },
+    ]);
+  });
+
+  test('are recorded before writing to the outputPath', async () => {
+    const charts = resourceCharts(synthesized);
+    const manifests = await writeOutputPath(outdir, synthesized, '{namespace}/{kind}-{name}');
+
+    expect(resourcesOf(summarizeManifests(manifests, { charts }))).toStrictEqual([
+      { chartId: 'infra', resources: ['Namespace/prod (Namespace-prod.k8s.yaml)', 'ConfigMap/config (dev/ConfigMap-config.k8s.yaml)'] },
+      { chartId: 'app', resources: ['Service/web (prod/Service-web.k8s.yaml)', 'Deployment/web (prod/Deployment-web.k8s.yaml)'] },
+    ]);
+  });
+});
+
+test('files can be omitted', () => {
+  const summary = summarizeManifests([{ file: 'app.k8s.yaml', resources: [service] }], { files: false });
+  expect(summary.charts[0].resources[0].file).toBeUndefined();
+});
+
+test('warns about duplicate and unnamed resources', () => {
+  const summary = summarizeManifests([
+    { file: '0000-a.k8s.yaml', resources: [service, { apiVersion: 'v1', kind: 'ConfigMap' }] },
+    { file: '0001-b.k8s.yaml', resources: [service] },
+  ]);
+
+  expect(summary.warnings).toStrictEqual([
+    'v1/ConfigMap in 0000-a.k8s.yaml has no name',
+    'v1/Service/prod/web is defined 2 times (in 0000-a.k8s.yaml, 0001-b.k8s.yaml)',
+  ]);
+});
+
+test('includes the given warnings', () => {
+  const summary = summarizeManifests([
+    { file: '0000-a.k8s.yaml', resources: [service] },
+    { file: '0001-b.k8s.yaml', resources: [service] },
+  ], { warnings: ['Chart "a" synthesized 1 resources (more than 0)'] });
+
+  expect(summary.warnings).toStrictEqual([
+    'Chart "a" synthesized 1 resources (more than 0)',
+    'v1/Service/prod/web is defined 2 times (in 0000-a.k8s.yaml, 0001-b.k8s.yaml)',
+  ]);
+});
+
+test('writes the summary as json', async () => {
+  const dir = fs.mkdtempSync(path.join(os.tmpdir(), 'cdk8s-summary-test'));
+  try {
+    const file = path.join(dir, 'reports', 'summary.json');
+    const summary = summarizeManifests([{ file: 'app.k8s.yaml', resources: [service] }]);
+    await writeSummary(file, summary);
+
+    expect(fs.readJsonSync(file)).toStrictEqual(JSON.parse(JSON.stringify(summary)));
+  } finally {
+    fs.removeSync(dir);
+  }
+});
@@ -1,3 +1,8 @@
+import * as os from 'os';
+import * as path from 'path';
+import * as fs from 'fs-extra';
+import * as yaml from 'yaml';
+
 // eslint-disable-next-line
 const cmd = require('../../src/cli/cmds/synth');
 
@@ -5,3 +10,40 @@ test('synth with both --stdout and --output throws exception', () => {
   // eslint-disable-next-line
   expect(cmd.handler({ app: 'cdk8s', output: 'test', stdout: true })).rejects.toEqual(new Error('\'--output\' and \'--stdout\' are mutually exclusive. Please only use one.'));
 });
+
+test('synth with --stdout writes all charts as a single yaml stream', async () => {
+  const workdir = fs.mkdtempSync(path.join(os.tmpdir(), 'cdk8s-synth-stdout-'));
+  const app = path.join(workdir, 'app.js');
+  fs.writeFileSync(app, [
+    'const fs = require("fs");',
+    'const path = require("path");',
+    'const outdir = process.env.CDK8S_OUTDIR;',
+    'fs.mkdirSync(outdir, { recursive: true });',
+    'fs.writeFileSync(path.join(outdir, "0000-first.k8s.yaml"), "kind: ConfigMap\\nmetadata:\\n  name: a\\n---\\nkind: ConfigMap\\nmetadata:\\n  name: b\\n");',
+    'fs.writeFileSync(path.join(outdir, "0001-second.k8s.yaml"), "kind: Secret\\nmetadata:\\n  name: c\\n");',
+  ].join('\n'));
+
+  const stdout = jest.spyOn(process.stdout, 'write').mockImplementation(() => true);
+  const log = jest.spyOn(console, 'log');
+  const error = jest.spyOn(console, 'error').mockImplementation(() => undefined);
+  try {
+    await cmd.handler({ app: `node ${app}`, output: 'dist', stdout: true });
+
+    const output = stdout.mock.calls.map(args => args[0]).join('');
+    expect(yaml.parseAllDocuments(output).map(d => d.toJS())).toEqual([
+      { kind: 'ConfigMap', metadata: { name: 'a' } },
+      { kind: 'ConfigMap', metadata: { name: 'b' } },
+      { kind: 'Secret', metadata: { name: 'c' } },
+    ]);
+    expect(output.split('\n').filter(l => l === '---')).toHaveLength(2);
+
+    // progress is logged to STDERR
+    expect(log).not.toHaveBeenCalled();
+    expect(error).toHaveBeenCalledWith(expect.stringContaining('0000-first.k8s.yaml'));
+  } finally {
+    stdout.mockRestore();
+    log.mockRestore();
+    error.mockRestore();
+    fs.removeSync(workdir);
+  }
+});
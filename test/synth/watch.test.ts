@@ -0,0 +1,82 @@
+import * as os from 'os';
+import * as path from 'path';
+import * as fs from 'fs-extra';
+import { compareSnapshots, DEFAULT_WATCH_EXCLUDE, formatChanges, listSourceFiles, SnapshotChanges, watchSources } from '../../src/synth/watch';
+
+let dir: string;
+
+beforeEach(() => {
+  dir = fs.mkdtempSync(path.join(os.tmpdir(), 'cdk8s-watch-'));
+});
+
+afterEach(() => {
+  fs.removeSync(dir);
+});
+
+test('lists source files that are included and not excluded', async () => {
+  fs.outputFileSync(path.join(dir, 'main.ts'), '');
+  fs.outputFileSync(path.join(dir, 'main.d.ts'), '');
+  fs.outputFileSync(path.join(dir, 'charts', 'app.ts'), '');
+  fs.outputFileSync(path.join(dir, 'cdk8s.yaml'), '');
+  fs.outputFileSync(path.join(dir, 'node_modules', 'cdk8s', 'index.ts'), '');
+  fs.outputFileSync(path.join(dir, 'dist', 'app.k8s.yaml'), '');
+
+  const files = await listSourceFiles(dir, ['**/*.ts', 'cdk8s.yaml'], [...DEFAULT_WATCH_EXCLUDE, 'dist/**']);
+  expect(files).toEqual(['cdk8s.yaml', 'charts/app.ts', 'main.ts']);
+});
+
+test('compares snapshots', () => {
+  const changes = compareSnapshots({ 'a.yaml': '1', 'b.yaml': '1', 'c.yaml': '1' }, { 'a.yaml': '1', 'b.yaml': '2', 'd.yaml': '1' });
+  expect(changes).toEqual({ added: ['d.yaml'], changed: ['b.yaml'], removed: ['c.yaml'] });
+  expect(formatChanges(changes)).toEqual('+d.yaml ~b.yaml -c.yaml');
+  expect(formatChanges({ added: [], changed: [], removed: [] })).toEqual('no changes');
+});
+
+test('debounces successive changes', async () => {
+  fs.outputFileSync(path.join(dir, 'main.ts'), 'v1');
+
+  const calls = new Array<SnapshotChanges>();
+  const stop = watchSources({ dir, include: ['**/*.ts'], exclude: [], interval: 20, debounce: 200 }, async changes => {
+    calls.push(changes);
+  });
+
+  try {
+    await sleep(100);
+    fs.outputFileSync(path.join(dir, 'main.ts'), 'v2');
+    await sleep(50);
+    fs.outputFileSync(path.join(dir, 'chart.ts'), 'v1');
+    await sleep(500);
+  } finally {
+    stop();
+  }
+
+  expect(calls).toEqual([{ added: ['chart.ts'], changed: ['main.ts'], removed: [] }]);
+});
+
+test('keeps watching when the handler fails', async () => {
+  fs.outputFileSync(path.join(dir, 'main.ts'), 'v1');
+
+  let calls = 0;
+  const stop = watchSources({ dir, include: ['**/*.ts'], exclude: [], interval: 20, debounce: 20 }, async () => {
+    calls++;
+    throw new Error('synth failed');
+  });
+
+  const error = jest.spyOn(console, 'error').mockImplementation(() => undefined);
+  try {
+    await sleep(100);
+    fs.outputFileSync(path.join(dir, 'main.ts'), 'v22');
+    await sleep(200);
+    fs.outputFileSync(path.join(dir, 'main.ts'), 'v333');
+    await sleep(200);
+  } finally {
+    stop();
+    error.mockRestore();
+  }
+
+  expect(calls).toEqual(2);
+});
+
+function sleep(ms: number) {
+  return new Promise(ok => setTimeout(ok, ms));
+}
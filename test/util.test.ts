@@ -1,7 +1,9 @@
 import { promises } from 'fs';
+import * as http from 'http';
+import { AddressInfo } from 'net';
 import { tmpdir } from 'os';
 import path from 'path';
-import { getFiles } from '../src/util';
+import { download, getFiles, globFiles, isGlob, mapConcurrently, matchGlob } from '../src/util';
 
 describe('getFiles', () => {
 
@@ -46,4 +48,136 @@ describe('getFiles', () => {
     //Bad directory name (non-existent) should yield an empty array
     expect(noFilesEither).toEqual([]);
   });
-});
\ No newline at end of file
+});
+
+describe('matchGlob', () => {
+  test.each([
+    ['s3.services.k8s.aws/*', 's3.services.k8s.aws/Bucket', true],
+    ['s3.services.k8s.aws/*', 'ec2.services.k8s.aws/Vpc', false],
+    ['*.services.k8s.aws/*', 'ec2.services.k8s.aws/Vpc', true],
+    ['*/Bucket', 's3.services.k8s.aws/Bucket', true],
+    ['*', 's3.services.k8s.aws/Bucket', false],
+    ['**', 's3.services.k8s.aws/Bucket', true],
+    ['**/*.ts', 'main.ts', true],
+    ['**/*.ts', 'src/charts/app.ts', true],
+    ['**/*.ts', 'main.py', false],
+    ['foo.bar/Ki?d', 'foo.bar/Kind', true],
+    ['foo.bar/Kind', 'fooxbar/Kind', false],
+    ['**/*', '.env', true],
+    ['crds/*.{yaml,json}', 'crds/widget.json', true],
+  ])('%s matches %s: %s', (pattern, value, expected) => {
+    expect(matchGlob(pattern, value)).toBe(expected);
+  });
+});
+
+test('isGlob', () => {
+  expect(isGlob('crds/*.yaml')).toBe(true);
+  expect(isGlob('crds/{a,b}.yaml')).toBe(true);
+  expect(isGlob('crds/widget.yaml')).toBe(false);
+});
+
+describe('globFiles', () => {
+  let dir: string;
+
+  beforeEach(async () => {
+    dir = await promises.mkdtemp(path.join(tmpdir(), 'cdk8s-globFiles-test'));
+    await promises.mkdir(path.join(dir, 'sub'));
+    for (const file of ['a.crd.yaml', 'sub/b.crd.yaml', 'sub/c.yaml', 'd.json']) {
+      await promises.writeFile(path.join(dir, file), 'contents');
+    }
+  });
+
+  afterEach(async () => {
+    await promises.rmdir(dir, { recursive: true });
+  });
+
+  test('matches files in subdirectories', async () => {
+    expect(await globFiles(`${dir}/**/*.crd.yaml`)).toEqual([path.join(dir, 'a.crd.yaml'), path.join(dir, 'sub', 'b.crd.yaml')]);
+  });
+
+  test('files matched by overlapping alternatives are returned once', async () => {
+    expect(await globFiles(`${dir}/{**/*.crd.yaml,sub/*}`)).toEqual([
+      path.join(dir, 'a.crd.yaml'),
+      path.join(dir, 'sub', 'b.crd.yaml'),
+      path.join(dir, 'sub', 'c.yaml'),
+    ]);
+  });
+
+  test('no matches', async () => {
+    expect(await globFiles(`${dir}/*.txt`)).toEqual([]);
+    expect(await globFiles(`${dir}/missing/*`)).toEqual([]);
+  });
+});
+
+describe('download', () => {
+
+  let server: http.Server;
+  let statuses: number[];
+  let requests: number;
+  let url: string;
+
+  beforeEach(async () => {
+    requests = 0;
+    server = http.createServer((_req, res) => {
+      res.statusCode = statuses[Math.min(requests++, statuses.length - 1)];
+      res.end(res.statusCode === 200 ? 'content' : 'error');
+    });
+
+    await new Promise<void>(ok => server.listen(0, '127.0.0.1', ok));
+    url = `http://127.0.0.1:${(server.address() as AddressInfo).port}/crd.yaml`;
+    jest.spyOn(console, 'error').mockImplementation(() => undefined);
+  });
+
+  afterEach(async () => {
+    await new Promise(ok => server.close(ok));
+    jest.restoreAllMocks();
+  });
+
+  test('retries on 5xx responses', async () => {
+    statuses = [503, 500, 200];
+    expect(await download(url, { retries: 3, retryMaxDelay: 1 })).toBe('content');
+    expect(requests).toBe(3);
+  });
+
+  test('reports the number of attempts', async () => {
+    statuses = [502];
+    await expect(download(url, { retries: 2, retryMaxDelay: 1 })).rejects.toThrow(`Failed to download ${url} after 3 attempts: Bad Gateway: ${url}`);
+    expect(requests).toBe(3);
+  });
+
+  test('does not retry 4xx responses', async () => {
+    statuses = [404];
+    await expect(download(url, { retries: 3, retryMaxDelay: 1 })).rejects.toThrow(`Not Found: ${url}`);
+    expect(requests).toBe(1);
+  });
+
+  test('retries connection errors', async () => {
+    await new Promise(ok => server.close(ok));
+    await expect(download(url, { retries: 1, retryMaxDelay: 1 })).rejects.toThrow(`Failed to download ${url} after 2 attempts: connect ECONNREFUSED`);
+  });
+
+});
+
+describe('mapConcurrently', () => {
+
+  test('runs at most the given number of calls at a time', async () => {
+    let running = 0;
+    let maxRunning = 0;
+
+    const results = await mapConcurrently([30, 10, 20, 5, 15], 2, async (delay, i) => {
+      running++;
+      maxRunning = Math.max(maxRunning, running);
+      await new Promise(ok => setTimeout(ok, delay));
+      running--;
+      return i;
+    });
+
+    expect(results).toStrictEqual([0, 1, 2, 3, 4]);
+    expect(maxRunning).toBe(2);
+  });
+
+  test('fails for invalid concurrency', async () => {
+    await expect(mapConcurrently([1], 0, async x => x)).rejects.toThrow('Concurrency must be a positive integer (got 0)');
+  });
+
+});